@@ -2,28 +2,44 @@ package main
 
 import (
 	"context"
-	"prod/internal/app"
+	"errors"
+	"flag"
+	"fmt"
+	"os"
+	"os/signal"
+	"prod/internal/cli"
 	"prod/internal/config"
 	"prod/pkg/logging"
+	"syscall"
 )
 
+// @title go-prod API
+// @description Reading the catalog is public. Changes and user management require a permission granted
+// @description to the roles of the user or to the scopes of the API key.
+// @securityDefinitions.apikey BearerAuth
+// @in header
+// @name Authorization
+// @description Access token in the form "Bearer <token>".
+// @securityDefinitions.apikey ApiKeyAuth
+// @in header
+// @name Authorization
+// @description Service API key in the form "ApiKey <key>".
 func main() {
-	ctx, cancel := context.WithCancel(context.Background())
+	ctx, cancel := signal.NotifyContext(context.Background(), os.Interrupt, syscall.SIGTERM)
 	defer cancel()
 
 	logger := logging.NewLogger()
 	ctx = logging.ContextWithLogger(ctx, logger)
 
-	logging.GetLogger(ctx).Infoln("Starting application")
-
-	cfg := config.GetConfig()
-	logging.GetLogger(ctx).Println("Loading config")
-
-	a, err := app.NewApp(ctx, cfg)
-	if err != nil {
+	if err := cli.Run(ctx, os.Args[1:]); err != nil {
+		if errors.Is(err, flag.ErrHelp) {
+			return
+		}
+		var validationErr *config.ValidationError
+		if errors.As(err, &validationErr) {
+			_, _ = fmt.Fprintln(os.Stderr, err)
+			os.Exit(1)
+		}
 		logging.GetLogger(ctx).Fatalln(err)
 	}
-
-	logging.GetLogger(ctx).Println("Before Run")
-	a.Run(ctx)
 }
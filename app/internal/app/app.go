@@ -2,6 +2,7 @@ package app
 
 import (
 	"context"
+	"crypto/rand"
 	"errors"
 	"fmt"
 	"github.com/jackc/pgx/v4/pgxpool"
@@ -11,9 +12,38 @@ import (
 	"golang.org/x/sync/errgroup"
 	"net"
 	"net/http"
+	apiKeyHandler "prod/internal/domain/apikey/handler"
+	apiKeyStorage "prod/internal/domain/apikey/storage"
+	categoryHandler "prod/internal/domain/category/handler"
+	categoryStorage "prod/internal/domain/category/storage"
+	currencyHandler "prod/internal/domain/currency/handler"
+	currencyStorage "prod/internal/domain/currency/storage"
+	imageHandler "prod/internal/domain/image/handler"
+	imageStorage "prod/internal/domain/image/storage"
+	productHandler "prod/internal/domain/product/handler"
+	productStorage "prod/internal/domain/product/storage"
+	"prod/internal/domain/product/suggest"
+	productValidator "prod/internal/domain/product/validator"
+	roleHandler "prod/internal/domain/role/handler"
+	roleStorage "prod/internal/domain/role/storage"
+	userHandler "prod/internal/domain/user/handler"
+	userStorage "prod/internal/domain/user/storage"
+	"prod/internal/rbac"
+	"prod/migrations"
+	"prod/pkg/auth"
 	"prod/pkg/client/postgresql"
+	"prod/pkg/compress"
+	"prod/pkg/httperr"
+	"prod/pkg/idempotency"
 	"prod/pkg/metric"
-	"time"
+	"prod/pkg/migrate"
+	"prod/pkg/password"
+	"prod/pkg/ratelimit"
+	"prod/pkg/recovery"
+	"prod/pkg/requestid"
+	"prod/pkg/secret"
+	"prod/pkg/timeout"
+	"sync/atomic"
 
 	_ "prod/docs"
 	"prod/internal/config"
@@ -22,14 +52,25 @@ import (
 
 type App struct {
 	cfg        *config.Config
+	watcher    *config.Watcher
 	router     *httprouter.Router
 	httpServer *http.Server
 	pgxPool    *pgxpool.Pool
+	dbPassword *secret.Value
+	pgClient   *postgresql.TxClient
+	txManager  *postgresql.TxManager
+	auth       *auth.Middleware
+	limiter    ratelimit.Limiter
+	idempotent *idempotency.PostgreSQLStore
 }
 
-func NewApp(ctx context.Context, cfg *config.Config) (App, error) {
+func NewApp(ctx context.Context, watcher *config.Watcher) (App, error) {
+	cfg := watcher.Current()
+
 	logging.GetLogger(ctx).Println("router init")
 	router := httprouter.New()
+	router.NotFound = http.HandlerFunc(httperr.NotFound)
+	router.MethodNotAllowed = http.HandlerFunc(httperr.MethodNotAllowed)
 
 	logging.GetLogger(ctx).Println("swagger init")
 	router.Handler(http.MethodGet, "/swagger", http.RedirectHandler("/swagger/index.html", http.StatusMovedPermanently))
@@ -38,26 +79,215 @@ func NewApp(ctx context.Context, cfg *config.Config) (App, error) {
 	metricHandler := metric.Handler{}
 	metricHandler.Register(router)
 
-	pgConfig := postgresql.NewPgConfig(cfg.PostgreSQL.Host, cfg.PostgreSQL.Port, cfg.PostgreSQL.Username, cfg.PostgreSQL.Password, cfg.PostgreSQL.Database)
-	pgClient, err := postgresql.NewClient(ctx, 5, time.Second*5, pgConfig)
+	pgConfig := newPgConfig(cfg)
+	dbPassword, err := newSecretValue(ctx, cfg, "postgresql.password")
 	if err != nil {
-		logging.GetLogger(ctx).Fatalln(err)
+		return App{}, err
+	}
+	if dbPassword != nil {
+		pgConfig.PasswordFunc = dbPassword.Get
+	}
+
+	pgxPool, err := postgresql.NewClient(ctx, pgConfig)
+	if err != nil {
+		return App{}, err
+	}
+	if err = migrateSchema(ctx, cfg, pgxPool); err != nil {
+		return App{}, err
+	}
+
+	pgClient := postgresql.NewTxClient(pgxPool)
+	txManager := postgresql.NewTxManager(pgxPool)
+
+	users := userStorage.NewUserStorage(pgClient)
+	roles := roleStorage.NewRoleStorage(pgClient)
+	if err = bootstrapAdmin(ctx, cfg, txManager, users, roles); err != nil {
+		return App{}, err
+	}
+
+	tokens, err := newTokenManager(ctx, cfg)
+	if err != nil {
+		return App{}, err
 	}
+	apiKeys := apiKeyStorage.NewAPIKeyStorage(pgClient)
+	authMiddleware := auth.NewMiddleware(tokens, roles, apiKeys)
+	guard := auth.Guard(authMiddleware.Require)
+
+	logging.GetLogger(ctx).Println("handlers init")
+	userHandler.NewHandler(users, roles, txManager, tokens, guard).Register(router)
+	roleHandler.NewHandler(roles, txManager, guard).Register(router)
+	apiKeyHandler.NewHandler(apiKeys, guard).Register(router)
+	categories := categoryStorage.NewCategoryStorage(pgClient)
+	currencies := currencyStorage.NewCurrencyStorage(pgClient)
+	images := imageStorage.NewImageStorage(pgClient)
+	products := productStorage.NewProductStorage(pgClient)
+	productHandler.NewHandler(
+		products,
+		productValidator.NewValidator(categories, currencies, images),
+		suggest.NewSuggester(products, cfg.Search.Autocomplete.CacheSize, cfg.Search.Autocomplete.CacheTTL),
+		cfg.Search.Facets.PriceRanges,
+		guard,
+	).Register(router)
+	categoryHandler.NewHandler(categories, guard).Register(router)
+	currencyHandler.NewHandler(currencies, guard).Register(router)
+	imageHandler.NewHandler(images, guard).Register(router)
 
-	//productStorage := storage.NewProductStorage(pgClient)
+	var limiter ratelimit.Limiter = ratelimit.NewMemoryLimiter()
+	if cfg.HTTP.RateLimit.Shared {
+		limiter = ratelimit.NewPostgreSQLLimiter(pgClient)
+	}
 
 	return App{
-		cfg:     cfg,
-		router:  router,
-		pgxPool: pgClient,
+		cfg:        cfg,
+		watcher:    watcher,
+		dbPassword: dbPassword,
+		router:     router,
+		pgxPool:    pgxPool,
+		pgClient:   pgClient,
+		txManager:  txManager,
+		auth:       authMiddleware,
+		limiter:    limiter,
+		idempotent: idempotency.NewPostgreSQLStore(pgClient, cfg.HTTP.Idempotency.Lease),
 	}, nil
 }
 
+func ConnectPostgreSQL(ctx context.Context, cfg *config.Config) (*pgxpool.Pool, error) {
+	return postgresql.NewClient(ctx, newPgConfig(cfg))
+}
+
+// newSecretValue returns a refreshable value for a config field given as a secret reference, nil for plain values.
+func newSecretValue(ctx context.Context, cfg *config.Config, path string) (*secret.Value, error) {
+	ref, ok := cfg.SecretRef(path)
+	if !ok {
+		return nil, nil
+	}
+	return secret.NewValue(ctx, cfg.SecretProvider(), ref)
+}
+
+// bootstrapAdmin creates the admin user from the config, updates its password when it has changed
+// and makes sure it holds the admin role.
+func bootstrapAdmin(
+	ctx context.Context,
+	cfg *config.Config,
+	txManager *postgresql.TxManager,
+	users *userStorage.UserStorage,
+	roles *roleStorage.RoleStorage,
+) error {
+	admin := cfg.AppConfig.AdminUser
+	if admin.Email == "" || admin.Password == "" {
+		logging.GetLogger(ctx).Warningln("admin user is not configured")
+		return nil
+	}
+
+	return txManager.WithinTx(ctx, func(ctx context.Context) error {
+		user, err := users.ByEmail(ctx, admin.Email)
+		switch {
+		case err == nil:
+			if password.Compare(user.PasswordHash, admin.Password) == nil {
+				return roles.AssignRole(ctx, user.Id, rbac.AdminRole)
+			}
+		case !errors.Is(err, postgresql.ErrNotFound):
+			return fmt.Errorf("failed to load admin user: %w", err)
+		}
+
+		hash, err := password.Hash(admin.Password)
+		if err != nil {
+			return err
+		}
+		if user, err = users.Upsert(ctx, admin.Email, hash); err != nil {
+			return fmt.Errorf("failed to save admin user: %w", err)
+		}
+		logging.GetLogger(ctx).Infof("admin user %s saved", admin.Email)
+
+		return roles.AssignRole(ctx, user.Id, rbac.AdminRole)
+	})
+}
+
+// newTokenManager signs tokens with the configured secret, in debug mode a random one is used when it is empty.
+func newTokenManager(ctx context.Context, cfg *config.Config) (*auth.TokenManager, error) {
+	key := []byte(cfg.Auth.JWTSecret)
+	if len(key) == 0 {
+		if !cfg.AppConfig.IsDebug {
+			return nil, errors.New("auth.jwt_secret must be set when is_debug is false")
+		}
+		key = make([]byte, 32)
+		if _, err := rand.Read(key); err != nil {
+			return nil, fmt.Errorf("failed to generate jwt secret: %w", err)
+		}
+		logging.GetLogger(ctx).Warningln("auth.jwt_secret is empty, tokens will not survive a restart")
+	}
+	return auth.NewTokenManager(key, cfg.Auth.Issuer, cfg.Auth.AccessTTL, cfg.Auth.RefreshTTL), nil
+}
+
+func migrateSchema(ctx context.Context, cfg *config.Config, pool *pgxpool.Pool) error {
+	migrator, err := migrate.NewMigrator(pool, migrations.FS)
+	if err != nil {
+		return err
+	}
+
+	if cfg.PostgreSQL.Migrations.AutoApply {
+		applied, err := migrator.Up(ctx)
+		if err != nil {
+			return err
+		}
+		logging.GetLogger(ctx).Infof("%d migrations applied", len(applied))
+	}
+
+	if cfg.PostgreSQL.Migrations.RequireLatest {
+		return migrator.Check(ctx)
+	}
+
+	return nil
+}
+
+func newPgConfig(cfg *config.Config) *postgresql.Config {
+	pg := cfg.PostgreSQL
+	return &postgresql.Config{
+		Host:              pg.Host,
+		Port:              pg.Port,
+		Username:          pg.Username,
+		Password:          pg.Password,
+		Database:          pg.Database,
+		SSLMode:           pg.SSLMode,
+		SSLRootCert:       pg.SSLRootCert,
+		MaxConns:          pg.MaxConns,
+		MinConns:          pg.MinConns,
+		MaxConnLifetime:   pg.MaxConnLifetime,
+		MaxConnIdleTime:   pg.MaxConnIdleTime,
+		HealthCheckPeriod: pg.HealthCheckPeriod,
+		ApplicationName:   pg.ApplicationName,
+		StatementTimeout:  pg.StatementTimeout,
+		SearchPath:        pg.SearchPath,
+		ConnectAttempts:   pg.Connect.Attempts,
+		ConnectDelay:      pg.Connect.Delay,
+		ConnectMaxDelay:   pg.Connect.MaxDelay,
+		ConnectMaxElapsed: pg.Connect.MaxElapsed,
+		ConnectTimeout:    pg.Connect.Timeout,
+	}
+}
+
 func (a *App) Run(ctx context.Context) error {
 	grp, ctx2 := errgroup.WithContext(ctx)
 	grp.Go(func() error {
 		return a.startHTTP(ctx2)
 	})
+	grp.Go(func() error {
+		return a.watcher.Run(ctx2)
+	})
+	if pgLimiter, ok := a.limiter.(*ratelimit.PostgreSQLLimiter); ok {
+		grp.Go(func() error {
+			return pgLimiter.Run(ctx2, a.cfg.HTTP.RateLimit.CleanupInterval)
+		})
+	}
+	// keys are cleaned up even when disabled, the feature can be turned on and off by a reload
+	grp.Go(func() error {
+		return a.idempotent.Run(ctx2, a.cfg.HTTP.Idempotency.CleanupInterval)
+	})
+	if a.dbPassword != nil && a.cfg.Secrets.RefreshInterval > 0 {
+		grp.Go(func() error {
+			return a.dbPassword.Run(ctx2, a.cfg.Secrets.RefreshInterval)
+		})
+	}
 
 	logging.GetLogger(ctx).Info("Application initialized and started")
 	return grp.Wait()
@@ -85,26 +315,25 @@ func (a *App) startHTTP(ctx context.Context) error {
 	//	"ExposedHeaders":     a.cfg.HTTP.CORS.ExposedHeaders,
 	//	"Debug":              a.cfg.HTTP.CORS.Debug,
 	//})
-	logging.GetLogger(ctx).Printf("CORS: %+v", a.cfg.HTTP.CORS)
-
-	c := cors.New(cors.Options{
-		AllowedMethods:     a.cfg.HTTP.CORS.AllowedMethods,
-		AllowedOrigins:     a.cfg.HTTP.CORS.AllowedOrigins,
-		AllowedHeaders:     a.cfg.HTTP.CORS.AllowedHeaders,
-		AllowCredentials:   a.cfg.HTTP.CORS.AllowCredentials,
-		OptionsPassthrough: a.cfg.HTTP.CORS.OptionsPassthrough,
-		ExposedHeaders:     a.cfg.HTTP.CORS.ExposedHeaders,
-		Debug:              a.cfg.HTTP.CORS.Debug,
+	handler := &reloadableHandler{}
+	handler.Store(a.newHTTPHandler(ctx, a.cfg))
+	a.watcher.Subscribe(func(cfg *config.Config) {
+		handler.Store(a.newHTTPHandler(ctx, cfg))
 	})
 
-	handler := c.Handler(a.router)
-
 	a.httpServer = &http.Server{
 		Handler:      handler,
 		WriteTimeout: a.cfg.HTTP.WriteTimeout,
 		ReadTimeout:  a.cfg.HTTP.ReadTimeout,
 	}
 
+	go func() {
+		<-ctx.Done()
+		if err := a.httpServer.Shutdown(context.Background()); err != nil {
+			logging.GetLogger(ctx).WithError(err).Errorln("failed to shutdown http server")
+		}
+	}()
+
 	logging.GetLogger(ctx).Println("http server started")
 	if err = a.httpServer.Serve(listener); err != nil {
 		switch {
@@ -120,3 +349,97 @@ func (a *App) startHTTP(ctx context.Context) error {
 	}
 	return err
 }
+
+// newHTTPHandler builds the middleware chain around the router from the reloadable parts of the config.
+func (a *App) newHTTPHandler(ctx context.Context, cfg *config.Config) http.Handler {
+	var handler http.Handler = a.router
+	if cfg.HTTP.Idempotency.Enabled {
+		handler = newIdempotencyHandler(ctx, cfg, a.idempotent, handler)
+	}
+	handler = newTimeoutHandler(ctx, cfg, handler)
+	if cfg.HTTP.RateLimit.Enabled {
+		handler = newRateLimitHandler(ctx, cfg, a.limiter, handler)
+	}
+	handler = a.auth.Identify(handler)
+	handler = newCORSHandler(ctx, cfg, handler)
+	handler = recovery.Middleware(handler)
+	if cfg.HTTP.Compression.Enabled {
+		handler = compress.Middleware(cfg.HTTP.Compression.MinSize)(handler)
+	}
+	return requestid.Middleware(handler)
+}
+
+// idempotentRoutes are the routes whose responses are stored for Idempotency-Key replays. Only the catalog creates
+// are listed, the responses of the login and admin routes carry tokens or plaintext API keys that must not be stored.
+var idempotentRoutes = []string{
+	http.MethodPost + " " + productHandler.URL,
+	http.MethodPost + " " + categoryHandler.URL,
+	http.MethodPost + " " + currencyHandler.URL,
+	http.MethodPost + " " + imageHandler.URL,
+}
+
+func newIdempotencyHandler(ctx context.Context, cfg *config.Config, store idempotency.Store, next http.Handler) http.Handler {
+	m, err := idempotency.NewMiddleware(store, cfg.HTTP.Idempotency.TTL, idempotentRoutes)
+	if err != nil {
+		logging.GetLogger(ctx).WithError(err).Errorln("idempotency keys are disabled")
+		return next
+	}
+
+	return m.Handler(next)
+}
+
+func newTimeoutHandler(ctx context.Context, cfg *config.Config, next http.Handler) http.Handler {
+	// checked by config validation, a failure here means a bug
+	rules, err := timeout.NewRules(cfg.HTTP.Timeout.Default, cfg.HTTP.Timeout.Routes)
+	if err != nil {
+		logging.GetLogger(ctx).WithError(err).Errorln("request timeouts are disabled")
+		return next
+	}
+
+	return timeout.Middleware(rules)(next)
+}
+
+func newRateLimitHandler(ctx context.Context, cfg *config.Config, limiter ratelimit.Limiter, next http.Handler) http.Handler {
+	// both are checked by config validation, a failure here means a bug
+	rules, err := ratelimit.NewRules(cfg.HTTP.RateLimit.Default, cfg.HTTP.RateLimit.Routes)
+	if err != nil {
+		logging.GetLogger(ctx).WithError(err).Errorln("rate limiting is disabled")
+		return next
+	}
+	ips, err := ratelimit.NewIPResolver(cfg.HTTP.TrustedProxies)
+	if err != nil {
+		logging.GetLogger(ctx).WithError(err).Errorln("rate limiting is disabled")
+		return next
+	}
+
+	return ratelimit.NewMiddleware(limiter, rules, ratelimit.HTTPKey(ips)).Handler(next)
+}
+
+func newCORSHandler(ctx context.Context, cfg *config.Config, next http.Handler) http.Handler {
+	logging.GetLogger(ctx).Printf("CORS: %+v", cfg.HTTP.CORS)
+
+	c := cors.New(cors.Options{
+		AllowedMethods:     cfg.HTTP.CORS.AllowedMethods,
+		AllowedOrigins:     cfg.HTTP.CORS.AllowedOrigins,
+		AllowedHeaders:     cfg.HTTP.CORS.AllowedHeaders,
+		AllowCredentials:   cfg.HTTP.CORS.AllowCredentials,
+		OptionsPassthrough: cfg.HTTP.CORS.OptionsPassthrough,
+		ExposedHeaders:     cfg.HTTP.CORS.ExposedHeaders,
+		Debug:              cfg.HTTP.CORS.Debug,
+	})
+
+	return c.Handler(next)
+}
+
+// reloadableHandler serves requests with the last stored handler, it lets middlewares be rebuilt on config reload.
+type reloadableHandler struct {
+	handler atomic.Pointer[http.Handler]
+}
+
+func (h *reloadableHandler) Store(handler http.Handler) {
+	h.handler.Store(&handler)
+}
+
+func (h *reloadableHandler) ServeHTTP(w http.ResponseWriter, r *http.Request) {
+	(*h.handler.Load()).ServeHTTP(w, r)
+}
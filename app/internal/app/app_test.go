@@ -0,0 +1,39 @@
+package app
+
+import (
+	"context"
+	"prod/internal/config"
+	"testing"
+	"time"
+)
+
+func TestNewTokenManager(t *testing.T) {
+	tests := []struct {
+		name    string
+		secret  string
+		debug   bool
+		wantErr bool
+	}{
+		{name: "configured", secret: "0123456789abcdef0123456789abcdef"},
+		{name: "empty in debug", debug: true},
+		{name: "empty outside debug", wantErr: true},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			cfg := &config.Config{}
+			cfg.Auth.JWTSecret = tt.secret
+			cfg.Auth.AccessTTL = time.Minute
+			cfg.Auth.RefreshTTL = time.Hour
+			cfg.AppConfig.IsDebug = tt.debug
+
+			tokens, err := newTokenManager(context.Background(), cfg)
+			if (err != nil) != tt.wantErr {
+				t.Fatalf("newTokenManager() error = %v, wantErr %t", err, tt.wantErr)
+			}
+			if !tt.wantErr && tokens == nil {
+				t.Fatal("newTokenManager() returned no manager")
+			}
+		})
+	}
+}
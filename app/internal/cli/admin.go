@@ -0,0 +1,109 @@
+package cli
+
+import (
+	"bufio"
+	"context"
+	"errors"
+	"flag"
+	"fmt"
+	"io"
+	"os"
+	"prod/internal/app"
+	"prod/internal/config"
+	roleStorage "prod/internal/domain/role/storage"
+	"prod/internal/domain/user/storage"
+	"prod/internal/rbac"
+	"prod/pkg/client/postgresql"
+	"prod/pkg/password"
+	"strings"
+)
+
+func admin(ctx context.Context, e *env, args []string) error {
+	_, args, err := subcommand(args, "create-user")
+	if err != nil {
+		return err
+	}
+
+	var email, role string
+	var passwordStdin bool
+	fs := flag.NewFlagSet("admin create-user", flag.ContinueOnError)
+	fs.StringVar(&email, "email", "", "user email")
+	fs.BoolVar(&passwordStdin, "password-stdin", false,
+		"read the password from stdin instead of "+passwordEnv+" or "+passwordEnv+config.FileEnvSuffix)
+	fs.StringVar(&role, "role", rbac.AdminRole, "role granted to the user: viewer, editor, admin or a custom one")
+	if err = fs.Parse(args); err != nil {
+		return err
+	}
+	if email == "" {
+		return fmt.Errorf("%w: -email is required", ErrUsage)
+	}
+	pass, err := readPassword(e.in, passwordStdin)
+	if err != nil {
+		return err
+	}
+
+	cfg, err := e.config(ctx)
+	if err != nil {
+		return err
+	}
+	pool, err := app.ConnectPostgreSQL(ctx, cfg)
+	if err != nil {
+		return err
+	}
+	defer pool.Close()
+
+	hash, err := password.Hash(pass)
+	if err != nil {
+		return err
+	}
+
+	client := postgresql.NewTxClient(pool)
+	users := storage.NewUserStorage(client)
+	roles := roleStorage.NewRoleStorage(client)
+
+	return postgresql.NewTxManager(pool).WithinTx(ctx, func(ctx context.Context) error {
+		user, err := users.Upsert(ctx, email, hash)
+		if err != nil {
+			return err
+		}
+		if err = roles.AssignRole(ctx, user.Id, role); err != nil {
+			return err
+		}
+
+		_, _ = fmt.Fprintf(e.out, "user %s saved with id %s and role %s\n", user.Email, user.Id, role)
+		return nil
+	})
+}
+
+// passwordEnv holds the password of the created user, it is not a flag so that it does not show up in
+// the shell history and the process list.
+const passwordEnv = "USER_PASSWORD"
+
+// readPassword reads the first line of in when fromStdin is set, otherwise USER_PASSWORD or the file named by
+// USER_PASSWORD_FILE.
+func readPassword(in io.Reader, fromStdin bool) (string, error) {
+	var pass string
+	switch path, fromFile := os.LookupEnv(passwordEnv + config.FileEnvSuffix); {
+	case fromStdin:
+		line, err := bufio.NewReader(in).ReadString('\n')
+		if err != nil && !errors.Is(err, io.EOF) {
+			return "", fmt.Errorf("failed to read the password from stdin: %w", err)
+		}
+		pass = line
+	case fromFile:
+		data, err := os.ReadFile(path)
+		if err != nil {
+			return "", fmt.Errorf("failed to read %s%s: %w", passwordEnv, config.FileEnvSuffix, err)
+		}
+		pass = string(data)
+	default:
+		pass = os.Getenv(passwordEnv)
+	}
+
+	pass = strings.TrimRight(pass, "\r\n")
+	if pass == "" {
+		return "", fmt.Errorf("%w: the password is required, pass it with -password-stdin, %s or %s%s",
+			ErrUsage, passwordEnv, passwordEnv, config.FileEnvSuffix)
+	}
+	return pass, nil
+}
@@ -0,0 +1,64 @@
+package cli
+
+import (
+	"errors"
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+)
+
+func TestReadPassword(t *testing.T) {
+	file := filepath.Join(t.TempDir(), "password")
+	if err := os.WriteFile(file, []byte("from-file\n"), 0o600); err != nil {
+		t.Fatal(err)
+	}
+
+	tests := []struct {
+		name      string
+		stdin     string
+		fromStdin bool
+		env       map[string]string
+		want      string
+		wantErr   error
+	}{
+		{name: "stdin", stdin: "from-stdin\nignored\n", fromStdin: true, want: "from-stdin"},
+		{name: "stdin without newline", stdin: "from-stdin", fromStdin: true, want: "from-stdin"},
+		{name: "stdin wins over env", stdin: "from-stdin\r\n", fromStdin: true, env: map[string]string{"USER_PASSWORD": "from-env"}, want: "from-stdin"},
+		{name: "env", env: map[string]string{"USER_PASSWORD": "from-env"}, want: "from-env"},
+		{name: "file wins over env", env: map[string]string{"USER_PASSWORD": "from-env", "USER_PASSWORD_FILE": file}, want: "from-file"},
+		{name: "empty stdin", stdin: "\n", fromStdin: true, wantErr: ErrUsage},
+		{name: "nothing given", wantErr: ErrUsage},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			t.Setenv("USER_PASSWORD", "")
+			os.Unsetenv("USER_PASSWORD")
+			for name, value := range tt.env {
+				t.Setenv(name, value)
+			}
+
+			got, err := readPassword(strings.NewReader(tt.stdin), tt.fromStdin)
+			if tt.wantErr != nil {
+				if !errors.Is(err, tt.wantErr) {
+					t.Fatalf("err = %v, want %v", err, tt.wantErr)
+				}
+				return
+			}
+			if err != nil {
+				t.Fatal(err)
+			}
+			if got != tt.want {
+				t.Errorf("readPassword() = %q, want %q", got, tt.want)
+			}
+		})
+	}
+}
+
+func TestReadPasswordMissingFile(t *testing.T) {
+	t.Setenv("USER_PASSWORD_FILE", filepath.Join(t.TempDir(), "missing"))
+	if _, err := readPassword(strings.NewReader(""), false); err == nil {
+		t.Error("expected an error for a missing password file")
+	}
+}
@@ -0,0 +1,114 @@
+package cli
+
+import (
+	"context"
+	"flag"
+	"fmt"
+	"prod/internal/app"
+	"prod/internal/domain/apikey/model"
+	"prod/internal/domain/apikey/storage"
+	"prod/pkg/auth"
+	"prod/pkg/client/postgresql"
+	"strings"
+	"text/tabwriter"
+	"time"
+)
+
+func apikey(ctx context.Context, e *env, args []string) error {
+	sub, args, err := subcommand(args, "create", "list", "revoke")
+	if err != nil {
+		return err
+	}
+
+	var name, scopes, id string
+	var ttl time.Duration
+	fs := flag.NewFlagSet("apikey "+sub, flag.ContinueOnError)
+	switch sub {
+	case "create":
+		fs.StringVar(&name, "name", "", "name of the service using the key")
+		fs.StringVar(&scopes, "scopes", "", "comma separated permissions, e.g. product:create,product:update")
+		fs.DurationVar(&ttl, "ttl", 0, "lifetime of the key, it never expires when zero")
+	case "revoke":
+		fs.StringVar(&id, "id", "", "key id")
+	}
+	if err = fs.Parse(args); err != nil {
+		return err
+	}
+	if sub == "revoke" && id == "" {
+		return fmt.Errorf("%w: -id is required", ErrUsage)
+	}
+
+	cfg, err := e.config(ctx)
+	if err != nil {
+		return err
+	}
+	pool, err := app.ConnectPostgreSQL(ctx, cfg)
+	if err != nil {
+		return err
+	}
+	defer pool.Close()
+
+	keys := storage.NewAPIKeyStorage(postgresql.NewTxClient(pool))
+
+	switch sub {
+	case "create":
+		k := model.APIKey{Name: name}
+		for _, scope := range strings.Split(scopes, ",") {
+			if scope = strings.TrimSpace(scope); scope != "" {
+				k.Scopes = append(k.Scopes, auth.Permission(scope))
+			}
+		}
+		if ttl > 0 {
+			expiresAt := time.Now().Add(ttl)
+			k.ExpiresAt = &expiresAt
+		}
+		if err = k.Validate(); err != nil {
+			return fmt.Errorf("%w: %w", ErrUsage, err)
+		}
+
+		created, err := keys.Issue(ctx, k)
+		if err != nil {
+			return err
+		}
+		_, _ = fmt.Fprintf(e.out, "api key %s created with id %s, it won't be shown again:\n%s\n",
+			created.Name, created.Id, created.Key)
+		return nil
+	case "revoke":
+		k, err := keys.Revoke(ctx, id)
+		if err != nil {
+			return err
+		}
+		_, _ = fmt.Fprintf(e.out, "api key %s revoked at %s\n", k.Name, k.RevokedAt.Format(time.RFC3339))
+		return nil
+	}
+
+	list, err := keys.All(ctx)
+	if err != nil {
+		return err
+	}
+	w := tabwriter.NewWriter(e.out, 0, 0, 2, ' ', 0)
+	_, _ = fmt.Fprintln(w, "ID\tNAME\tPREFIX\tSCOPES\tEXPIRES\tLAST USED\tSTATUS")
+	for _, k := range list {
+		scopes := make([]string, 0, len(k.Scopes))
+		for _, scope := range k.Scopes {
+			scopes = append(scopes, string(scope))
+		}
+		status := "active"
+		switch {
+		case k.RevokedAt != nil:
+			status = "revoked"
+		case k.ExpiresAt != nil && !k.ExpiresAt.After(time.Now()):
+			status = "expired"
+		}
+		_, _ = fmt.Fprintf(w, "%s\t%s\t%s\t%s\t%s\t%s\t%s\n",
+			k.Id, k.Name, k.Prefix, strings.Join(scopes, ","), formatTime(k.ExpiresAt), formatTime(k.LastUsedAt), status)
+	}
+	return w.Flush()
+}
+
+func formatTime(t *time.Time) string {
+	if t == nil {
+		return "-"
+	}
+	return t.Format("2006-01-02 15:04:05")
+}
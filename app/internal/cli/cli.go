@@ -0,0 +1,164 @@
+package cli
+
+import (
+	"context"
+	"errors"
+	"flag"
+	"fmt"
+	"github.com/sirupsen/logrus"
+	"io"
+	"os"
+	"prod/internal/config"
+	"prod/pkg/logging"
+	"sort"
+	"strings"
+)
+
+var ErrUsage = errors.New("invalid usage")
+
+type command struct {
+	usage string
+	run   func(ctx context.Context, env *env, args []string) error
+}
+
+var commands = map[string]command{
+	"serve": {
+		usage: "start the HTTP server",
+		run:   serve,
+	},
+	"migrate": {
+		usage: "manage the database schema: up | down [-steps N] | status | create <name>",
+		run:   migrateCmd,
+	},
+	"seed": {
+		usage: "fill an empty catalog with fixture data",
+		run:   seed,
+	},
+	"config": {
+		usage: "check the configuration: validate | print",
+		run:   configCmd,
+	},
+	"admin": {
+		usage: "manage administrators: create-user -email <email> [-password-stdin] [-role <role>]",
+		run:   admin,
+	},
+	"apikey": {
+		usage: "manage service API keys: create -name <name> -scopes <list> [-ttl D] | list | revoke -id <id>",
+		run:   apikey,
+	},
+}
+
+// env is shared by all commands, the config is loaded lazily so that commands like "migrate create" work without it.
+type env struct {
+	configPath string
+	overrides  overrides
+	in         io.Reader
+	out        io.Writer
+	cfg        *config.Config
+}
+
+func (e *env) loadOptions() config.LoadOptions {
+	return config.LoadOptions{Path: e.configPath, Overrides: e.overrides}
+}
+
+func (e *env) config(ctx context.Context) (*config.Config, error) {
+	if e.cfg != nil {
+		return e.cfg, nil
+	}
+
+	cfg, err := config.Load(e.loadOptions())
+	if err != nil {
+		return nil, err
+	}
+	setupLogging(ctx, cfg)
+
+	e.cfg = cfg
+	return cfg, nil
+}
+
+// setupLogging applies the logging settings, the level is validated with the rest of the config.
+func setupLogging(ctx context.Context, cfg *config.Config) {
+	if level, err := logrus.ParseLevel(cfg.AppConfig.LogLevel); err == nil {
+		logging.GetLogger(ctx).SetLevel(level)
+	}
+}
+
+// Run parses global flags and runs the subcommand, "serve" is used when none is given.
+func Run(ctx context.Context, args []string) error {
+	e := &env{in: os.Stdin, out: os.Stdout}
+
+	fs := flag.NewFlagSet("app", flag.ContinueOnError)
+	fs.StringVar(&e.configPath, config.FlagConfigPathName, "", "this is app config file, "+config.EnvConfigPathName+" env is used when empty")
+	fs.Var(&e.overrides, "set", "override a config value, e.g. -set http.port=8080 (repeatable)")
+	fs.Usage = func() {
+		usage(fs)
+	}
+	if err := fs.Parse(args); err != nil {
+		return err
+	}
+
+	name := "serve"
+	if fs.NArg() > 0 {
+		name = fs.Arg(0)
+	}
+
+	cmd, ok := commands[name]
+	if !ok {
+		usage(fs)
+		return fmt.Errorf("%w: unknown command %q", ErrUsage, name)
+	}
+
+	var rest []string
+	if fs.NArg() > 1 {
+		rest = fs.Args()[1:]
+	}
+	return cmd.run(ctx, e, rest)
+}
+
+// overrides collects repeated "-set key=value" flags.
+type overrides map[string]string
+
+func (o *overrides) String() string {
+	return fmt.Sprint(map[string]string(*o))
+}
+
+func (o *overrides) Set(s string) error {
+	key, value, ok := strings.Cut(s, "=")
+	if !ok || key == "" {
+		return fmt.Errorf("expected key=value, got %q", s)
+	}
+	if *o == nil {
+		*o = make(overrides)
+	}
+	(*o)[key] = value
+	return nil
+}
+
+func usage(fs *flag.FlagSet) {
+	out := fs.Output()
+	_, _ = fmt.Fprintf(out, "Usage: %s [flags] <command> [args]\n\nCommands:\n", fs.Name())
+
+	names := make([]string, 0, len(commands))
+	for name := range commands {
+		names = append(names, name)
+	}
+	sort.Strings(names)
+	for _, name := range names {
+		_, _ = fmt.Fprintf(out, "  %-8s %s\n", name, commands[name].usage)
+	}
+
+	_, _ = fmt.Fprintln(out, "\nFlags:")
+	fs.PrintDefaults()
+}
+
+func subcommand(args []string, names ...string) (string, []string, error) {
+	if len(args) == 0 {
+		return "", nil, fmt.Errorf("%w: expected one of %s", ErrUsage, strings.Join(names, ", "))
+	}
+	for _, name := range names {
+		if args[0] == name {
+			return name, args[1:], nil
+		}
+	}
+	return "", nil, fmt.Errorf("%w: unknown subcommand %q, expected one of %s", ErrUsage, args[0], strings.Join(names, ", "))
+}
@@ -0,0 +1,54 @@
+package cli
+
+import (
+	"context"
+	"flag"
+	"fmt"
+	"gopkg.in/yaml.v3"
+	"prod/internal/config"
+	"text/tabwriter"
+)
+
+func configCmd(ctx context.Context, e *env, args []string) error {
+	sub, args, err := subcommand(args, "validate", "print")
+	if err != nil {
+		return err
+	}
+
+	var sources bool
+	fs := flag.NewFlagSet("config "+sub, flag.ContinueOnError)
+	if sub == "print" {
+		fs.BoolVar(&sources, "sources", false, "print every value with the layer it comes from")
+	}
+	if err = fs.Parse(args); err != nil {
+		return err
+	}
+
+	cfg, err := e.config(ctx)
+	if err != nil {
+		return err
+	}
+
+	if sub == "validate" {
+		_, _ = fmt.Fprintln(e.out, "config is valid")
+		return nil
+	}
+
+	if sources {
+		if cfg.Path() != "" {
+			_, _ = fmt.Fprintf(e.out, "# file: %s\n", cfg.Path())
+		}
+		w := tabwriter.NewWriter(e.out, 0, 0, 2, ' ', 0)
+		for _, t := range cfg.Trace() {
+			_, _ = fmt.Fprintf(w, "%s\t%s\t%s\n", t.Path, t.Value, t.Source)
+		}
+		return w.Flush()
+	}
+
+	enc := yaml.NewEncoder(e.out)
+	enc.SetIndent(2)
+	if err = enc.Encode(config.Redacted(cfg)); err != nil {
+		return err
+	}
+	return enc.Close()
+}
@@ -0,0 +1,123 @@
+package cli
+
+import (
+	"context"
+	"flag"
+	"fmt"
+	"os"
+	"path/filepath"
+	"prod/internal/app"
+	"prod/migrations"
+	"prod/pkg/migrate"
+	"regexp"
+	"strings"
+	"text/tabwriter"
+)
+
+var migrationNameRegexp = regexp.MustCompile(`\W+`)
+
+func migrateCmd(ctx context.Context, e *env, args []string) error {
+	sub, args, err := subcommand(args, "up", "down", "status", "create")
+	if err != nil {
+		return err
+	}
+
+	if sub == "create" {
+		return createMigration(e, args)
+	}
+
+	steps := 1
+	fs := flag.NewFlagSet("migrate "+sub, flag.ContinueOnError)
+	if sub == "down" {
+		fs.IntVar(&steps, "steps", 1, "number of migrations to revert")
+	}
+	if err = fs.Parse(args); err != nil {
+		return err
+	}
+
+	cfg, err := e.config(ctx)
+	if err != nil {
+		return err
+	}
+	pool, err := app.ConnectPostgreSQL(ctx, cfg)
+	if err != nil {
+		return err
+	}
+	defer pool.Close()
+
+	migrator, err := migrate.NewMigrator(pool, migrations.FS)
+	if err != nil {
+		return err
+	}
+
+	switch sub {
+	case "up":
+		applied, err := migrator.Up(ctx)
+		for _, m := range applied {
+			_, _ = fmt.Fprintf(e.out, "applied %d_%s\n", m.Version, m.Name)
+		}
+		return err
+	case "down":
+		reverted, err := migrator.Down(ctx, steps)
+		for _, m := range reverted {
+			_, _ = fmt.Fprintf(e.out, "reverted %d_%s\n", m.Version, m.Name)
+		}
+		return err
+	default:
+		list, err := migrator.Status(ctx)
+		if err != nil {
+			return err
+		}
+		w := tabwriter.NewWriter(e.out, 0, 0, 2, ' ', 0)
+		_, _ = fmt.Fprintln(w, "VERSION\tNAME\tAPPLIED AT")
+		for _, s := range list {
+			appliedAt := "pending"
+			if s.AppliedAt != nil {
+				appliedAt = s.AppliedAt.Format("2006-01-02 15:04:05")
+			}
+			if s.Missing {
+				appliedAt += " (missing in application)"
+			}
+			_, _ = fmt.Fprintf(w, "%d\t%s\t%s\n", s.Version, s.Name, appliedAt)
+		}
+		return w.Flush()
+	}
+}
+
+func createMigration(e *env, args []string) error {
+	var dir string
+	fs := flag.NewFlagSet("migrate create", flag.ContinueOnError)
+	// the default is the directory embedded by prod/migrations, relative to the repository root like the Makefile
+	fs.StringVar(&dir, "dir", "app/migrations", "migrations source directory")
+	if err := fs.Parse(args); err != nil {
+		return err
+	}
+
+	name := strings.Trim(migrationNameRegexp.ReplaceAllString(strings.ToLower(strings.Join(fs.Args(), "_")), "_"), "_")
+	if name == "" {
+		return fmt.Errorf("%w: migration name is required", ErrUsage)
+	}
+
+	existing, err := migrate.Load(os.DirFS(dir))
+	if err != nil {
+		return err
+	}
+	version := int64(1)
+	if len(existing) > 0 {
+		version = existing[len(existing)-1].Version + 1
+	}
+
+	for _, direction := range []string{"up", "down"} {
+		path := filepath.Join(dir, fmt.Sprintf("%05d_%s.%s.sql", version, name, direction))
+		f, err := os.OpenFile(path, os.O_CREATE|os.O_EXCL|os.O_WRONLY, 0o644)
+		if err != nil {
+			return err
+		}
+		if err = f.Close(); err != nil {
+			return err
+		}
+		_, _ = fmt.Fprintf(e.out, "created %s\n", path)
+	}
+
+	return nil
+}
@@ -0,0 +1,116 @@
+package cli
+
+import (
+	"context"
+	"encoding/json"
+	"fmt"
+	"prod/internal/app"
+	categoryModel "prod/internal/domain/category/model"
+	categoryStorage "prod/internal/domain/category/storage"
+	currencyStorage "prod/internal/domain/currency/storage"
+	productModel "prod/internal/domain/product/model"
+	productStorage "prod/internal/domain/product/storage"
+	"prod/pkg/client/postgresql"
+	"strconv"
+)
+
+type categoryFixture struct {
+	name     string
+	products []productFixture
+}
+
+// productFixture prices are in minor units of the currency, kopecks and cents.
+type productFixture struct {
+	name          string
+	description   string
+	price         int64
+	currency      string
+	rating        int32
+	specification string
+}
+
+// catalogFixtures are seeded in order, so that the ids are the same on every run.
+var catalogFixtures = []categoryFixture{
+	{"Смартфоны", []productFixture{
+		{"Phone X", "Флагманский смартфон с OLED-экраном", 8999000, "рубль", 5, `{"color": "black", "ram_gb": 8, "tags": ["5g", "nfc"]}`},
+		{"Phone Lite", "Компактный смартфон на каждый день", 1999000, "рубль", 4, `{"color": "white", "ram_gb": 4, "tags": ["nfc"]}`},
+	}},
+	{"Ноутбуки", []productFixture{
+		{"Book Pro 14", "Ноутбук для работы и разработки", 149900, "dollar", 5, `{"color": "silver", "ram_gb": 16, "tags": ["thunderbolt"]}`},
+		{"Book Air 13", "Лёгкий ноутбук для поездок", 99900, "dollar", 4, `{"color": "gold", "ram_gb": 8, "tags": []}`},
+	}},
+}
+
+func seed(ctx context.Context, e *env, _ []string) error {
+	cfg, err := e.config(ctx)
+	if err != nil {
+		return err
+	}
+	pool, err := app.ConnectPostgreSQL(ctx, cfg)
+	if err != nil {
+		return err
+	}
+	defer pool.Close()
+
+	client := postgresql.NewTxClient(pool)
+	categories := categoryStorage.NewCategoryStorage(client)
+	currencies := currencyStorage.NewCurrencyStorage(client)
+	products := productStorage.NewProductStorage(client)
+
+	return postgresql.NewTxManager(pool).WithinTx(ctx, func(ctx context.Context) error {
+		existing, err := categories.All(ctx)
+		if err != nil {
+			return err
+		}
+		if len(existing) > 0 {
+			_, _ = fmt.Fprintln(e.out, "catalog is not empty, seed skipped")
+			return nil
+		}
+
+		currencyList, err := currencies.All(ctx)
+		if err != nil {
+			return err
+		}
+		currencyIds := make(map[string]int32, len(currencyList))
+		for _, c := range currencyList {
+			id, err := strconv.ParseInt(c.Id, 10, 32)
+			if err != nil {
+				return err
+			}
+			currencyIds[c.Name] = int32(id)
+		}
+
+		for _, fixture := range catalogFixtures {
+			category, err := categories.Create(ctx, categoryModel.Category{Name: fixture.name})
+			if err != nil {
+				return err
+			}
+			categoryId, err := strconv.ParseInt(category.Id, 10, 32)
+			if err != nil {
+				return err
+			}
+
+			for _, f := range fixture.products {
+				currencyId, ok := currencyIds[f.currency]
+				if !ok {
+					return fmt.Errorf("currency %q is not found", f.currency)
+				}
+				_, err = products.Create(ctx, productModel.Product{
+					Name:          f.name,
+					Description:   f.description,
+					Price:         f.price,
+					CurrencyId:    currencyId,
+					Rating:        f.rating,
+					CategoryId:    int32(categoryId),
+					Specification: json.RawMessage(f.specification),
+				})
+				if err != nil {
+					return err
+				}
+			}
+			_, _ = fmt.Fprintf(e.out, "category %s seeded with %d products\n", fixture.name, len(fixture.products))
+		}
+
+		return nil
+	})
+}
@@ -0,0 +1,31 @@
+package cli
+
+import (
+	"context"
+	"prod/internal/app"
+	"prod/internal/config"
+	"prod/pkg/logging"
+)
+
+func serve(ctx context.Context, e *env, _ []string) error {
+	logging.GetLogger(ctx).Infoln("Starting application")
+
+	cfg, err := e.config(ctx)
+	if err != nil {
+		return err
+	}
+	logging.GetLogger(ctx).Println("Loading config")
+
+	watcher := config.NewWatcher(e.loadOptions(), cfg)
+	watcher.Subscribe(func(cfg *config.Config) {
+		setupLogging(ctx, cfg)
+	})
+
+	a, err := app.NewApp(ctx, watcher)
+	if err != nil {
+		return err
+	}
+
+	logging.GetLogger(ctx).Println("Before Run")
+	return a.Run(ctx)
+}
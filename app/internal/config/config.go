@@ -1,11 +1,7 @@
 package config
 
 import (
-	"flag"
-	"github.com/ilyakaznacheev/cleanenv"
-	"log"
-	"os"
-	"sync"
+	"prod/pkg/secret"
 	"time"
 )
 
@@ -14,67 +10,123 @@ type Config struct {
 	HTTP struct {
 		IP           string        `yaml:"ip" env:"HTTP_IP" env-default:"127.0.0.1"`
 		Port         int           `yaml:"port" env:"HTTP_PORT" env-default:"8080"`
-		ReadTimeout  time.Duration `yaml:"read_timeout" env:"HTTP_READ_TIMEOUT" env-default:"15"`
-		WriteTimeout time.Duration `yaml:"write_timeout" env:"HTTP_WRITE_TIMEOUT" env-default:"15"`
+		ReadTimeout  time.Duration `yaml:"read_timeout" env:"HTTP_READ_TIMEOUT" env-default:"15s"`
+		WriteTimeout time.Duration `yaml:"write_timeout" env:"HTTP_WRITE_TIMEOUT" env-default:"15s"`
 		CORS         struct {
-			AllowedMethods     []string `yaml:"allowed_methods" env:"HTTP_CORS_ALLOWED_METHODS"`
-			AllowedOrigins     []string `yaml:"allowed_origins" env:"HTTP_CORS_ALLOWED_ORIGINS"`
-			AllowedHeaders     []string `yaml:"allowed_headers" env:"HTTP_CORS_ALLOWED_HEADERS"`
-			AllowCredentials   bool     `yaml:"allow_credentials" env:"HTTP_CORS_ALLOW_CREDENTIALS"`
-			OptionsPassthrough bool     `yaml:"options_passthrough" env:"HTTP_CORS_OPTIONS_PASSWORD"`
-			ExposedHeaders     []string `yaml:"exposed_headers" env:"HTTP_CORS_EXPOSED_HEADERS"`
-			Debug              bool     `yaml:"debug" env:"HTTP_CORS_DEBUG" env-default:"false"`
+			AllowedMethods     []string `yaml:"allowed_methods" env:"HTTP_CORS_ALLOWED_METHODS" reload:"true"`
+			AllowedOrigins     []string `yaml:"allowed_origins" env:"HTTP_CORS_ALLOWED_ORIGINS" reload:"true"`
+			AllowedHeaders     []string `yaml:"allowed_headers" env:"HTTP_CORS_ALLOWED_HEADERS" reload:"true"`
+			AllowCredentials   bool     `yaml:"allow_credentials" env:"HTTP_CORS_ALLOW_CREDENTIALS" reload:"true"`
+			OptionsPassthrough bool     `yaml:"options_passthrough" env:"HTTP_CORS_OPTIONS_PASSTHROUGH" reload:"true"`
+			ExposedHeaders     []string `yaml:"exposed_headers" env:"HTTP_CORS_EXPOSED_HEADERS" reload:"true"`
+			Debug              bool     `yaml:"debug" env:"HTTP_CORS_DEBUG" env-default:"false" reload:"true"`
 		} `yaml:"cors"`
+		// TrustedProxies lists addresses and CIDR ranges whose X-Forwarded-For header is believed.
+		TrustedProxies []string `yaml:"trusted_proxies" env:"HTTP_TRUSTED_PROXIES" reload:"true"`
+		RateLimit      struct {
+			Enabled bool `yaml:"enabled" env:"HTTP_RATE_LIMIT_ENABLED" env-default:"false" reload:"true"`
+			// Default and Routes values look like "100/1m" or "off", routes are keyed like "POST /api/auth/login".
+			Default         string            `yaml:"default" env:"HTTP_RATE_LIMIT_DEFAULT" env-default:"300/1m" reload:"true"`
+			Routes          map[string]string `yaml:"routes" env:"HTTP_RATE_LIMIT_ROUTES" reload:"true"`
+			Shared          bool              `yaml:"shared" env:"HTTP_RATE_LIMIT_SHARED" env-default:"false"`
+			CleanupInterval time.Duration     `yaml:"cleanup_interval" env:"HTTP_RATE_LIMIT_CLEANUP_INTERVAL" env-default:"5m"`
+		} `yaml:"rate_limit"`
+		// Timeout bounds the handling of a request, queries are canceled when it passes.
+		// Values look like "5s" or "off", routes are keyed like rate_limit routes.
+		Timeout struct {
+			Default string            `yaml:"default" env:"HTTP_TIMEOUT_DEFAULT" env-default:"10s" reload:"true"`
+			Routes  map[string]string `yaml:"routes" env:"HTTP_TIMEOUT_ROUTES" reload:"true"`
+		} `yaml:"timeout"`
+		Compression struct {
+			Enabled bool `yaml:"enabled" env:"HTTP_COMPRESSION_ENABLED" env-default:"true" reload:"true"`
+			// MinSize is the smallest body in bytes that is compressed, smaller ones are not worth it.
+			MinSize int `yaml:"min_size" env:"HTTP_COMPRESSION_MIN_SIZE" env-default:"1024" reload:"true"`
+		} `yaml:"compression"`
+		Idempotency struct {
+			Enabled bool `yaml:"enabled" env:"HTTP_IDEMPOTENCY_ENABLED" env-default:"true" reload:"true"`
+			// TTL is how long responses are replayed for repeated keys.
+			TTL             time.Duration `yaml:"ttl" env:"HTTP_IDEMPOTENCY_TTL" env-default:"24h" reload:"true"`
+			CleanupInterval time.Duration `yaml:"cleanup_interval" env:"HTTP_IDEMPOTENCY_CLEANUP_INTERVAL" env-default:"1h"`
+			// Lease is how long a request in progress holds its key, the key of a crashed instance is free after it.
+			Lease time.Duration `yaml:"lease" env:"HTTP_IDEMPOTENCY_LEASE" env-default:"1m"`
+		} `yaml:"idempotency"`
 	} `yaml:"http"`
 	AppConfig struct {
 		IsDebug   bool   `yaml:"is_debug" env:"IS_DEBUG" env-default:"false"`
-		LogLevel  string `yaml:"log_level" env:"LOG_LEVEL" env-default:"info"`
+		LogLevel  string `yaml:"log_level" env:"LOG_LEVEL" env-default:"info" reload:"true"`
 		AdminUser struct {
 			Email    string `yaml:"email" env:"ADMIN_EMAIL" env-default:"admin@example.com"`
-			Password string `yaml:"password" env:"ADMIN_PASSWORD" env-default:"admin"`
+			Password string `yaml:"password" env:"ADMIN_PASSWORD" env-default:"admin" secret:"true"`
 		} `yaml:"admin_user"`
 	} `yaml:"app_config"`
 	PostgreSQL struct {
 		Host     string `yaml:"host" env:"PGSQL_HOST" env-required:"true"`
 		Port     string `yaml:"port" env:"PGSQL_PORT" env-required:"true"`
 		Username string `yaml:"username" env:"PGSQL_USER" env-required:"true"`
-		Password string `yaml:"password" env:"PGSQL_PASSWORD" env-required:"true"`
+		Password string `yaml:"password" env:"PGSQL_PASSWORD" env-required:"true" secret:"true"`
 		Database string `yaml:"database" env:"PGSQL_DB" env-required:"true"`
-	} `yaml:"postgresql"`
-}
-
-const (
-	EnvConfigPathName  = "CONFIG_PATH"
-	FlagConfigPathName = "config"
-)
 
-var configPath string
-var instance *Config
-var once sync.Once
+		SSLMode     string `yaml:"ssl_mode" env:"PGSQL_SSL_MODE" env-default:"disable"`
+		SSLRootCert string `yaml:"ssl_root_cert" env:"PGSQL_SSL_ROOT_CERT"`
 
-func GetConfig() *Config {
-	once.Do(func() {
-		flag.StringVar(&configPath, FlagConfigPathName, "config/config.local.yaml", "this is app config file")
-		flag.Parse()
+		MaxConns          int32         `yaml:"max_conns" env:"PGSQL_MAX_CONNS" env-default:"10"`
+		MinConns          int32         `yaml:"min_conns" env:"PGSQL_MIN_CONNS" env-default:"0"`
+		MaxConnLifetime   time.Duration `yaml:"max_conn_lifetime" env:"PGSQL_MAX_CONN_LIFETIME" env-default:"1h"`
+		MaxConnIdleTime   time.Duration `yaml:"max_conn_idle_time" env:"PGSQL_MAX_CONN_IDLE_TIME" env-default:"30m"`
+		HealthCheckPeriod time.Duration `yaml:"health_check_period" env:"PGSQL_HEALTH_CHECK_PERIOD" env-default:"1m"`
 
-		log.Print("Config init")
+		ApplicationName  string        `yaml:"application_name" env:"PGSQL_APPLICATION_NAME" env-default:"go-prod"`
+		StatementTimeout time.Duration `yaml:"statement_timeout" env:"PGSQL_STATEMENT_TIMEOUT" env-default:"0s"`
+		SearchPath       string        `yaml:"search_path" env:"PGSQL_SEARCH_PATH"`
 
-		if configPath == "" {
-			configPath = os.Getenv(EnvConfigPathName)
-		}
+		Connect struct {
+			Attempts   int           `yaml:"attempts" env:"PGSQL_CONNECT_ATTEMPTS" env-default:"5"`
+			Delay      time.Duration `yaml:"delay" env:"PGSQL_CONNECT_DELAY" env-default:"1s"`
+			MaxDelay   time.Duration `yaml:"max_delay" env:"PGSQL_CONNECT_MAX_DELAY" env-default:"10s"`
+			MaxElapsed time.Duration `yaml:"max_elapsed" env:"PGSQL_CONNECT_MAX_ELAPSED" env-default:"1m"`
+			Timeout    time.Duration `yaml:"timeout" env:"PGSQL_CONNECT_TIMEOUT" env-default:"5s"`
+		} `yaml:"connect"`
 
-		if configPath == "" {
-			log.Fatal("config file path is empty")
-		}
-
-		instance = &Config{}
+		Migrations struct {
+			AutoApply     bool `yaml:"auto_apply" env:"PGSQL_MIGRATIONS_AUTO_APPLY" env-default:"false"`
+			RequireLatest bool `yaml:"require_latest" env:"PGSQL_MIGRATIONS_REQUIRE_LATEST" env-default:"false"`
+		} `yaml:"migrations"`
+	} `yaml:"postgresql"`
+	Search struct {
+		Autocomplete struct {
+			// CacheSize is the number of hot prefixes kept in memory, 0 disables the cache.
+			CacheSize int           `yaml:"cache_size" env:"SEARCH_AUTOCOMPLETE_CACHE_SIZE" env-default:"1000"`
+			CacheTTL  time.Duration `yaml:"cache_ttl" env:"SEARCH_AUTOCOMPLETE_CACHE_TTL" env-default:"30s"`
+		} `yaml:"autocomplete"`
+		Facets struct {
+			// PriceRanges are the ascending bounds of the price facet, requests may pass their own.
+			PriceRanges []int64 `yaml:"price_ranges" env:"SEARCH_FACETS_PRICE_RANGES" env-default:"1000,5000,10000,50000"`
+		} `yaml:"facets"`
+	} `yaml:"search"`
+	Auth struct {
+		JWTSecret  string        `yaml:"jwt_secret" env:"AUTH_JWT_SECRET" secret:"true"`
+		Issuer     string        `yaml:"issuer" env:"AUTH_ISSUER" env-default:"go-prod"`
+		AccessTTL  time.Duration `yaml:"access_ttl" env:"AUTH_ACCESS_TTL" env-default:"15m"`
+		RefreshTTL time.Duration `yaml:"refresh_ttl" env:"AUTH_REFRESH_TTL" env-default:"720h"`
+	} `yaml:"auth"`
+	Secrets struct {
+		Vault struct {
+			Address   string        `yaml:"address" env:"VAULT_ADDR"`
+			Token     string        `yaml:"token" env:"VAULT_TOKEN" secret:"true"`
+			Mount     string        `yaml:"mount" env:"VAULT_KV_MOUNT" env-default:"secret"`
+			KVVersion int           `yaml:"kv_version" env:"VAULT_KV_VERSION" env-default:"2"`
+			Timeout   time.Duration `yaml:"timeout" env:"VAULT_TIMEOUT" env-default:"5s"`
+		} `yaml:"vault"`
+		RefreshInterval time.Duration `yaml:"refresh_interval" env:"SECRETS_REFRESH_INTERVAL" env-default:"5m"`
+	} `yaml:"secrets"`
 
-		if err := cleanenv.ReadConfig(configPath, instance); err != nil {
-			helpText := "ALVCODE - prod app"
-			help, _ := cleanenv.GetDescription(instance, &helpText)
-			log.Print(help)
-			log.Fatal(err)
-		}
-	})
-	return instance
+	path        string
+	sources     map[string]Source
+	unknownKeys []string
+	secretRefs  map[string]secret.Ref
 }
+
+const (
+	EnvConfigPathName  = "CONFIG_PATH"
+	FlagConfigPathName = "config"
+)
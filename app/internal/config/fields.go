@@ -0,0 +1,149 @@
+package config
+
+import (
+	"fmt"
+	"reflect"
+	"strconv"
+	"strings"
+	"time"
+)
+
+// field is a leaf of the Config tree addressed by the dotted path of its yaml keys, e.g. "http.cors.debug".
+type field struct {
+	path  string
+	tag   reflect.StructTag
+	value reflect.Value
+}
+
+func (f field) env() string {
+	return f.tag.Get("env")
+}
+
+func (f field) defaultValue() (string, bool) {
+	return f.tag.Lookup("env-default")
+}
+
+func (f field) required() bool {
+	return f.tag.Get("env-required") == "true"
+}
+
+func (f field) secret() bool {
+	return f.tag.Get("secret") == "true"
+}
+
+var durationType = reflect.TypeOf(time.Duration(0))
+
+// fields lists the leaves of v, a pointer to a struct, in declaration order.
+func fields(v interface{}) []field {
+	list := make([]field, 0)
+	walk(reflect.ValueOf(v).Elem(), "", &list)
+	return list
+}
+
+func walk(v reflect.Value, prefix string, list *[]field) {
+	t := v.Type()
+	for i := 0; i < t.NumField(); i++ {
+		sf := t.Field(i)
+		if !sf.IsExported() {
+			continue
+		}
+		name := strings.Split(sf.Tag.Get("yaml"), ",")[0]
+		if name == "-" {
+			continue
+		}
+		if name == "" {
+			name = strings.ToLower(sf.Name)
+		}
+		path := name
+		if prefix != "" {
+			path = prefix + "." + name
+		}
+
+		if sf.Type.Kind() == reflect.Struct {
+			walk(v.Field(i), path, list)
+			continue
+		}
+		*list = append(*list, field{path: path, tag: sf.Tag, value: v.Field(i)})
+	}
+}
+
+func fieldMap(v interface{}) map[string]field {
+	list := fields(v)
+	m := make(map[string]field, len(list))
+	for _, f := range list {
+		m[f.path] = f
+	}
+	return m
+}
+
+// set parses s according to the kind of the field, lists are comma separated and maps are "key=value" lists.
+func (f field) set(s string) error {
+	v := f.value
+	if v.Type() == durationType {
+		d, err := time.ParseDuration(s)
+		if err != nil {
+			return err
+		}
+		v.SetInt(int64(d))
+		return nil
+	}
+
+	switch v.Kind() {
+	case reflect.String:
+		v.SetString(s)
+	case reflect.Bool:
+		b, err := strconv.ParseBool(s)
+		if err != nil {
+			return err
+		}
+		v.SetBool(b)
+	case reflect.Int, reflect.Int8, reflect.Int16, reflect.Int32, reflect.Int64:
+		i, err := strconv.ParseInt(s, 10, v.Type().Bits())
+		if err != nil {
+			return err
+		}
+		v.SetInt(i)
+	case reflect.Uint, reflect.Uint8, reflect.Uint16, reflect.Uint32, reflect.Uint64:
+		i, err := strconv.ParseUint(s, 10, v.Type().Bits())
+		if err != nil {
+			return err
+		}
+		v.SetUint(i)
+	case reflect.Float32, reflect.Float64:
+		fl, err := strconv.ParseFloat(s, v.Type().Bits())
+		if err != nil {
+			return err
+		}
+		v.SetFloat(fl)
+	case reflect.Slice:
+		list := reflect.MakeSlice(v.Type(), 0, 0)
+		for _, item := range strings.Split(s, ",") {
+			if item = strings.TrimSpace(item); item == "" {
+				continue
+			}
+			elem := reflect.New(v.Type().Elem()).Elem()
+			if err := (field{value: elem}).set(item); err != nil {
+				return err
+			}
+			list = reflect.Append(list, elem)
+		}
+		v.Set(list)
+	case reflect.Map:
+		m := reflect.MakeMap(v.Type())
+		for _, item := range strings.Split(s, ",") {
+			if item = strings.TrimSpace(item); item == "" {
+				continue
+			}
+			key, value, _ := strings.Cut(item, "=")
+			elem := reflect.New(v.Type().Elem()).Elem()
+			if err := (field{value: elem}).set(strings.TrimSpace(value)); err != nil {
+				return fmt.Errorf("%s: %w", key, err)
+			}
+			m.SetMapIndex(reflect.ValueOf(strings.TrimSpace(key)), elem)
+		}
+		v.Set(m)
+	default:
+		return fmt.Errorf("unsupported type %s", v.Type())
+	}
+	return nil
+}
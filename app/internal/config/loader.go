@@ -0,0 +1,218 @@
+package config
+
+import (
+	"fmt"
+	"gopkg.in/yaml.v3"
+	"os"
+	"reflect"
+	"strings"
+)
+
+// Source tells which layer a config value comes from, later layers override earlier ones.
+type Source string
+
+const (
+	SourceUnset   Source = ""
+	SourceDefault Source = "default"
+	SourceFile    Source = "file"
+	SourceEnv     Source = "env"
+	SourceEnvFile Source = "env_file"
+	SourceFlag    Source = "flag"
+)
+
+type LoadOptions struct {
+	// Path is the optional config file, CONFIG_PATH is used when empty and
+	// the config is read from defaults and env alone when both are empty.
+	Path string
+	// Overrides are values given on the command line, keyed by yaml path such as "http.port".
+	Overrides map[string]string
+}
+
+// Load builds the config from defaults, the optional file, env and command line overrides, in this order.
+func Load(opts LoadOptions) (*Config, error) {
+	cfg := &Config{sources: make(map[string]Source)}
+	list := fields(cfg)
+	p := make(problems, 0)
+
+	for _, f := range list {
+		if value, ok := f.defaultValue(); ok {
+			if err := f.set(value); err != nil {
+				p.add("%s: invalid default %q: %v", f.path, value, err)
+				continue
+			}
+			cfg.sources[f.path] = SourceDefault
+		}
+	}
+
+	cfg.path = opts.Path
+	if cfg.path == "" {
+		cfg.path = os.Getenv(EnvConfigPathName)
+	}
+	if cfg.path != "" {
+		if err := cfg.readFile(); err != nil {
+			return nil, err
+		}
+	}
+
+	for _, f := range list {
+		name := f.env()
+		if name == "" {
+			continue
+		}
+		fileValue, fromFile, err := readEnvFile(name)
+		if err != nil {
+			p.add("%s: failed to read %s%s: %v", f.path, name, FileEnvSuffix, err)
+			continue
+		}
+		value, fromEnv := os.LookupEnv(name)
+		switch {
+		case fromEnv && fromFile:
+			p.add("%s: both %s and %s%s are set", f.path, name, name, FileEnvSuffix)
+			continue
+		case fromFile:
+			value = fileValue
+		case !fromEnv:
+			continue
+		}
+
+		if err := f.set(value); err != nil {
+			p.add("%s: invalid value of %s: %v", f.path, name, err)
+			continue
+		}
+		cfg.sources[f.path] = SourceEnv
+		if fromFile {
+			cfg.sources[f.path] = SourceEnvFile
+		}
+	}
+
+	byPath := fieldMap(cfg)
+	for path, value := range opts.Overrides {
+		f, ok := byPath[path]
+		if !ok {
+			p.add("%s: unknown config key", path)
+			continue
+		}
+		if err := f.set(value); err != nil {
+			p.add("%s: invalid value %q: %v", path, value, err)
+			continue
+		}
+		cfg.sources[path] = SourceFlag
+	}
+
+	for _, f := range list {
+		if f.required() && cfg.sources[f.path] == SourceUnset {
+			p.add("%s: value is required, set it in the config file or %s", f.path, f.env())
+		}
+	}
+
+	if len(p) == 0 {
+		cfg.resolveSecrets(&p)
+	}
+
+	p = append(p, cfg.validate()...)
+	if len(p) > 0 {
+		return nil, &ValidationError{Problems: p}
+	}
+	return cfg, nil
+}
+
+func (c *Config) readFile() error {
+	data, err := os.ReadFile(c.path)
+	if err != nil {
+		return fmt.Errorf("failed to read config file: %w", err)
+	}
+
+	if err = yaml.Unmarshal(data, c); err != nil {
+		return fmt.Errorf("config file parsing error: %w", err)
+	}
+
+	var root yaml.Node
+	if err = yaml.Unmarshal(data, &root); err != nil {
+		return fmt.Errorf("config file parsing error: %w", err)
+	}
+	byPath := fieldMap(c)
+	for _, path := range yamlPaths(&root, "") {
+		if _, ok := byPath[path]; ok {
+			c.sources[path] = SourceFile
+			continue
+		}
+		if f, ok := mapField(byPath, path); ok {
+			c.sources[f.path] = SourceFile
+			continue
+		}
+		c.unknownKeys = append(c.unknownKeys, path)
+	}
+
+	return nil
+}
+
+// mapField finds the map field containing the value at path, such as "a.b" for "a.b.key".
+func mapField(byPath map[string]field, path string) (field, bool) {
+	for i := strings.LastIndex(path, "."); i > 0; i = strings.LastIndex(path[:i], ".") {
+		if f, ok := byPath[path[:i]]; ok && f.value.Kind() == reflect.Map {
+			return f, true
+		}
+	}
+	return field{}, false
+}
+
+// yamlPaths lists the dotted paths of all non-mapping values in the document.
+func yamlPaths(node *yaml.Node, prefix string) []string {
+	switch node.Kind {
+	case yaml.DocumentNode:
+		paths := make([]string, 0)
+		for _, child := range node.Content {
+			paths = append(paths, yamlPaths(child, prefix)...)
+		}
+		return paths
+	case yaml.MappingNode:
+		paths := make([]string, 0)
+		for i := 0; i+1 < len(node.Content); i += 2 {
+			path := node.Content[i].Value
+			if prefix != "" {
+				path = prefix + "." + path
+			}
+			paths = append(paths, yamlPaths(node.Content[i+1], path)...)
+		}
+		return paths
+	default:
+		if prefix == "" {
+			return nil
+		}
+		return []string{prefix}
+	}
+}
+
+// Path returns the config file the config was read from, empty in env-only mode.
+func (c *Config) Path() string {
+	return c.path
+}
+
+// Source returns the layer the value at the yaml path comes from.
+func (c *Config) Source(path string) Source {
+	return c.sources[path]
+}
+
+type Trace struct {
+	Path   string
+	Value  string
+	Source Source
+}
+
+// Trace lists every config value with its source in declaration order, secrets are redacted.
+func (c *Config) Trace() []Trace {
+	list := fields(Redacted(c))
+	trace := make([]Trace, 0, len(list))
+	for _, f := range list {
+		value := fmt.Sprint(f.value.Interface())
+		if ref, ok := c.SecretRef(f.path); ok {
+			value = ref.String()
+		}
+		trace = append(trace, Trace{
+			Path:   f.path,
+			Value:  value,
+			Source: c.sources[f.path],
+		})
+	}
+	return trace
+}
@@ -0,0 +1,172 @@
+package config
+
+import (
+	"errors"
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+)
+
+const testConfig = `
+http:
+  port: 9000
+postgresql:
+  host: db
+  port: "5432"
+  username: app
+  password: secret
+  database: app
+`
+
+func writeConfig(t *testing.T, content string) string {
+	t.Helper()
+	path := filepath.Join(t.TempDir(), "config.yaml")
+	if err := os.WriteFile(path, []byte(content), 0o600); err != nil {
+		t.Fatal(err)
+	}
+	return path
+}
+
+func TestLoadLayers(t *testing.T) {
+	t.Setenv("IS_DEBUG", "true")
+	t.Setenv("HTTP_IP", "0.0.0.0")
+	t.Setenv("PGSQL_HOST", "env-db")
+
+	passwordFile := filepath.Join(t.TempDir(), "password")
+	if err := os.WriteFile(passwordFile, []byte("from-file\n"), 0o600); err != nil {
+		t.Fatal(err)
+	}
+	t.Setenv("PGSQL_PASSWORD_FILE", passwordFile)
+
+	cfg, err := Load(LoadOptions{
+		Path:      writeConfig(t, testConfig),
+		Overrides: map[string]string{"postgresql.username": "flag-user"},
+	})
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	tests := []struct {
+		path       string
+		got        interface{}
+		want       interface{}
+		wantSource Source
+	}{
+		{path: "http.read_timeout", got: cfg.HTTP.ReadTimeout.String(), want: "15s", wantSource: SourceDefault},
+		{path: "http.port", got: cfg.HTTP.Port, want: 9000, wantSource: SourceFile},
+		{path: "http.ip", got: cfg.HTTP.IP, want: "0.0.0.0", wantSource: SourceEnv},
+		{path: "postgresql.host", got: cfg.PostgreSQL.Host, want: "env-db", wantSource: SourceEnv},
+		{path: "postgresql.password", got: cfg.PostgreSQL.Password, want: "from-file", wantSource: SourceEnvFile},
+		{path: "postgresql.username", got: cfg.PostgreSQL.Username, want: "flag-user", wantSource: SourceFlag},
+		{path: "auth.jwt_secret", got: cfg.Auth.JWTSecret, want: "", wantSource: SourceUnset},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.path, func(t *testing.T) {
+			if tt.got != tt.want {
+				t.Errorf("value = %v, want %v", tt.got, tt.want)
+			}
+			if source := cfg.Source(tt.path); source != tt.wantSource {
+				t.Errorf("source = %q, want %q", source, tt.wantSource)
+			}
+		})
+	}
+
+	if prices := cfg.Search.Facets.PriceRanges; len(prices) != 4 || prices[0] != 1000 {
+		t.Errorf("search.facets.price_ranges = %v, want the default ranges", prices)
+	}
+}
+
+func TestLoadProblems(t *testing.T) {
+	tests := []struct {
+		name      string
+		config    string
+		env       map[string]string
+		overrides map[string]string
+		want      []string
+	}{
+		{
+			name:   "unknown file key",
+			config: testConfig + "unknown:\n  key: 1\n",
+			want:   []string{"unknown.key: unknown config key"},
+		},
+		{
+			name:   "required value missing",
+			config: "app_config:\n  is_debug: true\n",
+			want: []string{
+				"postgresql.host: value is required, set it in the config file or PGSQL_HOST",
+				"postgresql.database: value is required",
+			},
+		},
+		{
+			name:   "invalid env value",
+			config: testConfig,
+			env:    map[string]string{"HTTP_PORT": "eighty"},
+			want:   []string{"http.port: invalid value of HTTP_PORT"},
+		},
+		{
+			name:   "env and _FILE at once",
+			config: testConfig,
+			env:    map[string]string{"PGSQL_USER": "a", "PGSQL_USER_FILE": "/nonexistent"},
+			want:   []string{"postgresql.username: failed to read PGSQL_USER_FILE"},
+		},
+		{
+			name:      "unknown override",
+			config:    testConfig,
+			overrides: map[string]string{"http.nope": "1"},
+			want:      []string{"http.nope: unknown config key"},
+		},
+		{
+			name:   "all problems reported at once",
+			config: testConfig + "search:\n  autocomplete:\n    cache_size: -1\n",
+			env:    map[string]string{"HTTP_IP": "localhost"},
+			want: []string{
+				`http.ip: "localhost" is not an IP address`,
+				"search.autocomplete.cache_size: must not be negative",
+			},
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			t.Setenv("IS_DEBUG", "true")
+			for name, value := range tt.env {
+				t.Setenv(name, value)
+			}
+
+			_, err := Load(LoadOptions{Path: writeConfig(t, tt.config), Overrides: tt.overrides})
+			var validationErr *ValidationError
+			if !errors.As(err, &validationErr) {
+				t.Fatalf("err = %v, want a ValidationError", err)
+			}
+			for _, want := range tt.want {
+				if !containsProblem(validationErr.Problems, want) {
+					t.Errorf("problems %q do not contain %q", validationErr.Problems, want)
+				}
+			}
+		})
+	}
+}
+
+func TestLoadBrokenFile(t *testing.T) {
+	_, err := Load(LoadOptions{Path: writeConfig(t, "database: go-prod  ssl_mode: disable\n")})
+	if err == nil || !strings.Contains(err.Error(), "config file parsing error") {
+		t.Errorf("err = %v, want a parsing error", err)
+	}
+}
+
+func TestLoadLocalConfig(t *testing.T) {
+	if _, err := Load(LoadOptions{Path: "../../../config/config.local.yaml"}); err != nil {
+		t.Fatalf("config.local.yaml does not load: %v", err)
+	}
+}
+
+func containsProblem(problems []string, want string) bool {
+	for _, p := range problems {
+		if strings.Contains(p, want) {
+			return true
+		}
+	}
+	return false
+}
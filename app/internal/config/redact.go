@@ -0,0 +1,24 @@
+package config
+
+import "reflect"
+
+const redactedValue = "******"
+
+// Redacted returns a copy of cfg with every non-empty field tagged `secret:"true"` masked.
+func Redacted(cfg *Config) *Config {
+	c := *cfg
+	redact(reflect.ValueOf(&c).Elem())
+	return &c
+}
+
+func redact(v reflect.Value) {
+	for i := 0; i < v.NumField(); i++ {
+		field := v.Field(i)
+		switch {
+		case field.Kind() == reflect.Struct:
+			redact(field)
+		case v.Type().Field(i).Tag.Get("secret") == "true" && field.Kind() == reflect.String && field.String() != "":
+			field.SetString(redactedValue)
+		}
+	}
+}
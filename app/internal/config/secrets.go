@@ -0,0 +1,88 @@
+package config
+
+import (
+	"context"
+	"fmt"
+	"os"
+	"prod/pkg/secret"
+	"prod/pkg/secret/vault"
+	"reflect"
+	"strings"
+)
+
+// FileEnvSuffix marks env variables holding a path to a file with the value, as mounted by Docker and Kubernetes secrets.
+const FileEnvSuffix = "_FILE"
+
+func readEnvFile(name string) (string, bool, error) {
+	path, ok := os.LookupEnv(name + FileEnvSuffix)
+	if !ok {
+		return "", false, nil
+	}
+	data, err := os.ReadFile(path)
+	if err != nil {
+		return "", true, err
+	}
+	return strings.TrimRight(string(data), "\r\n"), true, nil
+}
+
+// SecretProvider returns the configured secret provider, nil when there is none.
+func (c *Config) SecretProvider() secret.Provider {
+	v := c.Secrets.Vault
+	if v.Address == "" {
+		return nil
+	}
+	return vault.NewClient(vault.Config{
+		Address:   v.Address,
+		Token:     v.Token,
+		Mount:     v.Mount,
+		KVVersion: v.KVVersion,
+		Timeout:   v.Timeout,
+	})
+}
+
+// SecretRef returns the reference the value at the yaml path was resolved from.
+func (c *Config) SecretRef(path string) (secret.Ref, bool) {
+	ref, ok := c.secretRefs[path]
+	return ref, ok
+}
+
+// resolveSecrets replaces references in fields tagged `secret:"true"` with values from the secret provider.
+func (c *Config) resolveSecrets(p *problems) {
+	c.secretRefs = make(map[string]secret.Ref)
+
+	for _, f := range fields(c) {
+		if !f.secret() || f.value.Kind() != reflect.String {
+			continue
+		}
+		ref, ok, err := secret.ParseRef(f.value.String())
+		if err != nil {
+			p.add("%s: %v", f.path, err)
+			continue
+		}
+		if ok {
+			c.secretRefs[f.path] = ref
+		}
+	}
+	if len(c.secretRefs) == 0 {
+		return
+	}
+
+	provider := c.SecretProvider()
+	if provider == nil {
+		p.add("secrets.vault.address: is required to resolve secret references")
+		return
+	}
+
+	ctx, cancel := context.WithTimeout(context.Background(), c.Secrets.Vault.Timeout)
+	defer cancel()
+
+	byPath := fieldMap(c)
+	for path, ref := range c.secretRefs {
+		value, err := provider.Get(ctx, ref)
+		if err != nil {
+			p.add("%s: %v", path, fmt.Errorf("failed to resolve %s: %w", ref, err))
+			continue
+		}
+		byPath[path].value.SetString(value)
+	}
+}
@@ -0,0 +1,176 @@
+package config
+
+import (
+	"fmt"
+	"github.com/sirupsen/logrus"
+	"net"
+	"prod/pkg/ratelimit"
+	"prod/pkg/timeout"
+	"slices"
+	"strconv"
+	"strings"
+)
+
+// ValidationError reports every problem found in the config at once.
+type ValidationError struct {
+	Problems []string
+}
+
+func (e *ValidationError) Error() string {
+	return "invalid config:\n  - " + strings.Join(e.Problems, "\n  - ")
+}
+
+type problems []string
+
+func (p *problems) add(format string, args ...interface{}) {
+	*p = append(*p, fmt.Sprintf(format, args...))
+}
+
+const minJWTSecretLength = 32
+
+// defaultAdminPassword is the env-default of app_config.admin_user.password, meant for local runs only.
+const defaultAdminPassword = "admin"
+
+var sslModes = []string{"disable", "allow", "prefer", "require", "verify-ca", "verify-full"}
+
+// Validate checks values that are well-formed but make no sense together or for the service.
+func (c *Config) Validate() error {
+	p := c.validate()
+	if len(p) == 0 {
+		return nil
+	}
+	return &ValidationError{Problems: p}
+}
+
+func (c *Config) validate() problems {
+	p := make(problems, 0)
+
+	for _, key := range c.unknownKeys {
+		p.add("%s: unknown config key in %s", key, c.path)
+	}
+
+	if net.ParseIP(c.HTTP.IP) == nil {
+		p.add("http.ip: %q is not an IP address", c.HTTP.IP)
+	}
+	validatePort(&p, "http.port", c.HTTP.Port)
+	positive(&p, "http.read_timeout", int64(c.HTTP.ReadTimeout))
+	positive(&p, "http.write_timeout", int64(c.HTTP.WriteTimeout))
+
+	cors := c.HTTP.CORS
+	if cors.AllowCredentials {
+		if slices.Contains(cors.AllowedOrigins, "*") || len(cors.AllowedOrigins) == 0 {
+			p.add("http.cors: allow_credentials can not be combined with any origin (\"*\" or empty allowed_origins), browsers reject such responses")
+		}
+		if slices.Contains(cors.AllowedHeaders, "*") {
+			p.add("http.cors: allowed_headers \"*\" is not a wildcard for requests with credentials, list the headers explicitly")
+		}
+		if slices.Contains(cors.ExposedHeaders, "*") {
+			p.add("http.cors: exposed_headers \"*\" is not a wildcard for requests with credentials, list the headers explicitly")
+		}
+	}
+
+	if _, err := ratelimit.NewIPResolver(c.HTTP.TrustedProxies); err != nil {
+		p.add("http.trusted_proxies: %v", err)
+	}
+	rateLimit := c.HTTP.RateLimit
+	if rateLimit.Enabled {
+		if _, err := ratelimit.NewRules(rateLimit.Default, rateLimit.Routes); err != nil {
+			p.add("http.rate_limit: %v", err)
+		}
+	}
+	if rateLimit.Shared {
+		positive(&p, "http.rate_limit.cleanup_interval", int64(rateLimit.CleanupInterval))
+	}
+	if c.HTTP.Compression.MinSize < 0 {
+		p.add("http.compression.min_size: must not be negative")
+	}
+	positive(&p, "http.idempotency.ttl", int64(c.HTTP.Idempotency.TTL))
+	positive(&p, "http.idempotency.cleanup_interval", int64(c.HTTP.Idempotency.CleanupInterval))
+	positive(&p, "http.idempotency.lease", int64(c.HTTP.Idempotency.Lease))
+	if _, err := timeout.NewRules(c.HTTP.Timeout.Default, c.HTTP.Timeout.Routes); err != nil {
+		p.add("http.timeout: %v", err)
+	}
+
+	if c.Search.Autocomplete.CacheSize < 0 {
+		p.add("search.autocomplete.cache_size: must not be negative")
+	}
+	if c.Search.Autocomplete.CacheSize > 0 {
+		positive(&p, "search.autocomplete.cache_ttl", int64(c.Search.Autocomplete.CacheTTL))
+	}
+
+	if !slices.IsSorted(c.Search.Facets.PriceRanges) || len(slices.Compact(slices.Clone(c.Search.Facets.PriceRanges))) != len(c.Search.Facets.PriceRanges) {
+		p.add("search.facets.price_ranges: must be strictly ascending")
+	}
+
+	if _, err := logrus.ParseLevel(c.AppConfig.LogLevel); err != nil {
+		p.add("app_config.log_level: %v", err)
+	}
+	if !c.AppConfig.IsDebug {
+		if c.AppConfig.AdminUser.Email == "" {
+			p.add("app_config.admin_user.email: must be set when is_debug is false")
+		}
+		switch c.AppConfig.AdminUser.Password {
+		case "":
+			p.add("app_config.admin_user.password: must be set when is_debug is false")
+		case defaultAdminPassword:
+			p.add("app_config.admin_user.password: the default password is only allowed when is_debug is true")
+		}
+	}
+
+	if !c.AppConfig.IsDebug && len(c.Auth.JWTSecret) < minJWTSecretLength {
+		p.add("auth.jwt_secret: must be at least %d bytes long when is_debug is false", minJWTSecretLength)
+	}
+	positive(&p, "auth.access_ttl", int64(c.Auth.AccessTTL))
+	if c.Auth.RefreshTTL <= c.Auth.AccessTTL {
+		p.add("auth.refresh_ttl: must be greater than access_ttl")
+	}
+
+	pg := c.PostgreSQL
+	if pg.Port != "" {
+		if port, err := strconv.Atoi(pg.Port); err != nil {
+			p.add("postgresql.port: %q is not a number", pg.Port)
+		} else {
+			validatePort(&p, "postgresql.port", port)
+		}
+	}
+	if !slices.Contains(sslModes, pg.SSLMode) {
+		p.add("postgresql.ssl_mode: %q is not one of %s", pg.SSLMode, strings.Join(sslModes, ", "))
+	}
+	if pg.SSLRootCert != "" && (pg.SSLMode == "disable" || pg.SSLMode == "allow") {
+		p.add("postgresql.ssl_root_cert: is ignored with ssl_mode %q", pg.SSLMode)
+	}
+	positive(&p, "postgresql.max_conns", int64(pg.MaxConns))
+	notNegative(&p, "postgresql.min_conns", int64(pg.MinConns))
+	if pg.MinConns > pg.MaxConns {
+		p.add("postgresql.min_conns: %d is greater than max_conns %d", pg.MinConns, pg.MaxConns)
+	}
+	positive(&p, "postgresql.max_conn_lifetime", int64(pg.MaxConnLifetime))
+	positive(&p, "postgresql.max_conn_idle_time", int64(pg.MaxConnIdleTime))
+	positive(&p, "postgresql.health_check_period", int64(pg.HealthCheckPeriod))
+	notNegative(&p, "postgresql.statement_timeout", int64(pg.StatementTimeout))
+	positive(&p, "postgresql.connect.attempts", int64(pg.Connect.Attempts))
+	notNegative(&p, "postgresql.connect.delay", int64(pg.Connect.Delay))
+	notNegative(&p, "postgresql.connect.max_delay", int64(pg.Connect.MaxDelay))
+	notNegative(&p, "postgresql.connect.max_elapsed", int64(pg.Connect.MaxElapsed))
+	positive(&p, "postgresql.connect.timeout", int64(pg.Connect.Timeout))
+
+	return p
+}
+
+func validatePort(p *problems, path string, port int) {
+	if port < 1 || port > 65535 {
+		p.add("%s: %d is out of range 1-65535", path, port)
+	}
+}
+
+func positive(p *problems, path string, value int64) {
+	if value <= 0 {
+		p.add("%s: must be positive", path)
+	}
+}
+
+func notNegative(p *problems, path string, value int64) {
+	if value < 0 {
+		p.add("%s: must not be negative", path)
+	}
+}
@@ -0,0 +1,149 @@
+package config
+
+import (
+	"errors"
+	"strings"
+	"testing"
+	"time"
+)
+
+// validConfig loads a config that passes validation, cases break one value of it.
+func validConfig(t *testing.T) *Config {
+	t.Helper()
+	t.Setenv("IS_DEBUG", "true")
+	cfg, err := Load(LoadOptions{Path: writeConfig(t, testConfig)})
+	if err != nil {
+		t.Fatal(err)
+	}
+	return cfg
+}
+
+func TestValidate(t *testing.T) {
+	tests := []struct {
+		name   string
+		modify func(c *Config)
+		want   string
+	}{
+		{name: "valid", modify: func(c *Config) {}},
+		{
+			name:   "http port out of range",
+			modify: func(c *Config) { c.HTTP.Port = 70000 },
+			want:   "http.port: 70000 is out of range 1-65535",
+		},
+		{
+			name: "credentials with any origin",
+			modify: func(c *Config) {
+				c.HTTP.CORS.AllowCredentials = true
+				c.HTTP.CORS.AllowedOrigins = []string{"*"}
+			},
+			want: "http.cors: allow_credentials can not be combined with any origin",
+		},
+		{
+			name:   "invalid trusted proxy",
+			modify: func(c *Config) { c.HTTP.TrustedProxies = []string{"not-an-ip"} },
+			want:   "http.trusted_proxies:",
+		},
+		{
+			name: "invalid rate limit",
+			modify: func(c *Config) {
+				c.HTTP.RateLimit.Enabled = true
+				c.HTTP.RateLimit.Default = "fast"
+			},
+			want: "http.rate_limit:",
+		},
+		{
+			name:   "invalid timeout",
+			modify: func(c *Config) { c.HTTP.Timeout.Default = "-1s" },
+			want:   "http.timeout:",
+		},
+		{
+			name:   "unsorted price ranges",
+			modify: func(c *Config) { c.Search.Facets.PriceRanges = []int64{100, 100, 50} },
+			want:   "search.facets.price_ranges: must be strictly ascending",
+		},
+		{
+			name:   "unknown log level",
+			modify: func(c *Config) { c.AppConfig.LogLevel = "loud" },
+			want:   "app_config.log_level:",
+		},
+		{
+			name: "missing admin password",
+			modify: func(c *Config) {
+				c.AppConfig.IsDebug = false
+				c.AppConfig.AdminUser.Password = ""
+			},
+			want: "app_config.admin_user.password: must be set when is_debug is false",
+		},
+		{
+			name:   "default admin password",
+			modify: func(c *Config) { c.AppConfig.IsDebug = false },
+			want:   "app_config.admin_user.password: the default password is only allowed when is_debug is true",
+		},
+		{
+			name:   "default admin password in debug",
+			modify: func(c *Config) { c.AppConfig.AdminUser.Password = defaultAdminPassword },
+		},
+		{
+			name:   "short jwt secret",
+			modify: func(c *Config) { c.AppConfig.IsDebug = false },
+			want:   "auth.jwt_secret: must be at least 32 bytes long",
+		},
+		{
+			name:   "refresh shorter than access",
+			modify: func(c *Config) { c.Auth.RefreshTTL = time.Minute },
+			want:   "auth.refresh_ttl: must be greater than access_ttl",
+		},
+		{
+			name:   "unknown ssl mode",
+			modify: func(c *Config) { c.PostgreSQL.SSLMode = "always" },
+			want:   `postgresql.ssl_mode: "always" is not one of`,
+		},
+		{
+			name:   "root cert without tls",
+			modify: func(c *Config) { c.PostgreSQL.SSLRootCert = "/ca.pem" },
+			want:   `postgresql.ssl_root_cert: is ignored with ssl_mode "disable"`,
+		},
+		{
+			name:   "min conns above max conns",
+			modify: func(c *Config) { c.PostgreSQL.MinConns = 20 },
+			want:   "postgresql.min_conns: 20 is greater than max_conns 10",
+		},
+		{
+			name:   "zero connect timeout",
+			modify: func(c *Config) { c.PostgreSQL.Connect.Timeout = 0 },
+			want:   "postgresql.connect.timeout: must be positive",
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			cfg := validConfig(t)
+			tt.modify(cfg)
+
+			err := cfg.Validate()
+			if tt.want == "" {
+				if err != nil {
+					t.Fatalf("unexpected error: %v", err)
+				}
+				return
+			}
+			var validationErr *ValidationError
+			if !errors.As(err, &validationErr) {
+				t.Fatalf("err = %v, want a ValidationError", err)
+			}
+			if !containsProblem(validationErr.Problems, tt.want) {
+				t.Errorf("problems %q do not contain %q", validationErr.Problems, tt.want)
+			}
+		})
+	}
+}
+
+func TestValidationErrorListsAllProblems(t *testing.T) {
+	err := (&ValidationError{Problems: []string{"a: bad", "b: worse"}}).Error()
+	if want := "invalid config:\n  - a: bad\n  - b: worse"; err != want {
+		t.Errorf("Error() = %q, want %q", err, want)
+	}
+	if strings.Count(err, "\n") != 2 {
+		t.Errorf("Error() = %q, want one line per problem", err)
+	}
+}
@@ -0,0 +1,145 @@
+package config
+
+import (
+	"context"
+	"fmt"
+	"github.com/fsnotify/fsnotify"
+	"os"
+	"os/signal"
+	"path/filepath"
+	"prod/pkg/logging"
+	"reflect"
+	"sync"
+	"sync/atomic"
+	"syscall"
+	"time"
+)
+
+const reloadDebounce = 200 * time.Millisecond
+
+// Watcher reloads the config when its file changes or the process receives SIGHUP.
+// Only fields tagged `reload:"true"` may change, a reload touching any other field is refused as a whole.
+type Watcher struct {
+	opts    LoadOptions
+	current atomic.Pointer[Config]
+
+	mu          sync.Mutex
+	subscribers []func(cfg *Config)
+}
+
+func NewWatcher(opts LoadOptions, initial *Config) *Watcher {
+	w := &Watcher{opts: opts}
+	w.current.Store(initial)
+	return w
+}
+
+func (w *Watcher) Current() *Config {
+	return w.current.Load()
+}
+
+// Subscribe registers fn to be called with every applied config, fn must not block.
+func (w *Watcher) Subscribe(fn func(cfg *Config)) {
+	w.mu.Lock()
+	defer w.mu.Unlock()
+	w.subscribers = append(w.subscribers, fn)
+}
+
+// Run watches for changes until ctx is done.
+func (w *Watcher) Run(ctx context.Context) error {
+	hup := make(chan os.Signal, 1)
+	signal.Notify(hup, syscall.SIGHUP)
+	defer signal.Stop(hup)
+
+	var events chan fsnotify.Event
+	var errs chan error
+	path := w.Current().Path()
+	if path != "" {
+		fw, err := fsnotify.NewWatcher()
+		if err != nil {
+			return fmt.Errorf("failed to create config watcher: %w", err)
+		}
+		defer fw.Close()
+
+		// the directory is watched so that atomic replacements (rename, Kubernetes ConfigMap symlink swap) are seen
+		if err = fw.Add(filepath.Dir(path)); err != nil {
+			return fmt.Errorf("failed to watch config file: %w", err)
+		}
+		events, errs = fw.Events, fw.Errors
+	}
+
+	debounce := time.NewTimer(reloadDebounce)
+	debounce.Stop()
+	defer debounce.Stop()
+
+	for {
+		select {
+		case <-ctx.Done():
+			return nil
+		case <-hup:
+			logging.GetLogger(ctx).Infoln("SIGHUP received, reloading config")
+			w.Reload(ctx)
+		case event := <-events:
+			if filepath.Clean(event.Name) == filepath.Clean(path) || event.Has(fsnotify.Create) {
+				debounce.Reset(reloadDebounce)
+			}
+		case <-debounce.C:
+			w.Reload(ctx)
+		case err := <-errs:
+			logging.GetLogger(ctx).WithError(err).Errorln("config watcher error")
+		}
+	}
+}
+
+// Reload loads the config again and applies it if only reloadable fields have changed.
+func (w *Watcher) Reload(ctx context.Context) {
+	old := w.Current()
+	opts := w.opts
+	opts.Path = old.Path()
+
+	cfg, err := Load(opts)
+	if err != nil {
+		logging.GetLogger(ctx).WithError(err).Errorln("config reload failed, keeping the current config")
+		return
+	}
+
+	changed, refused := diff(old, cfg)
+	if len(refused) > 0 {
+		logging.GetLogger(ctx).WithField("fields", refused).
+			Errorln("config reload refused, these fields require a restart")
+		return
+	}
+	if len(changed) == 0 {
+		return
+	}
+
+	w.current.Store(cfg)
+	logging.GetLogger(ctx).WithField("fields", changed).Infoln("config reloaded")
+
+	w.mu.Lock()
+	subscribers := append([]func(cfg *Config){}, w.subscribers...)
+	w.mu.Unlock()
+	for _, fn := range subscribers {
+		fn(cfg)
+	}
+}
+
+func diff(old, cfg *Config) (changed []string, refused []string) {
+	oldFields := fieldMap(old)
+	for _, f := range fields(cfg) {
+		if reflect.DeepEqual(oldFields[f.path].value.Interface(), f.value.Interface()) {
+			continue
+		}
+		// rotated secrets are picked up by their consumers, only a changed reference needs a restart
+		oldRef, oldOk := old.SecretRef(f.path)
+		ref, ok := cfg.SecretRef(f.path)
+		if oldOk && ok && oldRef == ref {
+			continue
+		}
+		if f.tag.Get("reload") == "true" {
+			changed = append(changed, f.path)
+		} else {
+			refused = append(refused, f.path)
+		}
+	}
+	return changed, refused
+}
@@ -0,0 +1,86 @@
+package config
+
+import (
+	"context"
+	"os"
+	"reflect"
+	"testing"
+)
+
+func TestWatcherReload(t *testing.T) {
+	tests := []struct {
+		name        string
+		config      string
+		wantApplied bool
+	}{
+		{
+			name:        "reloadable field",
+			config:      testConfig + "app_config:\n  log_level: debug\n",
+			wantApplied: true,
+		},
+		{
+			name:   "field requiring a restart",
+			config: testConfig + "app_config:\n  log_level: debug\nauth:\n  access_ttl: 5m\n",
+		},
+		{
+			name:   "invalid config",
+			config: testConfig + "app_config:\n  log_level: loud\n",
+		},
+		{
+			name:   "nothing changed",
+			config: testConfig,
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			t.Setenv("IS_DEBUG", "true")
+			path := writeConfig(t, testConfig)
+			initial, err := Load(LoadOptions{Path: path})
+			if err != nil {
+				t.Fatal(err)
+			}
+			w := NewWatcher(LoadOptions{}, initial)
+			notified := 0
+			w.Subscribe(func(cfg *Config) { notified++ })
+
+			if err = os.WriteFile(path, []byte(tt.config), 0o600); err != nil {
+				t.Fatal(err)
+			}
+			w.Reload(context.Background())
+
+			applied := w.Current() != initial
+			if applied != tt.wantApplied {
+				t.Errorf("applied = %v, want %v", applied, tt.wantApplied)
+			}
+			if applied && w.Current().AppConfig.LogLevel != "debug" {
+				t.Errorf("log_level = %q, want debug", w.Current().AppConfig.LogLevel)
+			}
+			if wantNotified := map[bool]int{true: 1}[tt.wantApplied]; notified != wantNotified {
+				t.Errorf("subscribers notified %d times, want %d", notified, wantNotified)
+			}
+		})
+	}
+}
+
+func TestDiff(t *testing.T) {
+	t.Setenv("IS_DEBUG", "true")
+	old, err := Load(LoadOptions{Path: writeConfig(t, testConfig)})
+	if err != nil {
+		t.Fatal(err)
+	}
+	cfg, err := Load(LoadOptions{Path: writeConfig(t, testConfig)})
+	if err != nil {
+		t.Fatal(err)
+	}
+	cfg.HTTP.CORS.AllowedOrigins = []string{"https://example.com"}
+	cfg.HTTP.Port = 9001
+
+	changed, refused := diff(old, cfg)
+	if want := []string{"http.cors.allowed_origins"}; !reflect.DeepEqual(changed, want) {
+		t.Errorf("changed = %v, want %v", changed, want)
+	}
+	if want := []string{"http.port"}; !reflect.DeepEqual(refused, want) {
+		t.Errorf("refused = %v, want %v", refused, want)
+	}
+}
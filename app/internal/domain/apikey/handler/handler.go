@@ -0,0 +1,121 @@
+package handler
+
+import (
+	"encoding/json"
+	"github.com/julienschmidt/httprouter"
+	"net/http"
+	"prod/internal/domain/apikey/model"
+	"prod/internal/domain/apikey/storage"
+	"prod/internal/rbac"
+	"prod/pkg/apperror"
+	"prod/pkg/auth"
+	"prod/pkg/httperr"
+	"prod/pkg/response"
+	"time"
+)
+
+const (
+	URL    = "/api/admin/api-keys"
+	oneURL = "/api/admin/api-keys/:id"
+)
+
+type Handler struct {
+	storage *storage.APIKeyStorage
+	guard   auth.Guard
+}
+
+func NewHandler(storage *storage.APIKeyStorage, guard auth.Guard) *Handler {
+	return &Handler{
+		storage: storage,
+		guard:   guard,
+	}
+}
+
+func (h *Handler) Register(router *httprouter.Router) {
+	manage := h.guard(rbac.UsersManage)
+	router.Handler(http.MethodGet, URL, manage(http.HandlerFunc(h.List)))
+	router.Handler(http.MethodPost, URL, manage(http.HandlerFunc(h.Create)))
+	router.Handler(http.MethodDelete, oneURL, manage(http.HandlerFunc(h.Revoke)))
+}
+
+type CreateRequest struct {
+	Name      string            `json:"name"`
+	Scopes    []auth.Permission `json:"scopes"`
+	ExpiresAt *time.Time        `json:"expires_at"`
+}
+
+// List
+// @Summary List API keys
+// @Tags Admin
+// @Produce json
+// @Security BearerAuth
+// @Success 200 {array} model.APIKey
+// @Failure 401 {object} httperr.Problem
+// @Failure 403 {object} httperr.Problem
+// @Router /api/admin/api-keys [get]
+func (h *Handler) List(w http.ResponseWriter, r *http.Request) {
+	list, err := h.storage.All(r.Context())
+	if err != nil {
+		httperr.Write(w, r, err)
+		return
+	}
+
+	response.JSON(w, http.StatusOK, list)
+}
+
+// Create
+// @Summary Create an API key, the key is only shown in this response
+// @Tags Admin
+// @Accept json
+// @Produce json
+// @Security BearerAuth
+// @Param key body CreateRequest true "API key"
+// @Success 201 {object} model.Created
+// @Failure 400 {object} httperr.Problem
+// @Failure 401 {object} httperr.Problem
+// @Failure 403 {object} httperr.Problem
+// @Router /api/admin/api-keys [post]
+func (h *Handler) Create(w http.ResponseWriter, r *http.Request) {
+	req := CreateRequest{}
+	if err := json.NewDecoder(r.Body).Decode(&req); err != nil {
+		httperr.Write(w, r, apperror.InvalidInput("malformed request body"))
+		return
+	}
+	k := model.APIKey{Name: req.Name, Scopes: req.Scopes, ExpiresAt: req.ExpiresAt}
+	if err := k.Validate(); err != nil {
+		httperr.Write(w, r, err)
+		return
+	}
+	if principal, ok := auth.PrincipalFromContext(r.Context()); ok && !principal.APIKey {
+		k.CreatedBy = &principal.Subject
+	}
+
+	created, err := h.storage.Issue(r.Context(), k)
+	if err != nil {
+		httperr.Write(w, r, err)
+		return
+	}
+
+	response.JSON(w, http.StatusCreated, created)
+}
+
+// Revoke
+// @Summary Revoke an API key
+// @Tags Admin
+// @Produce json
+// @Security BearerAuth
+// @Param id path string true "API key ID"
+// @Success 200 {object} model.APIKey
+// @Failure 401 {object} httperr.Problem
+// @Failure 403 {object} httperr.Problem
+// @Failure 404 {object} httperr.Problem
+// @Router /api/admin/api-keys/{id} [delete]
+func (h *Handler) Revoke(w http.ResponseWriter, r *http.Request) {
+	k, err := h.storage.Revoke(r.Context(), httprouter.ParamsFromContext(r.Context()).ByName("id"))
+	if err != nil {
+		httperr.Write(w, r, err)
+		return
+	}
+
+	response.JSON(w, http.StatusOK, k)
+}
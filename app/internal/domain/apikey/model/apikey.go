@@ -0,0 +1,46 @@
+package model
+
+import (
+	"prod/internal/rbac"
+	"prod/pkg/apperror"
+	"prod/pkg/auth"
+	"time"
+)
+
+type APIKey struct {
+	Id         string            `json:"id"`
+	Name       string            `json:"name"`
+	Prefix     string            `json:"prefix"`
+	Hash       string            `json:"-"`
+	Scopes     []auth.Permission `json:"scopes"`
+	ExpiresAt  *time.Time        `json:"expires_at"`
+	LastUsedAt *time.Time        `json:"last_used_at"`
+	RevokedAt  *time.Time        `json:"revoked_at"`
+	CreatedBy  *string           `json:"created_by"`
+	CreatedAt  time.Time         `json:"created_at"`
+}
+
+// Created is returned once on creation, the key itself is never stored.
+type Created struct {
+	APIKey
+	Key string `json:"key"`
+}
+
+// Validate checks the attributes of a new key.
+func (k APIKey) Validate() error {
+	if k.Name == "" {
+		return apperror.InvalidInput("name is required")
+	}
+	if len(k.Scopes) == 0 {
+		return apperror.InvalidInput("at least one scope is required")
+	}
+	for _, scope := range k.Scopes {
+		if !rbac.Known(scope) {
+			return apperror.InvalidInput("unknown scope %q", scope)
+		}
+	}
+	if k.ExpiresAt != nil && !k.ExpiresAt.After(time.Now()) {
+		return apperror.InvalidInput("expires_at must be in the future")
+	}
+	return nil
+}
@@ -0,0 +1,186 @@
+package storage
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	sq "github.com/Masterminds/squirrel"
+	"github.com/jackc/pgx/v4"
+	"prod/internal/domain/apikey/model"
+	"prod/pkg/apperror"
+	"prod/pkg/auth"
+	"prod/pkg/client/postgresql"
+	db "prod/pkg/client/postgresql/model"
+	"strings"
+)
+
+type APIKeyStorage struct {
+	queryBuilder sq.StatementBuilderType
+	client       postgresql.Client
+}
+
+func NewAPIKeyStorage(client postgresql.Client) *APIKeyStorage {
+	return &APIKeyStorage{
+		queryBuilder: sq.StatementBuilder.PlaceholderFormat(sq.Dollar),
+		client:       client,
+	}
+}
+
+const (
+	scheme = "public"
+	table  = "api_key"
+
+	// touchInterval limits last_used_at updates to one per key and interval.
+	touchInterval = "1 minute"
+)
+
+var columns = []string{
+	"id", "name", "prefix", "key_hash", "scopes", "expires_at", "last_used_at", "revoked_at", "created_by", "created_at",
+}
+
+func (s *APIKeyStorage) All(ctx context.Context) ([]model.APIKey, error) {
+	query := s.queryBuilder.Select(columns...).
+		From(scheme + "." + table).
+		OrderBy("created_at DESC")
+
+	sql, args, err := query.ToSql()
+	if err != nil {
+		return nil, db.ErrCreateQuery(err)
+	}
+
+	rows, err := s.client.Query(ctx, sql, args...)
+	if err != nil {
+		return nil, db.ErrDoQuery(postgresql.ClassifyError(err))
+	}
+
+	defer rows.Close()
+
+	list := make([]model.APIKey, 0)
+	for rows.Next() {
+		k, err := scanAPIKey(rows)
+		if err != nil {
+			return nil, db.ErrScan(postgresql.ClassifyError(err))
+		}
+		list = append(list, k)
+	}
+	if err = rows.Err(); err != nil {
+		return nil, db.ErrDoQuery(postgresql.ClassifyError(err))
+	}
+
+	return list, nil
+}
+
+func (s *APIKeyStorage) Create(ctx context.Context, k model.APIKey) (model.APIKey, error) {
+	query := s.queryBuilder.Insert(scheme+"."+table).
+		Columns("name", "prefix", "key_hash", "scopes", "expires_at", "created_by").
+		Values(k.Name, k.Prefix, k.Hash, scopeStrings(k.Scopes), k.ExpiresAt, k.CreatedBy).
+		Suffix("RETURNING " + strings.Join(columns, ", "))
+
+	return s.queryOne(ctx, query)
+}
+
+// Issue generates a new key and stores its hash, the key is only returned here.
+func (s *APIKeyStorage) Issue(ctx context.Context, k model.APIKey) (model.Created, error) {
+	key, prefix, hash, err := auth.NewAPIKey()
+	if err != nil {
+		return model.Created{}, fmt.Errorf("failed to generate api key: %w", err)
+	}
+	k.Prefix, k.Hash = prefix, hash
+
+	k, err = s.Create(ctx, k)
+	if err != nil {
+		return model.Created{}, err
+	}
+
+	return model.Created{APIKey: k, Key: key}, nil
+}
+
+// Revoke marks the key as revoked, revoking it again keeps the first revocation time.
+func (s *APIKeyStorage) Revoke(ctx context.Context, id string) (model.APIKey, error) {
+	query := s.queryBuilder.Update(scheme+"."+table).
+		Set("revoked_at", sq.Expr("coalesce(revoked_at, now())")).
+		Where(sq.Eq{"id": id}).
+		Suffix("RETURNING " + strings.Join(columns, ", "))
+
+	return s.queryOne(ctx, query)
+}
+
+// Credentials implements auth.APIKeyStore.
+func (s *APIKeyStorage) Credentials(ctx context.Context, prefix string) (auth.APIKey, error) {
+	query := s.queryBuilder.Select(columns...).
+		From(scheme + "." + table).
+		Where(sq.Eq{"prefix": prefix})
+
+	k, err := s.queryOne(ctx, query)
+	switch {
+	case errors.Is(err, postgresql.ErrNotFound):
+		return auth.APIKey{}, fmt.Errorf("%w: %w", apperror.ErrNotFound, err)
+	case err != nil:
+		return auth.APIKey{}, err
+	}
+
+	return auth.APIKey{
+		ID:        k.Id,
+		Hash:      k.Hash,
+		Scopes:    k.Scopes,
+		ExpiresAt: k.ExpiresAt,
+		RevokedAt: k.RevokedAt,
+	}, nil
+}
+
+// Touch implements auth.APIKeyStore, last_used_at is only written once per touchInterval.
+func (s *APIKeyStorage) Touch(ctx context.Context, id string) error {
+	query := s.queryBuilder.Update(scheme+"."+table).
+		Set("last_used_at", sq.Expr("now()")).
+		Where(sq.Eq{"id": id}).
+		Where("(last_used_at IS NULL OR last_used_at < now() - interval '" + touchInterval + "')")
+
+	sql, args, err := query.ToSql()
+	if err != nil {
+		return db.ErrCreateQuery(err)
+	}
+
+	if _, err = s.client.Exec(ctx, sql, args...); err != nil {
+		return db.ErrDoQuery(postgresql.ClassifyError(err))
+	}
+
+	return nil
+}
+
+func (s *APIKeyStorage) queryOne(ctx context.Context, query sq.Sqlizer) (model.APIKey, error) {
+	sql, args, err := query.ToSql()
+	if err != nil {
+		return model.APIKey{}, db.ErrCreateQuery(err)
+	}
+
+	k, err := scanAPIKey(s.client.QueryRow(ctx, sql, args...))
+	if err != nil {
+		return k, db.ErrDoQuery(postgresql.ClassifyError(err))
+	}
+
+	return k, nil
+}
+
+func scanAPIKey(row pgx.Row) (model.APIKey, error) {
+	k := model.APIKey{}
+	var scopes []string
+	err := row.Scan(&k.Id, &k.Name, &k.Prefix, &k.Hash, &scopes, &k.ExpiresAt, &k.LastUsedAt, &k.RevokedAt,
+		&k.CreatedBy, &k.CreatedAt)
+	if err != nil {
+		return k, err
+	}
+
+	k.Scopes = make([]auth.Permission, 0, len(scopes))
+	for _, scope := range scopes {
+		k.Scopes = append(k.Scopes, auth.Permission(scope))
+	}
+	return k, nil
+}
+
+func scopeStrings(scopes []auth.Permission) []string {
+	list := make([]string, 0, len(scopes))
+	for _, scope := range scopes {
+		list = append(list, string(scope))
+	}
+	return list
+}
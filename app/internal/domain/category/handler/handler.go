@@ -0,0 +1,177 @@
+package handler
+
+import (
+	"encoding/json"
+	"github.com/julienschmidt/httprouter"
+	"net/http"
+	"prod/internal/domain/category/model"
+	"prod/internal/domain/category/storage"
+	"prod/internal/rbac"
+	"prod/pkg/apperror"
+	"prod/pkg/auth"
+	"prod/pkg/httperr"
+	"prod/pkg/response"
+	"time"
+)
+
+const (
+	URL    = "/api/categories"
+	oneURL = "/api/categories/:id"
+)
+
+type Handler struct {
+	storage *storage.CategoryStorage
+	guard   auth.Guard
+}
+
+// NewHandler creates the category handler, guard enforces the permission of every write endpoint.
+func NewHandler(storage *storage.CategoryStorage, guard auth.Guard) *Handler {
+	return &Handler{
+		storage: storage,
+		guard:   guard,
+	}
+}
+
+func (h *Handler) Register(router *httprouter.Router) {
+	router.HandlerFunc(http.MethodGet, URL, h.List)
+	router.HandlerFunc(http.MethodGet, oneURL, h.One)
+	router.Handler(http.MethodPost, URL, h.guard(rbac.CategoryCreate)(http.HandlerFunc(h.Create)))
+	router.Handler(http.MethodPut, oneURL, h.guard(rbac.CategoryUpdate)(http.HandlerFunc(h.Update)))
+	router.Handler(http.MethodDelete, oneURL, h.guard(rbac.CategoryDelete)(http.HandlerFunc(h.Delete)))
+}
+
+// List
+// @Summary List categories
+// @Tags Categories
+// @Produce json
+// @Param If-None-Match header string false "ETag of a cached response"
+// @Success 200 {array} model.Category
+// @Success 304 "The cached response is still valid"
+// @Router /api/categories [get]
+func (h *Handler) List(w http.ResponseWriter, r *http.Request) {
+	list, err := h.storage.All(r.Context())
+	if err != nil {
+		httperr.Write(w, r, err)
+		return
+	}
+
+	// a list has no Last-Modified, deleted items would not move it
+	response.CachedJSON(w, r, list, time.Time{})
+}
+
+// One
+// @Summary Get a category
+// @Description The ETag is a hash of the body, it validates cached responses. Unlike product writes,
+// @Description writes are not checked against it with If-Match.
+// @Tags Categories
+// @Produce json
+// @Param id path string true "Category ID"
+// @Param If-None-Match header string false "ETag of a cached response"
+// @Success 200 {object} model.Category
+// @Success 304 "The cached response is still valid"
+// @Failure 404 {object} httperr.Problem
+// @Router /api/categories/{id} [get]
+func (h *Handler) One(w http.ResponseWriter, r *http.Request) {
+	c, err := h.storage.One(r.Context(), httprouter.ParamsFromContext(r.Context()).ByName("id"))
+	if err != nil {
+		httperr.Write(w, r, err)
+		return
+	}
+
+	// a category has no version or update time, so it has no Last-Modified and its writes take no
+	// If-Match: categories are rarely renamed, unlike products that editors change concurrently
+	response.CachedJSON(w, r, c, time.Time{})
+}
+
+// Create
+// @Summary Create a category
+// @Tags Categories
+// @Accept json
+// @Produce json
+// @Security BearerAuth
+// @Security ApiKeyAuth
+// @Param Idempotency-Key header string false "Makes the request safe to retry, the first response is replayed"
+// @Param category body model.Category true "Category"
+// @Success 201 {object} model.Category
+// @Failure 400 {object} httperr.Problem
+// @Failure 401 {object} httperr.Problem
+// @Failure 403 {object} httperr.Problem
+// @Failure 422 {object} httperr.Problem
+// @Router /api/categories [post]
+func (h *Handler) Create(w http.ResponseWriter, r *http.Request) {
+	c := model.Category{}
+	if err := json.NewDecoder(r.Body).Decode(&c); err != nil {
+		httperr.Write(w, r, apperror.InvalidInput("malformed request body"))
+		return
+	}
+	if err := c.Validate(); err != nil {
+		httperr.Write(w, r, err)
+		return
+	}
+
+	c, err := h.storage.Create(r.Context(), c)
+	if err != nil {
+		httperr.Write(w, r, err)
+		return
+	}
+
+	response.JSON(w, http.StatusCreated, c)
+}
+
+// Update
+// @Summary Update a category
+// @Tags Categories
+// @Accept json
+// @Produce json
+// @Security BearerAuth
+// @Security ApiKeyAuth
+// @Param id path string true "Category ID"
+// @Param category body model.Category true "Category"
+// @Success 200 {object} model.Category
+// @Failure 400 {object} httperr.Problem
+// @Failure 401 {object} httperr.Problem
+// @Failure 403 {object} httperr.Problem
+// @Failure 404 {object} httperr.Problem
+// @Failure 422 {object} httperr.Problem
+// @Router /api/categories/{id} [put]
+func (h *Handler) Update(w http.ResponseWriter, r *http.Request) {
+	c := model.Category{}
+	if err := json.NewDecoder(r.Body).Decode(&c); err != nil {
+		httperr.Write(w, r, apperror.InvalidInput("malformed request body"))
+		return
+	}
+	c.Id = httprouter.ParamsFromContext(r.Context()).ByName("id")
+	if err := c.Validate(); err != nil {
+		httperr.Write(w, r, err)
+		return
+	}
+
+	c, err := h.storage.Update(r.Context(), c)
+	if err != nil {
+		httperr.Write(w, r, err)
+		return
+	}
+
+	response.JSON(w, http.StatusOK, c)
+}
+
+// Delete
+// @Summary Delete a category
+// @Tags Categories
+// @Security BearerAuth
+// @Security ApiKeyAuth
+// @Param id path string true "Category ID"
+// @Success 204
+// @Failure 401 {object} httperr.Problem
+// @Failure 403 {object} httperr.Problem
+// @Failure 404 {object} httperr.Problem
+// @Failure 409 {object} httperr.Problem
+// @Router /api/categories/{id} [delete]
+func (h *Handler) Delete(w http.ResponseWriter, r *http.Request) {
+	if err := h.storage.Delete(r.Context(), httprouter.ParamsFromContext(r.Context()).ByName("id")); err != nil {
+		httperr.Write(w, r, err)
+		return
+	}
+
+	response.NoContent(w)
+}
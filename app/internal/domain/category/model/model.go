@@ -1,6 +1,12 @@
 package model
 
+import "prod/pkg/validate"
+
 type Category struct {
 	Id   string `json:"id"`
-	Name string `json:"name"`
+	Name string `json:"name" validate:"required,maxlen=255"`
+}
+
+func (c Category) Validate() error {
+	return validate.Struct(c)
 }
@@ -1,11 +1,136 @@
 package storage
 
 import (
+	"context"
+	sq "github.com/Masterminds/squirrel"
+	"prod/internal/domain/category/model"
 	"prod/pkg/client/postgresql"
-	"prod/pkg/logging"
+	db "prod/pkg/client/postgresql/model"
 )
 
-type storage struct {
-	client postgresql.Client
-	logger *logging.Logger
+type CategoryStorage struct {
+	queryBuilder sq.StatementBuilderType
+	client       postgresql.Client
+}
+
+func NewCategoryStorage(client postgresql.Client) *CategoryStorage {
+	return &CategoryStorage{
+		queryBuilder: sq.StatementBuilder.PlaceholderFormat(sq.Dollar),
+		client:       client,
+	}
+}
+
+const (
+	scheme = "public"
+	table  = "category"
+)
+
+func (s *CategoryStorage) All(ctx context.Context) ([]model.Category, error) {
+	query := s.queryBuilder.Select("id", "name").
+		From(scheme + "." + table).
+		OrderBy("id")
+
+	sql, args, err := query.ToSql()
+	if err != nil {
+		return nil, db.ErrCreateQuery(err)
+	}
+
+	rows, err := s.client.Query(ctx, sql, args...)
+	if err != nil {
+		return nil, db.ErrDoQuery(postgresql.ClassifyError(err))
+	}
+
+	defer rows.Close()
+
+	list := make([]model.Category, 0)
+	for rows.Next() {
+		c := model.Category{}
+		if err = rows.Scan(&c.Id, &c.Name); err != nil {
+			return nil, db.ErrScan(postgresql.ClassifyError(err))
+		}
+		list = append(list, c)
+	}
+	if err = rows.Err(); err != nil {
+		return nil, db.ErrDoQuery(postgresql.ClassifyError(err))
+	}
+
+	return list, nil
+}
+
+func (s *CategoryStorage) One(ctx context.Context, id string) (model.Category, error) {
+	query := s.queryBuilder.Select("id", "name").
+		From(scheme + "." + table).
+		Where(sq.Eq{"id": id})
+
+	return s.queryOne(ctx, query)
+}
+
+func (s *CategoryStorage) Exists(ctx context.Context, id string) (bool, error) {
+	query := s.queryBuilder.Select("1").
+		From(scheme + "." + table).
+		Where(sq.Eq{"id": id}).
+		Prefix("SELECT EXISTS (").
+		Suffix(")")
+
+	sql, args, err := query.ToSql()
+	if err != nil {
+		return false, db.ErrCreateQuery(err)
+	}
+
+	var exists bool
+	if err = s.client.QueryRow(ctx, sql, args...).Scan(&exists); err != nil {
+		return false, db.ErrDoQuery(postgresql.ClassifyError(err))
+	}
+
+	return exists, nil
+}
+
+func (s *CategoryStorage) Create(ctx context.Context, c model.Category) (model.Category, error) {
+	query := s.queryBuilder.Insert(scheme + "." + table).
+		Columns("name").
+		Values(c.Name).
+		Suffix("RETURNING id, name")
+
+	return s.queryOne(ctx, query)
+}
+
+func (s *CategoryStorage) Update(ctx context.Context, c model.Category) (model.Category, error) {
+	query := s.queryBuilder.Update(scheme+"."+table).
+		Set("name", c.Name).
+		Where(sq.Eq{"id": c.Id}).
+		Suffix("RETURNING id, name")
+
+	return s.queryOne(ctx, query)
+}
+
+func (s *CategoryStorage) Delete(ctx context.Context, id string) error {
+	query := s.queryBuilder.Delete(scheme + "." + table).
+		Where(sq.Eq{"id": id}).
+		Suffix("RETURNING id")
+
+	sql, args, err := query.ToSql()
+	if err != nil {
+		return db.ErrCreateQuery(err)
+	}
+
+	if err = s.client.QueryRow(ctx, sql, args...).Scan(&id); err != nil {
+		return db.ErrDoQuery(postgresql.ClassifyError(err))
+	}
+
+	return nil
+}
+
+func (s *CategoryStorage) queryOne(ctx context.Context, query sq.Sqlizer) (model.Category, error) {
+	c := model.Category{}
+
+	sql, args, err := query.ToSql()
+	if err != nil {
+		return c, db.ErrCreateQuery(err)
+	}
+
+	if err = s.client.QueryRow(ctx, sql, args...).Scan(&c.Id, &c.Name); err != nil {
+		return c, db.ErrDoQuery(postgresql.ClassifyError(err))
+	}
+
+	return c, nil
 }
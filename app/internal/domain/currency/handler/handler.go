@@ -0,0 +1,167 @@
+package handler
+
+import (
+	"encoding/json"
+	"github.com/julienschmidt/httprouter"
+	"net/http"
+	"prod/internal/domain/currency/model"
+	"prod/internal/domain/currency/storage"
+	"prod/internal/rbac"
+	"prod/pkg/apperror"
+	"prod/pkg/auth"
+	"prod/pkg/httperr"
+	"prod/pkg/response"
+)
+
+const (
+	URL    = "/api/currencies"
+	oneURL = "/api/currencies/:id"
+)
+
+type Handler struct {
+	storage *storage.CurrencyStorage
+	guard   auth.Guard
+}
+
+// NewHandler creates the currency handler, guard enforces the permission of every write endpoint.
+func NewHandler(storage *storage.CurrencyStorage, guard auth.Guard) *Handler {
+	return &Handler{
+		storage: storage,
+		guard:   guard,
+	}
+}
+
+func (h *Handler) Register(router *httprouter.Router) {
+	router.HandlerFunc(http.MethodGet, URL, h.List)
+	router.HandlerFunc(http.MethodGet, oneURL, h.One)
+	router.Handler(http.MethodPost, URL, h.guard(rbac.CurrencyCreate)(http.HandlerFunc(h.Create)))
+	router.Handler(http.MethodPut, oneURL, h.guard(rbac.CurrencyUpdate)(http.HandlerFunc(h.Update)))
+	router.Handler(http.MethodDelete, oneURL, h.guard(rbac.CurrencyDelete)(http.HandlerFunc(h.Delete)))
+}
+
+// List
+// @Summary List currencies
+// @Tags Currencies
+// @Produce json
+// @Success 200 {array} model.Currency
+// @Router /api/currencies [get]
+func (h *Handler) List(w http.ResponseWriter, r *http.Request) {
+	list, err := h.storage.All(r.Context())
+	if err != nil {
+		httperr.Write(w, r, err)
+		return
+	}
+
+	response.JSON(w, http.StatusOK, list)
+}
+
+// One
+// @Summary Get a currency
+// @Tags Currencies
+// @Produce json
+// @Param id path string true "Currency ID"
+// @Success 200 {object} model.Currency
+// @Failure 404 {object} httperr.Problem
+// @Router /api/currencies/{id} [get]
+func (h *Handler) One(w http.ResponseWriter, r *http.Request) {
+	c, err := h.storage.One(r.Context(), httprouter.ParamsFromContext(r.Context()).ByName("id"))
+	if err != nil {
+		httperr.Write(w, r, err)
+		return
+	}
+
+	response.JSON(w, http.StatusOK, c)
+}
+
+// Create
+// @Summary Create a currency
+// @Tags Currencies
+// @Accept json
+// @Produce json
+// @Security BearerAuth
+// @Security ApiKeyAuth
+// @Param Idempotency-Key header string false "Makes the request safe to retry, the first response is replayed"
+// @Param currency body model.Currency true "Currency"
+// @Success 201 {object} model.Currency
+// @Failure 400 {object} httperr.Problem
+// @Failure 401 {object} httperr.Problem
+// @Failure 403 {object} httperr.Problem
+// @Failure 422 {object} httperr.Problem
+// @Router /api/currencies [post]
+func (h *Handler) Create(w http.ResponseWriter, r *http.Request) {
+	c := model.Currency{}
+	if err := json.NewDecoder(r.Body).Decode(&c); err != nil {
+		httperr.Write(w, r, apperror.InvalidInput("malformed request body"))
+		return
+	}
+	if err := c.Validate(); err != nil {
+		httperr.Write(w, r, err)
+		return
+	}
+
+	c, err := h.storage.Create(r.Context(), c)
+	if err != nil {
+		httperr.Write(w, r, err)
+		return
+	}
+
+	response.JSON(w, http.StatusCreated, c)
+}
+
+// Update
+// @Summary Update a currency
+// @Tags Currencies
+// @Accept json
+// @Produce json
+// @Security BearerAuth
+// @Security ApiKeyAuth
+// @Param id path string true "Currency ID"
+// @Param currency body model.Currency true "Currency"
+// @Success 200 {object} model.Currency
+// @Failure 400 {object} httperr.Problem
+// @Failure 401 {object} httperr.Problem
+// @Failure 403 {object} httperr.Problem
+// @Failure 404 {object} httperr.Problem
+// @Failure 422 {object} httperr.Problem
+// @Router /api/currencies/{id} [put]
+func (h *Handler) Update(w http.ResponseWriter, r *http.Request) {
+	c := model.Currency{}
+	if err := json.NewDecoder(r.Body).Decode(&c); err != nil {
+		httperr.Write(w, r, apperror.InvalidInput("malformed request body"))
+		return
+	}
+	c.Id = httprouter.ParamsFromContext(r.Context()).ByName("id")
+	if err := c.Validate(); err != nil {
+		httperr.Write(w, r, err)
+		return
+	}
+
+	c, err := h.storage.Update(r.Context(), c)
+	if err != nil {
+		httperr.Write(w, r, err)
+		return
+	}
+
+	response.JSON(w, http.StatusOK, c)
+}
+
+// Delete
+// @Summary Delete a currency
+// @Tags Currencies
+// @Security BearerAuth
+// @Security ApiKeyAuth
+// @Param id path string true "Currency ID"
+// @Success 204
+// @Failure 401 {object} httperr.Problem
+// @Failure 403 {object} httperr.Problem
+// @Failure 404 {object} httperr.Problem
+// @Failure 409 {object} httperr.Problem
+// @Router /api/currencies/{id} [delete]
+func (h *Handler) Delete(w http.ResponseWriter, r *http.Request) {
+	if err := h.storage.Delete(r.Context(), httprouter.ParamsFromContext(r.Context()).ByName("id")); err != nil {
+		httperr.Write(w, r, err)
+		return
+	}
+
+	response.NoContent(w)
+}
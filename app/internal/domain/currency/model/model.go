@@ -1,7 +1,13 @@
 package model
 
+import "prod/pkg/validate"
+
 type Currency struct {
 	Id     string `json:"id"`
-	Name   string `json:"name"`
-	Symbol string `json:"symbol"`
+	Name   string `json:"name" validate:"required,maxlen=64"`
+	Symbol string `json:"symbol" validate:"required,maxlen=8"`
+}
+
+func (c Currency) Validate() error {
+	return validate.Struct(c)
 }
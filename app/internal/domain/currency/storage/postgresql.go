@@ -0,0 +1,137 @@
+package storage
+
+import (
+	"context"
+	sq "github.com/Masterminds/squirrel"
+	"prod/internal/domain/currency/model"
+	"prod/pkg/client/postgresql"
+	db "prod/pkg/client/postgresql/model"
+)
+
+type CurrencyStorage struct {
+	queryBuilder sq.StatementBuilderType
+	client       postgresql.Client
+}
+
+func NewCurrencyStorage(client postgresql.Client) *CurrencyStorage {
+	return &CurrencyStorage{
+		queryBuilder: sq.StatementBuilder.PlaceholderFormat(sq.Dollar),
+		client:       client,
+	}
+}
+
+const (
+	scheme = "public"
+	table  = "currency"
+)
+
+func (s *CurrencyStorage) All(ctx context.Context) ([]model.Currency, error) {
+	query := s.queryBuilder.Select("id", "name", "symbol").
+		From(scheme + "." + table).
+		OrderBy("id")
+
+	sql, args, err := query.ToSql()
+	if err != nil {
+		return nil, db.ErrCreateQuery(err)
+	}
+
+	rows, err := s.client.Query(ctx, sql, args...)
+	if err != nil {
+		return nil, db.ErrDoQuery(postgresql.ClassifyError(err))
+	}
+
+	defer rows.Close()
+
+	list := make([]model.Currency, 0)
+	for rows.Next() {
+		c := model.Currency{}
+		if err = rows.Scan(&c.Id, &c.Name, &c.Symbol); err != nil {
+			return nil, db.ErrScan(postgresql.ClassifyError(err))
+		}
+		list = append(list, c)
+	}
+	if err = rows.Err(); err != nil {
+		return nil, db.ErrDoQuery(postgresql.ClassifyError(err))
+	}
+
+	return list, nil
+}
+
+func (s *CurrencyStorage) One(ctx context.Context, id string) (model.Currency, error) {
+	query := s.queryBuilder.Select("id", "name", "symbol").
+		From(scheme + "." + table).
+		Where(sq.Eq{"id": id})
+
+	return s.queryOne(ctx, query)
+}
+
+func (s *CurrencyStorage) Exists(ctx context.Context, id string) (bool, error) {
+	query := s.queryBuilder.Select("1").
+		From(scheme + "." + table).
+		Where(sq.Eq{"id": id}).
+		Prefix("SELECT EXISTS (").
+		Suffix(")")
+
+	sql, args, err := query.ToSql()
+	if err != nil {
+		return false, db.ErrCreateQuery(err)
+	}
+
+	var exists bool
+	if err = s.client.QueryRow(ctx, sql, args...).Scan(&exists); err != nil {
+		return false, db.ErrDoQuery(postgresql.ClassifyError(err))
+	}
+
+	return exists, nil
+}
+
+func (s *CurrencyStorage) Create(ctx context.Context, c model.Currency) (model.Currency, error) {
+	query := s.queryBuilder.Insert(scheme+"."+table).
+		Columns("name", "symbol").
+		Values(c.Name, c.Symbol).
+		Suffix("RETURNING id, name, symbol")
+
+	return s.queryOne(ctx, query)
+}
+
+func (s *CurrencyStorage) Update(ctx context.Context, c model.Currency) (model.Currency, error) {
+	query := s.queryBuilder.Update(scheme+"."+table).
+		Set("name", c.Name).
+		Set("symbol", c.Symbol).
+		Where(sq.Eq{"id": c.Id}).
+		Suffix("RETURNING id, name, symbol")
+
+	return s.queryOne(ctx, query)
+}
+
+func (s *CurrencyStorage) Delete(ctx context.Context, id string) error {
+	query := s.queryBuilder.Delete(scheme + "." + table).
+		Where(sq.Eq{"id": id}).
+		Suffix("RETURNING id")
+
+	sql, args, err := query.ToSql()
+	if err != nil {
+		return db.ErrCreateQuery(err)
+	}
+
+	if err = s.client.QueryRow(ctx, sql, args...).Scan(&id); err != nil {
+		return db.ErrDoQuery(postgresql.ClassifyError(err))
+	}
+
+	return nil
+}
+
+func (s *CurrencyStorage) queryOne(ctx context.Context, query sq.Sqlizer) (model.Currency, error) {
+	c := model.Currency{}
+
+	sql, args, err := query.ToSql()
+	if err != nil {
+		return c, db.ErrCreateQuery(err)
+	}
+
+	if err = s.client.QueryRow(ctx, sql, args...).Scan(&c.Id, &c.Name, &c.Symbol); err != nil {
+		return c, db.ErrDoQuery(postgresql.ClassifyError(err))
+	}
+
+	return c, nil
+}
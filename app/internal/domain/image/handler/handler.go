@@ -0,0 +1,177 @@
+package handler
+
+import (
+	"encoding/json"
+	"github.com/julienschmidt/httprouter"
+	"net/http"
+	"prod/internal/domain/image/model"
+	"prod/internal/domain/image/storage"
+	"prod/internal/rbac"
+	"prod/pkg/apperror"
+	"prod/pkg/auth"
+	"prod/pkg/httperr"
+	"prod/pkg/response"
+	"time"
+)
+
+const (
+	URL    = "/api/images"
+	oneURL = "/api/images/:id"
+)
+
+type Handler struct {
+	storage *storage.ImageStorage
+	guard   auth.Guard
+}
+
+// NewHandler creates the image handler, guard enforces the permission of every write endpoint.
+func NewHandler(storage *storage.ImageStorage, guard auth.Guard) *Handler {
+	return &Handler{
+		storage: storage,
+		guard:   guard,
+	}
+}
+
+func (h *Handler) Register(router *httprouter.Router) {
+	router.HandlerFunc(http.MethodGet, URL, h.List)
+	router.HandlerFunc(http.MethodGet, oneURL, h.One)
+	router.Handler(http.MethodPost, URL, h.guard(rbac.ImageCreate)(http.HandlerFunc(h.Create)))
+	router.Handler(http.MethodPut, oneURL, h.guard(rbac.ImageUpdate)(http.HandlerFunc(h.Update)))
+	router.Handler(http.MethodDelete, oneURL, h.guard(rbac.ImageDelete)(http.HandlerFunc(h.Delete)))
+}
+
+// List
+// @Summary List images
+// @Tags Images
+// @Produce json
+// @Param If-None-Match header string false "ETag of a cached response"
+// @Success 200 {array} model.Image
+// @Success 304 "The cached response is still valid"
+// @Router /api/images [get]
+func (h *Handler) List(w http.ResponseWriter, r *http.Request) {
+	list, err := h.storage.All(r.Context())
+	if err != nil {
+		httperr.Write(w, r, err)
+		return
+	}
+
+	// a list has no Last-Modified, deleted items would not move it
+	response.CachedJSON(w, r, list, time.Time{})
+}
+
+// One
+// @Summary Get an image
+// @Description The ETag is a hash of the body, it validates cached responses. Unlike product writes,
+// @Description writes are not checked against it with If-Match.
+// @Tags Images
+// @Produce json
+// @Param id path string true "Image ID"
+// @Param If-None-Match header string false "ETag of a cached response"
+// @Success 200 {object} model.Image
+// @Success 304 "The cached response is still valid"
+// @Failure 404 {object} httperr.Problem
+// @Router /api/images/{id} [get]
+func (h *Handler) One(w http.ResponseWriter, r *http.Request) {
+	i, err := h.storage.One(r.Context(), httprouter.ParamsFromContext(r.Context()).ByName("id"))
+	if err != nil {
+		httperr.Write(w, r, err)
+		return
+	}
+
+	// an image has no version or update time, so it has no Last-Modified and its writes take no
+	// If-Match: images are replaced as a whole, unlike products that editors change field by field
+	response.CachedJSON(w, r, i, time.Time{})
+}
+
+// Create
+// @Summary Create an image
+// @Tags Images
+// @Accept json
+// @Produce json
+// @Security BearerAuth
+// @Security ApiKeyAuth
+// @Param Idempotency-Key header string false "Makes the request safe to retry, the first response is replayed"
+// @Param image body model.Image true "Image"
+// @Success 201 {object} model.Image
+// @Failure 400 {object} httperr.Problem
+// @Failure 401 {object} httperr.Problem
+// @Failure 403 {object} httperr.Problem
+// @Failure 422 {object} httperr.Problem
+// @Router /api/images [post]
+func (h *Handler) Create(w http.ResponseWriter, r *http.Request) {
+	i := model.Image{}
+	if err := json.NewDecoder(r.Body).Decode(&i); err != nil {
+		httperr.Write(w, r, apperror.InvalidInput("malformed request body"))
+		return
+	}
+	if err := i.Validate(); err != nil {
+		httperr.Write(w, r, err)
+		return
+	}
+
+	i, err := h.storage.Create(r.Context(), i)
+	if err != nil {
+		httperr.Write(w, r, err)
+		return
+	}
+
+	response.JSON(w, http.StatusCreated, i)
+}
+
+// Update
+// @Summary Update an image
+// @Tags Images
+// @Accept json
+// @Produce json
+// @Security BearerAuth
+// @Security ApiKeyAuth
+// @Param id path string true "Image ID"
+// @Param image body model.Image true "Image"
+// @Success 200 {object} model.Image
+// @Failure 400 {object} httperr.Problem
+// @Failure 401 {object} httperr.Problem
+// @Failure 403 {object} httperr.Problem
+// @Failure 404 {object} httperr.Problem
+// @Failure 422 {object} httperr.Problem
+// @Router /api/images/{id} [put]
+func (h *Handler) Update(w http.ResponseWriter, r *http.Request) {
+	i := model.Image{}
+	if err := json.NewDecoder(r.Body).Decode(&i); err != nil {
+		httperr.Write(w, r, apperror.InvalidInput("malformed request body"))
+		return
+	}
+	i.Id = httprouter.ParamsFromContext(r.Context()).ByName("id")
+	if err := i.Validate(); err != nil {
+		httperr.Write(w, r, err)
+		return
+	}
+
+	i, err := h.storage.Update(r.Context(), i)
+	if err != nil {
+		httperr.Write(w, r, err)
+		return
+	}
+
+	response.JSON(w, http.StatusOK, i)
+}
+
+// Delete
+// @Summary Delete an image
+// @Tags Images
+// @Security BearerAuth
+// @Security ApiKeyAuth
+// @Param id path string true "Image ID"
+// @Success 204
+// @Failure 401 {object} httperr.Problem
+// @Failure 403 {object} httperr.Problem
+// @Failure 404 {object} httperr.Problem
+// @Failure 409 {object} httperr.Problem
+// @Router /api/images/{id} [delete]
+func (h *Handler) Delete(w http.ResponseWriter, r *http.Request) {
+	if err := h.storage.Delete(r.Context(), httprouter.ParamsFromContext(r.Context()).ByName("id")); err != nil {
+		httperr.Write(w, r, err)
+		return
+	}
+
+	response.NoContent(w)
+}
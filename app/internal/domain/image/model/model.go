@@ -1,8 +1,14 @@
 package model
 
+import "prod/pkg/validate"
+
 type Image struct {
 	Id    string `json:"id"`
-	Name  string `json:"name"`
+	Name  string `json:"name" validate:"required,maxlen=255"`
 	Size  uint64 `json:"size"`
-	Bytes []byte `json:"bytes"`
+	Bytes []byte `json:"bytes" validate:"required"`
+}
+
+func (i Image) Validate() error {
+	return validate.Struct(i)
 }
@@ -0,0 +1,138 @@
+package storage
+
+import (
+	"context"
+	sq "github.com/Masterminds/squirrel"
+	"prod/internal/domain/image/model"
+	"prod/pkg/client/postgresql"
+	db "prod/pkg/client/postgresql/model"
+)
+
+type ImageStorage struct {
+	queryBuilder sq.StatementBuilderType
+	client       postgresql.Client
+}
+
+func NewImageStorage(client postgresql.Client) *ImageStorage {
+	return &ImageStorage{
+		queryBuilder: sq.StatementBuilder.PlaceholderFormat(sq.Dollar),
+		client:       client,
+	}
+}
+
+const (
+	scheme = "public"
+	table  = "image"
+)
+
+func (s *ImageStorage) All(ctx context.Context) ([]model.Image, error) {
+	query := s.queryBuilder.Select("id", "name", "size").
+		From(scheme + "." + table).
+		OrderBy("name")
+
+	sql, args, err := query.ToSql()
+	if err != nil {
+		return nil, db.ErrCreateQuery(err)
+	}
+
+	rows, err := s.client.Query(ctx, sql, args...)
+	if err != nil {
+		return nil, db.ErrDoQuery(postgresql.ClassifyError(err))
+	}
+
+	defer rows.Close()
+
+	list := make([]model.Image, 0)
+	for rows.Next() {
+		i := model.Image{}
+		if err = rows.Scan(&i.Id, &i.Name, &i.Size); err != nil {
+			return nil, db.ErrScan(postgresql.ClassifyError(err))
+		}
+		list = append(list, i)
+	}
+	if err = rows.Err(); err != nil {
+		return nil, db.ErrDoQuery(postgresql.ClassifyError(err))
+	}
+
+	return list, nil
+}
+
+func (s *ImageStorage) One(ctx context.Context, id string) (model.Image, error) {
+	query := s.queryBuilder.Select("id", "name", "size", "bytes").
+		From(scheme + "." + table).
+		Where(sq.Eq{"id": id})
+
+	return s.queryOne(ctx, query)
+}
+
+func (s *ImageStorage) Exists(ctx context.Context, id string) (bool, error) {
+	query := s.queryBuilder.Select("1").
+		From(scheme + "." + table).
+		Where(sq.Eq{"id": id}).
+		Prefix("SELECT EXISTS (").
+		Suffix(")")
+
+	sql, args, err := query.ToSql()
+	if err != nil {
+		return false, db.ErrCreateQuery(err)
+	}
+
+	var exists bool
+	if err = s.client.QueryRow(ctx, sql, args...).Scan(&exists); err != nil {
+		return false, db.ErrDoQuery(postgresql.ClassifyError(err))
+	}
+
+	return exists, nil
+}
+
+func (s *ImageStorage) Create(ctx context.Context, i model.Image) (model.Image, error) {
+	query := s.queryBuilder.Insert(scheme+"."+table).
+		Columns("name", "size", "bytes").
+		Values(i.Name, len(i.Bytes), i.Bytes).
+		Suffix("RETURNING id, name, size, bytes")
+
+	return s.queryOne(ctx, query)
+}
+
+func (s *ImageStorage) Update(ctx context.Context, i model.Image) (model.Image, error) {
+	query := s.queryBuilder.Update(scheme+"."+table).
+		Set("name", i.Name).
+		Set("size", len(i.Bytes)).
+		Set("bytes", i.Bytes).
+		Where(sq.Eq{"id": i.Id}).
+		Suffix("RETURNING id, name, size, bytes")
+
+	return s.queryOne(ctx, query)
+}
+
+func (s *ImageStorage) Delete(ctx context.Context, id string) error {
+	query := s.queryBuilder.Delete(scheme + "." + table).
+		Where(sq.Eq{"id": id}).
+		Suffix("RETURNING id")
+
+	sql, args, err := query.ToSql()
+	if err != nil {
+		return db.ErrCreateQuery(err)
+	}
+
+	if err = s.client.QueryRow(ctx, sql, args...).Scan(&id); err != nil {
+		return db.ErrDoQuery(postgresql.ClassifyError(err))
+	}
+
+	return nil
+}
+
+func (s *ImageStorage) queryOne(ctx context.Context, query sq.Sqlizer) (model.Image, error) {
+	i := model.Image{}
+
+	sql, args, err := query.ToSql()
+	if err != nil {
+		return i, db.ErrCreateQuery(err)
+	}
+
+	if err = s.client.QueryRow(ctx, sql, args...).Scan(&i.Id, &i.Name, &i.Size, &i.Bytes); err != nil {
+		return i, db.ErrDoQuery(postgresql.ClassifyError(err))
+	}
+
+	return i, nil
+}
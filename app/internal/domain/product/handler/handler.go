@@ -0,0 +1,719 @@
+package handler
+
+import (
+	"bytes"
+	"encoding/json"
+	"github.com/julienschmidt/httprouter"
+	"net/http"
+	"net/url"
+	"prod/internal/domain/product/model"
+	"prod/internal/domain/product/storage"
+	"prod/internal/domain/product/suggest"
+	"prod/internal/domain/product/validator"
+	"prod/internal/rbac"
+	"prod/pkg/apperror"
+	"prod/pkg/auth"
+	"prod/pkg/httperr"
+	"prod/pkg/response"
+	"regexp"
+	"slices"
+	"strconv"
+	"strings"
+	"time"
+	"unicode/utf8"
+)
+
+const (
+	URL        = "/api/products"
+	oneURL     = "/api/products/:id"
+	searchURL  = "/api/search/products"
+	suggestURL = "/api/search/suggestions"
+
+	defaultLimit   = 100
+	maxLimit       = 1000
+	maxQueryLength = 256
+
+	defaultSuggestions = 10
+	maxSuggestions     = 50
+	maxPrefixLength    = 64
+
+	maxSpecificationFacets  = 10
+	maxSpecificationFilters = 10
+)
+
+type Handler struct {
+	storage   *storage.ProductStorage
+	validator *validator.Validator
+	suggester *suggest.Suggester
+	// priceRanges are the bounds of the price facet when the request has none.
+	priceRanges []int64
+	guard       auth.Guard
+}
+
+// NewHandler creates the product handler, guard enforces the permission of every write endpoint.
+func NewHandler(
+	storage *storage.ProductStorage,
+	validator *validator.Validator,
+	suggester *suggest.Suggester,
+	priceRanges []int64,
+	guard auth.Guard,
+) *Handler {
+	return &Handler{
+		storage:     storage,
+		validator:   validator,
+		suggester:   suggester,
+		priceRanges: priceRanges,
+		guard:       guard,
+	}
+}
+
+func (h *Handler) Register(router *httprouter.Router) {
+	router.HandlerFunc(http.MethodGet, URL, h.List)
+	router.HandlerFunc(http.MethodGet, oneURL, h.One)
+	router.HandlerFunc(http.MethodGet, searchURL, h.Search)
+	router.HandlerFunc(http.MethodGet, suggestURL, h.Suggest)
+	router.Handler(http.MethodPost, URL, h.guard(rbac.ProductCreate)(http.HandlerFunc(h.Create)))
+	router.Handler(http.MethodPut, oneURL, h.guard(rbac.ProductUpdate)(http.HandlerFunc(h.Update)))
+	router.Handler(http.MethodPatch, oneURL, h.guard(rbac.ProductUpdate)(http.HandlerFunc(h.Patch)))
+	router.Handler(http.MethodDelete, oneURL, h.guard(rbac.ProductDelete)(http.HandlerFunc(h.Delete)))
+}
+
+// List
+// @Summary List products
+// @Description Specification values are filtered with spec.<key> parameters: spec.color=red, spec.ram_gb>=16,
+// @Description spec.size.width<30 or spec.tags contains x, other operators are != > <= and <.
+// @Tags Products
+// @Produce json
+// @Param category_id query int false "Category ID"
+// @Param currency_id query int false "Currency ID"
+// @Param price_from query int false "Minimal price"
+// @Param price_to query int false "Maximal price"
+// @Param rating_from query int false "Minimal rating"
+// @Param rating_to query int false "Maximal rating"
+// @Param name query string false "Part of the name"
+// @Param sort query string false "Sort field" Enums(name, price, rating, created_at)
+// @Param order query string false "Sort order" Enums(asc, desc)
+// @Param limit query int false "Page size" default(100) maximum(1000)
+// @Param offset query int false "Page offset"
+// @Param facets query string false "Comma separated facets to count: category, currency, rating, price, specification.<key>"
+// @Param price_ranges query string false "Comma separated ascending bounds of the price facet"
+// @Param If-None-Match header string false "ETag of a cached response"
+// @Success 200 {array} model.Product "A model.Page of items and facets when facets are requested"
+// @Success 304 "The cached response is still valid"
+// @Failure 400 {object} httperr.Problem
+// @Router /api/products [get]
+func (h *Handler) List(w http.ResponseWriter, r *http.Request) {
+	options, err := parseOptions(r.URL.Query())
+	if err != nil {
+		httperr.Write(w, r, err)
+		return
+	}
+
+	if options.SortBy == storage.SortByRelevance {
+		httperr.Write(w, r, apperror.InvalidInput("sort by relevance is available for search only"))
+		return
+	}
+
+	facets, err := h.parseFacets(r.URL.Query())
+	if err != nil {
+		httperr.Write(w, r, err)
+		return
+	}
+
+	list, err := h.storage.All(r.Context(), options)
+	if err != nil {
+		httperr.Write(w, r, err)
+		return
+	}
+
+	// a list has no Last-Modified, deleted items would not move it
+	if facets.Empty() {
+		response.CachedJSON(w, r, list, time.Time{})
+		return
+	}
+
+	page := model.Page{Items: list}
+	if page.Facets, err = h.storage.Facets(r.Context(), options.Filter, facets); err != nil {
+		httperr.Write(w, r, err)
+		return
+	}
+	response.CachedJSON(w, r, page, time.Time{})
+}
+
+// Search
+// @Summary Search products by text
+// @Description The query uses the web search syntax: quoted phrases, "or" and "-" to exclude words.
+// @Description name_highlight and snippet are HTML-escaped, their matched words are marked with <mark> tags.
+// @Description The spec.<key> filters of the product list apply as well.
+// @Tags Products
+// @Produce json
+// @Param q query string true "Search query" maxlength(256)
+// @Param category_id query int false "Category ID"
+// @Param currency_id query int false "Currency ID"
+// @Param price_from query int false "Minimal price"
+// @Param price_to query int false "Maximal price"
+// @Param rating_from query int false "Minimal rating"
+// @Param rating_to query int false "Maximal rating"
+// @Param name query string false "Part of the name"
+// @Param sort query string false "Sort field" Enums(relevance, name, price, rating, created_at) default(relevance)
+// @Param order query string false "Sort order, relevance is always descending" Enums(asc, desc)
+// @Param limit query int false "Page size" default(100) maximum(1000)
+// @Param offset query int false "Page offset"
+// @Success 200 {array} model.SearchHit
+// @Failure 400 {object} httperr.Problem
+// @Router /api/search/products [get]
+func (h *Handler) Search(w http.ResponseWriter, r *http.Request) {
+	text := strings.TrimSpace(r.URL.Query().Get("q"))
+	switch {
+	case text == "":
+		httperr.Write(w, r, apperror.Invalid(apperror.Field("q", "is required")))
+		return
+	case utf8.RuneCountInString(text) > maxQueryLength:
+		httperr.Write(w, r, apperror.Invalid(apperror.Field("q", "must not be longer than %d characters", maxQueryLength)))
+		return
+	}
+
+	options, err := parseOptions(r.URL.Query())
+	if err != nil {
+		httperr.Write(w, r, err)
+		return
+	}
+
+	list, err := h.storage.Search(r.Context(), text, options)
+	if err != nil {
+		httperr.Write(w, r, err)
+		return
+	}
+
+	response.JSON(w, http.StatusOK, list)
+}
+
+// Suggest
+// @Summary Complete a product name
+// @Description Names starting with the prefix come first, names with a similar word follow to forgive typos.
+// @Tags Products
+// @Produce json
+// @Param q query string true "What the user has typed so far" maxlength(64)
+// @Param category_id query int false "Category ID"
+// @Param limit query int false "Number of suggestions" default(10) maximum(50)
+// @Success 200 {array} model.Suggestion
+// @Failure 400 {object} httperr.Problem
+// @Router /api/search/suggestions [get]
+func (h *Handler) Suggest(w http.ResponseWriter, r *http.Request) {
+	query := r.URL.Query()
+	prefix := strings.TrimSpace(query.Get("q"))
+	switch {
+	case prefix == "":
+		httperr.Write(w, r, apperror.Invalid(apperror.Field("q", "is required")))
+		return
+	case utf8.RuneCountInString(prefix) > maxPrefixLength:
+		httperr.Write(w, r, apperror.Invalid(apperror.Field("q", "must not be longer than %d characters", maxPrefixLength)))
+		return
+	}
+
+	categoryId, err := parseInt32(query, "category_id")
+	if err != nil {
+		httperr.Write(w, r, err)
+		return
+	}
+	limit, err := parseUint(query, "limit")
+	if err != nil {
+		httperr.Write(w, r, err)
+		return
+	}
+	switch {
+	case limit == 0:
+		limit = defaultSuggestions
+	case limit > maxSuggestions:
+		httperr.Write(w, r, apperror.InvalidInput("limit must not exceed %d", maxSuggestions))
+		return
+	}
+
+	list, err := h.suggester.Suggest(r.Context(), prefix, categoryId, limit)
+	if err != nil {
+		httperr.Write(w, r, err)
+		return
+	}
+
+	response.JSON(w, http.StatusOK, list)
+}
+
+// One
+// @Summary Get a product
+// @Tags Products
+// @Produce json
+// @Param id path string true "Product ID"
+// @Param If-None-Match header string false "ETag of a cached response"
+// @Success 200 {object} model.Product
+// @Success 304 "The cached response is still valid"
+// @Failure 404 {object} httperr.Problem
+// @Router /api/products/{id} [get]
+func (h *Handler) One(w http.ResponseWriter, r *http.Request) {
+	p, err := h.storage.One(r.Context(), httprouter.ParamsFromContext(r.Context()).ByName("id"))
+	if err != nil {
+		httperr.Write(w, r, err)
+		return
+	}
+
+	response.VersionedJSON(w, r, p, p.ETag(), p.LastModified())
+}
+
+// Create
+// @Summary Create a product
+// @Tags Products
+// @Accept json
+// @Produce json
+// @Security BearerAuth
+// @Security ApiKeyAuth
+// @Param Idempotency-Key header string false "Makes the request safe to retry, the first response is replayed"
+// @Param product body model.Product true "Product"
+// @Success 201 {object} model.Product
+// @Failure 400 {object} httperr.Problem
+// @Failure 401 {object} httperr.Problem
+// @Failure 403 {object} httperr.Problem
+// @Failure 409 {object} httperr.Problem
+// @Failure 422 {object} httperr.Problem
+// @Router /api/products [post]
+func (h *Handler) Create(w http.ResponseWriter, r *http.Request) {
+	p := model.Product{}
+	if err := json.NewDecoder(r.Body).Decode(&p); err != nil {
+		httperr.Write(w, r, apperror.InvalidInput("malformed request body"))
+		return
+	}
+	if err := h.validator.Validate(r.Context(), p); err != nil {
+		httperr.Write(w, r, err)
+		return
+	}
+
+	p, err := h.storage.Create(r.Context(), p)
+	if err != nil {
+		httperr.Write(w, r, err)
+		return
+	}
+	h.suggester.Purge()
+
+	w.Header().Set("ETag", p.ETag())
+	response.JSON(w, http.StatusCreated, p)
+}
+
+// Update
+// @Summary Update a product
+// @Tags Products
+// @Accept json
+// @Produce json
+// @Security BearerAuth
+// @Security ApiKeyAuth
+// @Param id path string true "Product ID"
+// @Param If-Match header string true "ETag of the product, * to skip the check"
+// @Param product body model.Product true "Product"
+// @Success 200 {object} model.Product
+// @Failure 400 {object} httperr.Problem
+// @Failure 401 {object} httperr.Problem
+// @Failure 403 {object} httperr.Problem
+// @Failure 404 {object} httperr.Problem
+// @Failure 409 {object} httperr.Problem
+// @Failure 422 {object} httperr.Problem
+// @Failure 412 {object} httperr.Problem "The product has changed"
+// @Failure 428 {object} httperr.Problem "If-Match is missing"
+// @Router /api/products/{id} [put]
+func (h *Handler) Update(w http.ResponseWriter, r *http.Request) {
+	p := model.Product{}
+	if err := json.NewDecoder(r.Body).Decode(&p); err != nil {
+		httperr.Write(w, r, apperror.InvalidInput("malformed request body"))
+		return
+	}
+	p.Id = httprouter.ParamsFromContext(r.Context()).ByName("id")
+	version, err := ifMatch(r)
+	if err != nil {
+		httperr.Write(w, r, err)
+		return
+	}
+	if err := h.validator.Validate(r.Context(), p); err != nil {
+		httperr.Write(w, r, err)
+		return
+	}
+
+	p, err = h.storage.Update(r.Context(), p, version)
+	if err != nil {
+		httperr.Write(w, r, err)
+		return
+	}
+	h.suggester.Purge()
+
+	w.Header().Set("ETag", p.ETag())
+	response.JSON(w, http.StatusOK, p)
+}
+
+// Patch
+// @Summary Change some fields of a product
+// @Description The body is a JSON merge patch (RFC 7396): its fields replace those of the product, objects such as
+// @Description the specification are merged and null removes a field. Read-only fields are ignored.
+// @Tags Products
+// @Accept json
+// @Produce json
+// @Security BearerAuth
+// @Security ApiKeyAuth
+// @Param id path string true "Product ID"
+// @Param If-Match header string true "ETag of the product, * to skip the check"
+// @Param patch body object true "Fields to change"
+// @Success 200 {object} model.Product
+// @Failure 400 {object} httperr.Problem
+// @Failure 401 {object} httperr.Problem
+// @Failure 403 {object} httperr.Problem
+// @Failure 404 {object} httperr.Problem
+// @Failure 409 {object} httperr.Problem
+// @Failure 422 {object} httperr.Problem
+// @Failure 412 {object} httperr.Problem "The product has changed"
+// @Failure 428 {object} httperr.Problem "If-Match is missing"
+// @Router /api/products/{id} [patch]
+func (h *Handler) Patch(w http.ResponseWriter, r *http.Request) {
+	var patch json.RawMessage
+	if err := json.NewDecoder(r.Body).Decode(&patch); err != nil {
+		httperr.Write(w, r, apperror.InvalidInput("malformed request body"))
+		return
+	}
+	version, err := ifMatch(r)
+	if err != nil {
+		httperr.Write(w, r, err)
+		return
+	}
+
+	current, err := h.storage.One(r.Context(), httprouter.ParamsFromContext(r.Context()).ByName("id"))
+	if err != nil {
+		httperr.Write(w, r, err)
+		return
+	}
+	if version == storage.AnyVersion {
+		// the patch is applied to the version read, a concurrent update in between must not be overwritten
+		version = current.Version
+	}
+
+	p, err := applyPatch(current, patch)
+	if err != nil {
+		httperr.Write(w, r, err)
+		return
+	}
+	if err := h.validator.Validate(r.Context(), p); err != nil {
+		httperr.Write(w, r, err)
+		return
+	}
+
+	p, err = h.storage.Update(r.Context(), p, version)
+	if err != nil {
+		httperr.Write(w, r, err)
+		return
+	}
+	h.suggester.Purge()
+
+	w.Header().Set("ETag", p.ETag())
+	response.JSON(w, http.StatusOK, p)
+}
+
+// Delete
+// @Summary Delete a product
+// @Tags Products
+// @Security BearerAuth
+// @Security ApiKeyAuth
+// @Param id path string true "Product ID"
+// @Param If-Match header string true "ETag of the product, * to skip the check"
+// @Success 204
+// @Failure 401 {object} httperr.Problem
+// @Failure 403 {object} httperr.Problem
+// @Failure 404 {object} httperr.Problem
+// @Failure 412 {object} httperr.Problem "The product has changed"
+// @Failure 428 {object} httperr.Problem "If-Match is missing"
+// @Router /api/products/{id} [delete]
+func (h *Handler) Delete(w http.ResponseWriter, r *http.Request) {
+	version, err := ifMatch(r)
+	if err != nil {
+		httperr.Write(w, r, err)
+		return
+	}
+	if err = h.storage.Delete(r.Context(), httprouter.ParamsFromContext(r.Context()).ByName("id"), version); err != nil {
+		httperr.Write(w, r, err)
+		return
+	}
+	h.suggester.Purge()
+
+	response.NoContent(w)
+}
+
+// ifMatch returns the product version expected by the If-Match header, storage.AnyVersion for "*".
+// The tags are compared with the strong comparison, so weak ones in the list never match.
+func ifMatch(r *http.Request) (int32, error) {
+	header := strings.TrimSpace(r.Header.Get("If-Match"))
+	if header == "" {
+		return 0, apperror.PreconditionRequired("If-Match with the ETag of the product is required")
+	}
+	if header == "*" {
+		return storage.AnyVersion, nil
+	}
+
+	versions := make([]int32, 0, 1)
+	for _, candidate := range strings.Split(header, ",") {
+		candidate = strings.TrimSpace(candidate)
+		version, err := strconv.ParseInt(strings.Trim(candidate, `"`), 10, 32)
+		if err != nil || int32(version) == storage.AnyVersion {
+			continue
+		}
+		if response.MatchStrongETag(candidate, model.Product{Version: int32(version)}.ETag()) && !slices.Contains(versions, int32(version)) {
+			versions = append(versions, int32(version))
+		}
+	}
+	if len(versions) != 1 {
+		return 0, apperror.PreconditionFailed("If-Match %s must name exactly one strong ETag of the product", header)
+	}
+	return versions[0], nil
+}
+
+// applyPatch merges the JSON merge patch into the product, the read-only fields keep the values of p.
+func applyPatch(p model.Product, patch json.RawMessage) (model.Product, error) {
+	var changes interface{}
+	if err := decodeJSON(patch, &changes); err != nil {
+		return p, apperror.InvalidInput("malformed request body")
+	}
+	if _, ok := changes.(map[string]interface{}); !ok {
+		return p, apperror.InvalidInput("the patch must be a JSON object")
+	}
+
+	current, err := json.Marshal(p)
+	if err != nil {
+		return p, err
+	}
+	var target interface{}
+	if err = decodeJSON(current, &target); err != nil {
+		return p, err
+	}
+
+	merged, err := json.Marshal(mergePatch(target, changes))
+	if err != nil {
+		return p, err
+	}
+	patched := model.Product{}
+	if err = json.Unmarshal(merged, &patched); err != nil {
+		return p, apperror.InvalidInput("the patched product is malformed: %v", err)
+	}
+
+	patched.Id, patched.CreatedAt, patched.UpdatedAt, patched.Version = p.Id, p.CreatedAt, p.UpdatedAt, p.Version
+	return patched, nil
+}
+
+// mergePatch applies patch to target as RFC 7396 describes: objects are merged recursively, null removes a member
+// and any other value replaces the target.
+func mergePatch(target interface{}, patch interface{}) interface{} {
+	changes, ok := patch.(map[string]interface{})
+	if !ok {
+		return patch
+	}
+	object, ok := target.(map[string]interface{})
+	if !ok {
+		object = make(map[string]interface{}, len(changes))
+	}
+
+	for key, value := range changes {
+		if value == nil {
+			delete(object, key)
+			continue
+		}
+		object[key] = mergePatch(object[key], value)
+	}
+	return object
+}
+
+// decodeJSON keeps numbers as json.Number, so large prices survive the round trip.
+func decodeJSON(data []byte, v interface{}) error {
+	decoder := json.NewDecoder(bytes.NewReader(data))
+	decoder.UseNumber()
+	return decoder.Decode(v)
+}
+
+// specificationKeyRegexp keeps facet keys to plain JSON object keys.
+var specificationKeyRegexp = regexp.MustCompile(`^[A-Za-z0-9_-]{1,64}$`)
+
+// parseFacets reads the facets query parameter, the price facet uses the configured ranges unless
+// price_ranges is given.
+func (h *Handler) parseFacets(query url.Values) (storage.FacetRequest, error) {
+	request := storage.FacetRequest{}
+	if query.Get("facets") == "" {
+		return request, nil
+	}
+
+	price := false
+	for _, name := range strings.Split(query.Get("facets"), ",") {
+		name = strings.TrimSpace(name)
+		switch {
+		case name == storage.FacetCategory:
+			request.Category = true
+		case name == storage.FacetCurrency:
+			request.Currency = true
+		case name == storage.FacetRating:
+			request.Rating = true
+		case name == storage.FacetPrice:
+			price = true
+		case strings.HasPrefix(name, storage.FacetSpecification):
+			key := strings.TrimPrefix(name, storage.FacetSpecification)
+			if !specificationKeyRegexp.MatchString(key) {
+				return request, apperror.Invalid(apperror.Field("facets", "%q is not a valid specification key", key))
+			}
+			if !slices.Contains(request.SpecificationKeys, key) {
+				request.SpecificationKeys = append(request.SpecificationKeys, key)
+			}
+		default:
+			return request, apperror.Invalid(apperror.Field("facets", "unknown facet %q", name))
+		}
+	}
+	if len(request.SpecificationKeys) > maxSpecificationFacets {
+		return request, apperror.Invalid(apperror.Field("facets", "at most %d specification keys are allowed", maxSpecificationFacets))
+	}
+
+	if !price {
+		return request, nil
+	}
+	request.PriceRanges = h.priceRanges
+	if s := query.Get("price_ranges"); s != "" {
+		bounds := make([]int64, 0)
+		for _, part := range strings.Split(s, ",") {
+			v, err := strconv.ParseInt(strings.TrimSpace(part), 10, 64)
+			if err != nil {
+				return request, apperror.Invalid(apperror.Field("price_ranges", "must be comma separated integers"))
+			}
+			if len(bounds) > 0 && v <= bounds[len(bounds)-1] {
+				return request, apperror.Invalid(apperror.Field("price_ranges", "must be strictly ascending"))
+			}
+			bounds = append(bounds, v)
+		}
+		request.PriceRanges = bounds
+	}
+	if len(request.PriceRanges) == 0 {
+		return request, apperror.Invalid(apperror.Field("price_ranges", "are required for the price facet"))
+	}
+
+	return request, nil
+}
+
+func parseOptions(query url.Values) (storage.Options, error) {
+	options := storage.Options{}
+	f := &options.Filter
+
+	var err error
+	if f.CategoryId, err = parseInt32(query, "category_id"); err != nil {
+		return options, err
+	}
+	if f.CurrencyId, err = parseInt32(query, "currency_id"); err != nil {
+		return options, err
+	}
+	if f.PriceFrom, err = parseInt64(query, "price_from"); err != nil {
+		return options, err
+	}
+	if f.PriceTo, err = parseInt64(query, "price_to"); err != nil {
+		return options, err
+	}
+	if f.RatingFrom, err = parseInt32(query, "rating_from"); err != nil {
+		return options, err
+	}
+	if f.RatingTo, err = parseInt32(query, "rating_to"); err != nil {
+		return options, err
+	}
+	f.Name = query.Get("name")
+	if f.Specification, err = parseSpecification(query); err != nil {
+		return options, err
+	}
+
+	if sort := query.Get("sort"); sort != "" {
+		options.SortBy = storage.SortField(sort)
+		if !options.SortBy.Valid() {
+			return options, apperror.InvalidInput("unknown sort field %q", sort)
+		}
+	}
+	switch query.Get("order") {
+	case "", "asc":
+	case "desc":
+		options.SortDesc = true
+	default:
+		return options, apperror.InvalidInput("order must be asc or desc")
+	}
+
+	if options.Limit, err = parseUint(query, "limit"); err != nil {
+		return options, err
+	}
+	switch {
+	case options.Limit == 0:
+		options.Limit = defaultLimit
+	case options.Limit > maxLimit:
+		return options, apperror.InvalidInput("limit must not exceed %d", maxLimit)
+	}
+	if options.Offset, err = parseUint(query, "offset"); err != nil {
+		return options, err
+	}
+
+	return options, nil
+}
+
+// parseSpecification reads the spec.* parameters. The operator ends up in the parameter name, e.g.
+// "spec.ram_gb>=16" is parsed as "spec.ram_gb>" with the value "16", so the expression is joined back.
+func parseSpecification(query url.Values) ([]storage.SpecFilter, error) {
+	names := make([]string, 0)
+	for name := range query {
+		if strings.HasPrefix(name, "spec.") {
+			names = append(names, name)
+		}
+	}
+	// a stable order keeps the generated query the same
+	slices.Sort(names)
+
+	filters := make([]storage.SpecFilter, 0)
+	for _, name := range names {
+		for _, value := range query[name] {
+			expr := name
+			if value != "" {
+				expr += "=" + value
+			}
+			f, err := storage.ParseSpecFilter(expr)
+			if err != nil {
+				return nil, apperror.Invalid(apperror.Field(name, "%v", err))
+			}
+			filters = append(filters, f)
+		}
+	}
+	if len(filters) > maxSpecificationFilters {
+		return nil, apperror.InvalidInput("at most %d specification filters are allowed", maxSpecificationFilters)
+	}
+	return filters, nil
+}
+
+func parseInt32(query url.Values, name string) (*int32, error) {
+	s := query.Get(name)
+	if s == "" {
+		return nil, nil
+	}
+	v, err := strconv.ParseInt(s, 10, 32)
+	if err != nil {
+		return nil, apperror.InvalidInput("%s must be an integer", name)
+	}
+	i := int32(v)
+	return &i, nil
+}
+
+func parseInt64(query url.Values, name string) (*int64, error) {
+	s := query.Get(name)
+	if s == "" {
+		return nil, nil
+	}
+	v, err := strconv.ParseInt(s, 10, 64)
+	if err != nil {
+		return nil, apperror.InvalidInput("%s must be an integer", name)
+	}
+	return &v, nil
+}
+
+func parseUint(query url.Values, name string) (uint64, error) {
+	s := query.Get(name)
+	if s == "" {
+		return 0, nil
+	}
+	v, err := strconv.ParseUint(s, 10, 64)
+	if err != nil {
+		return 0, apperror.InvalidInput("%s must be a non-negative integer", name)
+	}
+	return v, nil
+}
@@ -0,0 +1,238 @@
+package handler
+
+import (
+	"encoding/json"
+	"errors"
+	"net/http"
+	"net/http/httptest"
+	"net/url"
+	"prod/internal/domain/product/model"
+	"prod/internal/domain/product/storage"
+	"prod/pkg/apperror"
+	"reflect"
+	"strconv"
+	"strings"
+	"testing"
+	"time"
+)
+
+func TestIfMatch(t *testing.T) {
+	tests := []struct {
+		name    string
+		header  string
+		want    int32
+		wantErr error
+	}{
+		{name: "missing", header: "", wantErr: apperror.ErrPreconditionRequired},
+		{name: "any", header: "*", want: storage.AnyVersion},
+		{name: "strong", header: `"3"`, want: 3},
+		{name: "spaces", header: ` "3" `, want: 3},
+		{name: "weak", header: `W/"3"`, wantErr: apperror.ErrPreconditionFailed},
+		{name: "weak and strong", header: `W/"2", "3"`, want: 3},
+		{name: "same tag twice", header: `"3", "3"`, want: 3},
+		{name: "several versions", header: `"2", "3"`, wantErr: apperror.ErrPreconditionFailed},
+		{name: "unquoted", header: `3`, wantErr: apperror.ErrPreconditionFailed},
+		{name: "leading zero", header: `"03"`, wantErr: apperror.ErrPreconditionFailed},
+		{name: "not a version", header: `"abc"`, wantErr: apperror.ErrPreconditionFailed},
+		{name: "zero version", header: `"0"`, wantErr: apperror.ErrPreconditionFailed},
+		{name: "compressed tag", header: `"3-gzip"`, wantErr: apperror.ErrPreconditionFailed},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			r := httptest.NewRequest(http.MethodPut, "/api/products/1", nil)
+			if tt.header != "" {
+				r.Header.Set("If-Match", tt.header)
+			}
+
+			got, err := ifMatch(r)
+			if !errors.Is(err, tt.wantErr) {
+				t.Fatalf("ifMatch() error = %v, want %v", err, tt.wantErr)
+			}
+			if got != tt.want {
+				t.Fatalf("ifMatch() = %d, want %d", got, tt.want)
+			}
+		})
+	}
+}
+
+func TestApplyPatch(t *testing.T) {
+	imageId := "0f8fad5b-d9cb-469f-a165-70867728950e"
+	createdAt := time.Date(2024, 5, 1, 12, 0, 0, 0, time.UTC)
+	current := model.Product{
+		Id:            "1",
+		Name:          "phone",
+		Description:   "a phone",
+		ImageId:       &imageId,
+		Price:         100,
+		CurrencyId:    1,
+		Rating:        4,
+		CategoryId:    2,
+		Specification: json.RawMessage(`{"color":"red","size":{"width":7,"height":15}}`),
+		CreatedAt:     createdAt,
+		Version:       3,
+	}
+
+	tests := []struct {
+		name    string
+		patch   string
+		modify  func(p *model.Product)
+		wantErr bool
+	}{
+		{name: "empty", patch: `{}`, modify: func(p *model.Product) {}},
+		{
+			name:   "scalar fields",
+			patch:  `{"name":"smartphone","price":9007199254740993,"rating":5}`,
+			modify: func(p *model.Product) { p.Name = "smartphone"; p.Price = 9007199254740993; p.Rating = 5 },
+		},
+		{
+			name:   "null removes the image",
+			patch:  `{"image_id":null}`,
+			modify: func(p *model.Product) { p.ImageId = nil },
+		},
+		{
+			name:  "specification is merged",
+			patch: `{"specification":{"color":null,"ram_gb":16,"size":{"width":8}}}`,
+			modify: func(p *model.Product) {
+				p.Specification = json.RawMessage(`{"ram_gb":16,"size":{"height":15,"width":8}}`)
+			},
+		},
+		{
+			name:   "specification is replaced by a non-object",
+			patch:  `{"specification":[1]}`,
+			modify: func(p *model.Product) { p.Specification = json.RawMessage(`[1]`) },
+		},
+		{
+			name:   "null removes the name",
+			patch:  `{"name":null}`,
+			modify: func(p *model.Product) { p.Name = "" },
+		},
+		{
+			name:   "read-only fields are ignored",
+			patch:  `{"id":"2","version":10,"created_at":"2020-01-01T00:00:00Z","updated_at":"2020-01-01T00:00:00Z"}`,
+			modify: func(p *model.Product) {},
+		},
+		{name: "not an object", patch: `[{"name":"phone"}]`, wantErr: true},
+		{name: "null", patch: `null`, wantErr: true},
+		{name: "wrong type", patch: `{"price":"cheap"}`, wantErr: true},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			got, err := applyPatch(current, json.RawMessage(tt.patch))
+			if tt.wantErr {
+				if !errors.Is(err, apperror.ErrInvalidInput) {
+					t.Fatalf("applyPatch() error = %v, want ErrInvalidInput", err)
+				}
+				return
+			}
+			if err != nil {
+				t.Fatalf("applyPatch() error = %v", err)
+			}
+
+			want := current
+			tt.modify(&want)
+			if !jsonEqual(got.Specification, want.Specification) {
+				t.Fatalf("specification = %s, want %s", got.Specification, want.Specification)
+			}
+			got.Specification, want.Specification = nil, nil
+			if !reflect.DeepEqual(got, want) {
+				t.Fatalf("applyPatch() = %+v, want %+v", got, want)
+			}
+		})
+	}
+}
+
+func jsonEqual(a json.RawMessage, b json.RawMessage) bool {
+	var va, vb interface{}
+	if json.Unmarshal(a, &va) != nil || json.Unmarshal(b, &vb) != nil {
+		return false
+	}
+	return reflect.DeepEqual(va, vb)
+}
+
+func TestParseSpecification(t *testing.T) {
+	tooMany := make([]string, 0, maxSpecificationFilters+1)
+	for i := 0; i <= maxSpecificationFilters; i++ {
+		tooMany = append(tooMany, "spec.k"+strconv.Itoa(i)+"=1")
+	}
+
+	tests := []struct {
+		name      string
+		query     string
+		want      []storage.SpecFilter
+		wantField string
+		wantErr   bool
+	}{
+		{name: "none", query: "name=phone&limit=10", want: []storage.SpecFilter{}},
+		{
+			name:  "each operator",
+			query: "spec.a=1&spec.b!=2&spec.c>3&spec.d>=4&spec.e<5&spec.f<=6&spec.g%20contains%20x",
+			want: []storage.SpecFilter{
+				{Path: []string{"a"}, Operator: storage.SpecEq, Value: "1"},
+				{Path: []string{"b"}, Operator: storage.SpecNotEq, Value: "2"},
+				{Path: []string{"c"}, Operator: storage.SpecGt, Value: "3"},
+				{Path: []string{"d"}, Operator: storage.SpecGtOrEq, Value: "4"},
+				{Path: []string{"e"}, Operator: storage.SpecLt, Value: "5"},
+				{Path: []string{"f"}, Operator: storage.SpecLtOrEq, Value: "6"},
+				{Path: []string{"g"}, Operator: storage.SpecContains, Value: "x"},
+			},
+		},
+		{
+			name:  "sorted by name",
+			query: "spec.size.width<30&spec.color=red",
+			want: []storage.SpecFilter{
+				{Path: []string{"color"}, Operator: storage.SpecEq, Value: "red"},
+				{Path: []string{"size", "width"}, Operator: storage.SpecLt, Value: "30"},
+			},
+		},
+		{
+			name:  "values with operators",
+			query: "spec.note=a%3Db%3E%3Dc&spec.formula!=x%3Cy",
+			want: []storage.SpecFilter{
+				{Path: []string{"formula"}, Operator: storage.SpecNotEq, Value: "x<y"},
+				{Path: []string{"note"}, Operator: storage.SpecEq, Value: "a=b>=c"},
+			},
+		},
+		{
+			name:  "repeated parameter",
+			query: "spec.color=red&spec.color=blue",
+			want: []storage.SpecFilter{
+				{Path: []string{"color"}, Operator: storage.SpecEq, Value: "red"},
+				{Path: []string{"color"}, Operator: storage.SpecEq, Value: "blue"},
+			},
+		},
+		{name: "invalid key", query: "spec.co%27lor=red", wantField: "spec.co'lor", wantErr: true},
+		{name: "empty key", query: "spec..x=1", wantField: "spec..x", wantErr: true},
+		{name: "no operator", query: "spec.color", wantField: "spec.color", wantErr: true},
+		{name: "not a number", query: "spec.ram_gb>=many", wantField: "spec.ram_gb>", wantErr: true},
+		{name: "too many", query: strings.Join(tooMany, "&"), wantErr: true},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			query, err := url.ParseQuery(tt.query)
+			if err != nil {
+				t.Fatalf("ParseQuery() error = %v", err)
+			}
+
+			got, err := parseSpecification(query)
+			if tt.wantErr {
+				var appErr *apperror.Error
+				if !errors.As(err, &appErr) || !errors.Is(err, apperror.ErrInvalidInput) {
+					t.Fatalf("parseSpecification() error = %v, want invalid input", err)
+				}
+				if tt.wantField != "" && (len(appErr.Fields) != 1 || appErr.Fields[0].Field != tt.wantField) {
+					t.Fatalf("parseSpecification() fields = %+v, want %s", appErr.Fields, tt.wantField)
+				}
+				return
+			}
+			if err != nil {
+				t.Fatalf("parseSpecification() error = %v", err)
+			}
+			if !reflect.DeepEqual(got, tt.want) {
+				t.Fatalf("parseSpecification() = %#v, want %#v", got, tt.want)
+			}
+		})
+	}
+}
@@ -1,17 +1,77 @@
 package model
 
-import "time"
+import (
+	"encoding/json"
+	"prod/pkg/validate"
+	"strconv"
+	"time"
+)
 
 type Product struct {
-	Id            string     `json:"id"`
-	Name          string     `json:"name"`
-	Description   string     `json:"description"`
-	ImageId       *string    `json:"image_id"`
-	Price         int64      `json:"price"`
-	CurrencyId    int32      `json:"currency_id"`
-	Rating        int32      `json:"rating"`
-	CategoryId    int32      `json:"category_id"`
-	Specification *string    `json:"specification"`
-	CreatedAt     time.Time  `json:"created_at"`
-	UpdatedAt     *time.Time `json:"updated_at"`
+	Id            string          `json:"id"`
+	Name          string          `json:"name" validate:"required,maxlen=255"`
+	Description   string          `json:"description" validate:"maxlen=5000"`
+	ImageId       *string         `json:"image_id" validate:"omitempty,uuid"`
+	Price         int64           `json:"price" validate:"min=0"`
+	CurrencyId    int32           `json:"currency_id" validate:"required,min=1"`
+	Rating        int32           `json:"rating" validate:"min=0,max=5"`
+	CategoryId    int32           `json:"category_id" validate:"required,min=1"`
+	Specification json.RawMessage `json:"specification" validate:"omitempty,json_object" swaggertype:"object"`
+	CreatedAt     time.Time       `json:"created_at"`
+	UpdatedAt     *time.Time      `json:"updated_at"`
+	// Version grows with every update, it is the ETag of the product.
+	Version int32 `json:"version"`
+}
+
+// Validate checks the fields of the product itself, references are checked by the validator package.
+func (p Product) Validate() error {
+	return validate.Struct(p)
+}
+
+// LastModified is the time of the last change of the product.
+func (p Product) LastModified() time.Time {
+	if p.UpdatedAt != nil {
+		return *p.UpdatedAt
+	}
+	return p.CreatedAt
+}
+
+// ETag is the strong entity tag of the product, compared with If-Match by updates.
+func (p Product) ETag() string {
+	return `"` + strconv.Itoa(int(p.Version)) + `"`
+}
+
+// SearchHit is a product found by full-text search. The highlights are HTML-escaped text, the matched words are
+// marked with <mark> tags.
+type SearchHit struct {
+	Product
+	Rank          float32 `json:"rank"`
+	NameHighlight string  `json:"name_highlight"`
+	Snippet       string  `json:"snippet"`
+}
+
+// Suggestion is a product name completing what the user has typed so far.
+type Suggestion struct {
+	Id           string  `json:"id"`
+	Name         string  `json:"name"`
+	CategoryId   int32   `json:"category_id"`
+	CategoryName *string `json:"category_name"`
+	Score        float32 `json:"score"`
+}
+
+// FacetValue counts the products having a value, price ranges also carry their bounds, To is exclusive.
+type FacetValue struct {
+	Value string `json:"value"`
+	Count int64  `json:"count"`
+	From  *int64 `json:"from,omitempty"`
+	To    *int64 `json:"to,omitempty"`
+}
+
+// Facets are keyed by the facet name, e.g. "category" or "specification.color".
+type Facets map[string][]FacetValue
+
+// Page is the product list with the facets of the filtered products.
+type Page struct {
+	Items  []Product `json:"items"`
+	Facets Facets    `json:"facets"`
 }
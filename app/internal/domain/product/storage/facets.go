@@ -0,0 +1,194 @@
+package storage
+
+import (
+	"context"
+	"fmt"
+	sq "github.com/Masterminds/squirrel"
+	"github.com/jackc/pgx/v4"
+	"prod/internal/domain/product/model"
+	"prod/pkg/client/postgresql"
+	db "prod/pkg/client/postgresql/model"
+	"sort"
+	"strconv"
+	"strings"
+)
+
+const (
+	FacetCategory      = "category"
+	FacetCurrency      = "currency"
+	FacetRating        = "rating"
+	FacetPrice         = "price"
+	FacetSpecification = "specification."
+)
+
+// FacetRequest selects the facets to count. PriceRanges are ascending bounds, n bounds make n+1 ranges
+// with open ends.
+type FacetRequest struct {
+	Category          bool
+	Currency          bool
+	Rating            bool
+	PriceRanges       []int64
+	SpecificationKeys []string
+}
+
+func (r FacetRequest) Empty() bool {
+	return !r.Category && !r.Currency && !r.Rating && len(r.PriceRanges) == 0 && len(r.SpecificationKeys) == 0
+}
+
+// Facets counts the products matching filter per value of every requested facet in a single query.
+// The filter of a facet's own dimension is ignored for it, so the other values stay visible as choices.
+// Products without a value, e.g. with a null specification value, are not counted.
+func (s *ProductStorage) Facets(ctx context.Context, filter Filter, request FacetRequest) (model.Facets, error) {
+	if request.Empty() {
+		return make(model.Facets), nil
+	}
+
+	sql, args, err := facetsQuery(filter, request)
+	if err != nil {
+		return nil, db.ErrCreateQuery(err)
+	}
+
+	rows, err := s.client.Query(ctx, sql, args...)
+	if err != nil {
+		return nil, db.ErrDoQuery(postgresql.ClassifyError(err))
+	}
+
+	defer rows.Close()
+
+	return scanFacets(rows, request.PriceRanges)
+}
+
+func facetsQuery(filter Filter, request FacetRequest) (string, []interface{}, error) {
+	parts := make([]sq.SelectBuilder, 0)
+	count := func(name string, value string, f Filter, args ...interface{}) sq.SelectBuilder {
+		return f.apply(sq.Select().
+			Column(sq.Expr("?::text AS facet", name)).
+			Column(value+" AS value", args...).
+			Column("count(*)").
+			From(scheme + "." + table)).
+			GroupBy("value")
+	}
+
+	if request.Category {
+		f := filter
+		f.CategoryId = nil
+		parts = append(parts, count(FacetCategory, "category_id::text", f))
+	}
+	if request.Currency {
+		f := filter
+		f.CurrencyId = nil
+		parts = append(parts, count(FacetCurrency, "currency_id::text", f))
+	}
+	if request.Rating {
+		f := filter
+		f.RatingFrom, f.RatingTo = nil, nil
+		parts = append(parts, count(FacetRating, "rating::text", f))
+	}
+	if len(request.PriceRanges) > 0 {
+		f := filter
+		f.PriceFrom, f.PriceTo = nil, nil
+		// width_bucket numbers the ranges from 0, below the first bound, to len(bounds)
+		parts = append(parts, count(FacetPrice, "width_bucket(price, ?::bigint[])::text", f, request.PriceRanges))
+	}
+	for _, key := range request.SpecificationKeys {
+		parts = append(parts, count(FacetSpecification+key, "specification->>?", filter, key).
+			// ?? is the jsonb key operator, escaped from the placeholders, it lets the index skip products
+			// without the key, ->> is also null for a null value
+			Where("specification ?? ?", key).
+			Where("specification->>? IS NOT NULL", key))
+	}
+
+	return unionAll(parts)
+}
+
+// scanFacets reads the rows of facetsQuery, rows with a null value are skipped.
+func scanFacets(rows pgx.Rows, priceRanges []int64) (model.Facets, error) {
+	facets := make(model.Facets)
+	for rows.Next() {
+		var name string
+		var value *string
+		v := model.FacetValue{}
+		if err := rows.Scan(&name, &value, &v.Count); err != nil {
+			return nil, db.ErrScan(postgresql.ClassifyError(err))
+		}
+		if value == nil {
+			continue
+		}
+		v.Value = *value
+		if name == FacetPrice {
+			v = priceRange(v, priceRanges)
+		}
+		facets[name] = append(facets[name], v)
+	}
+	if err := rows.Err(); err != nil {
+		return nil, db.ErrDoQuery(postgresql.ClassifyError(err))
+	}
+
+	for name, values := range facets {
+		sortFacet(name, values)
+	}
+	return facets, nil
+}
+
+func unionAll(parts []sq.SelectBuilder) (string, []interface{}, error) {
+	queries := make([]string, 0, len(parts))
+	args := make([]interface{}, 0)
+	for _, part := range parts {
+		sql, partArgs, err := part.ToSql()
+		if err != nil {
+			return "", nil, err
+		}
+		queries = append(queries, "("+sql+")")
+		args = append(args, partArgs...)
+	}
+
+	sql, err := sq.Dollar.ReplacePlaceholders(strings.Join(queries, " UNION ALL "))
+	return sql, args, err
+}
+
+// priceRange turns the bucket number of width_bucket into the bounds of the range.
+func priceRange(v model.FacetValue, bounds []int64) model.FacetValue {
+	bucket, err := strconv.Atoi(v.Value)
+	if err != nil {
+		return v
+	}
+	if bucket > 0 {
+		v.From = &bounds[bucket-1]
+	}
+	if bucket < len(bounds) {
+		v.To = &bounds[bucket]
+	}
+
+	switch {
+	case v.From == nil:
+		v.Value = fmt.Sprintf("-%d", *v.To)
+	case v.To == nil:
+		v.Value = fmt.Sprintf("%d-", *v.From)
+	default:
+		v.Value = fmt.Sprintf("%d-%d", *v.From, *v.To)
+	}
+	return v
+}
+
+// sortFacet orders price ranges and ratings by their values, the other facets by the count.
+func sortFacet(name string, values []model.FacetValue) {
+	switch name {
+	case FacetPrice:
+		sort.Slice(values, func(i, j int) bool {
+			return values[i].From == nil || (values[j].From != nil && *values[i].From < *values[j].From)
+		})
+	case FacetRating:
+		sort.Slice(values, func(i, j int) bool {
+			a, _ := strconv.Atoi(values[i].Value)
+			b, _ := strconv.Atoi(values[j].Value)
+			return a > b
+		})
+	default:
+		sort.Slice(values, func(i, j int) bool {
+			if values[i].Count != values[j].Count {
+				return values[i].Count > values[j].Count
+			}
+			return values[i].Value < values[j].Value
+		})
+	}
+}
@@ -0,0 +1,120 @@
+package storage
+
+import (
+	"github.com/jackc/pgx/v4"
+	"prod/internal/domain/product/model"
+	"reflect"
+	"testing"
+)
+
+func TestFacetsQuery(t *testing.T) {
+	tests := []struct {
+		name     string
+		filter   Filter
+		request  FacetRequest
+		wantSql  string
+		wantArgs []interface{}
+	}{
+		{
+			name:    "own dimension is not filtered",
+			filter:  Filter{CategoryId: int32Ptr(1)},
+			request: FacetRequest{Category: true, PriceRanges: []int64{100, 200}},
+			wantSql: "(SELECT $1::text AS facet, category_id::text AS value, count(*) FROM public.product GROUP BY value) UNION ALL " +
+				"(SELECT $2::text AS facet, width_bucket(price, $3::bigint[])::text AS value, count(*) FROM public.product " +
+				"WHERE category_id = $4 GROUP BY value)",
+			wantArgs: []interface{}{"category", "price", []int64{100, 200}, int32(1)},
+		},
+		{
+			name:    "specification skips missing keys and null values",
+			request: FacetRequest{SpecificationKeys: []string{"color"}},
+			wantSql: "(SELECT $1::text AS facet, specification->>$2 AS value, count(*) FROM public.product " +
+				"WHERE specification ? $3 AND specification->>$4 IS NOT NULL GROUP BY value)",
+			wantArgs: []interface{}{"specification.color", "color", "color", "color"},
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			sql, args, err := facetsQuery(tt.filter, tt.request)
+			if err != nil {
+				t.Fatalf("facetsQuery() error = %v", err)
+			}
+			if sql != tt.wantSql {
+				t.Fatalf("facetsQuery() sql =\n%s\nwant\n%s", sql, tt.wantSql)
+			}
+			if !reflect.DeepEqual(args, tt.wantArgs) {
+				t.Fatalf("facetsQuery() args = %#v, want %#v", args, tt.wantArgs)
+			}
+		})
+	}
+}
+
+// fakeRows returns facet rows of name, value and count, a nil value is a NULL.
+type fakeRows struct {
+	pgx.Rows
+	rows [][]interface{}
+	next int
+}
+
+func (r *fakeRows) Next() bool {
+	r.next++
+	return r.next <= len(r.rows)
+}
+
+func (r *fakeRows) Scan(dest ...interface{}) error {
+	for i, value := range r.rows[r.next-1] {
+		target := reflect.ValueOf(dest[i]).Elem()
+		if value == nil {
+			target.Set(reflect.Zero(target.Type()))
+			continue
+		}
+		v := reflect.ValueOf(value)
+		if target.Kind() == reflect.Pointer {
+			p := reflect.New(v.Type())
+			p.Elem().Set(v)
+			v = p
+		}
+		target.Set(v)
+	}
+	return nil
+}
+
+func (r *fakeRows) Err() error {
+	return nil
+}
+
+func TestScanFacets(t *testing.T) {
+	rows := &fakeRows{rows: [][]interface{}{
+		{FacetCategory, "1", int64(3)},
+		{FacetCategory, nil, int64(2)},
+		{FacetCategory, "2", int64(5)},
+		{FacetSpecification + "color", "red", int64(1)},
+		{FacetSpecification + "color", nil, int64(4)},
+		{FacetSpecification + "size", nil, int64(1)},
+		{FacetRating, "4", int64(1)},
+		{FacetRating, "5", int64(2)},
+		{FacetPrice, "2", int64(1)},
+		{FacetPrice, "0", int64(2)},
+		{FacetPrice, "1", int64(3)},
+	}}
+	bounds := []int64{100, 200}
+
+	got, err := scanFacets(rows, bounds)
+	if err != nil {
+		t.Fatalf("scanFacets() error = %v", err)
+	}
+
+	want := model.Facets{
+		FacetCategory:                {{Value: "2", Count: 5}, {Value: "1", Count: 3}},
+		FacetSpecification + "color": {{Value: "red", Count: 1}},
+		FacetRating:                  {{Value: "5", Count: 2}, {Value: "4", Count: 1}},
+		FacetPrice: {
+			{Value: "-100", Count: 2, To: &bounds[0]},
+			{Value: "100-200", Count: 3, From: &bounds[0], To: &bounds[1]},
+			{Value: "200-", Count: 1, From: &bounds[1]},
+		},
+	}
+	if !reflect.DeepEqual(got, want) {
+		t.Fatalf("scanFacets() = %+v, want %+v", got, want)
+	}
+}
@@ -0,0 +1,113 @@
+package storage
+
+import (
+	"fmt"
+	sq "github.com/Masterminds/squirrel"
+	"strings"
+)
+
+type SortField string
+
+const (
+	SortByName      SortField = "name"
+	SortByPrice     SortField = "price"
+	SortByRating    SortField = "rating"
+	SortByCreatedAt SortField = "created_at"
+	// SortByRelevance orders search results by their rank, it is the default of Search and unknown to All.
+	SortByRelevance SortField = "relevance"
+)
+
+func (f SortField) Valid() bool {
+	switch f {
+	case SortByName, SortByPrice, SortByRating, SortByCreatedAt, SortByRelevance:
+		return true
+	}
+	return false
+}
+
+type Filter struct {
+	CategoryId *int32
+	CurrencyId *int32
+	PriceFrom  *int64
+	PriceTo    *int64
+	RatingFrom *int32
+	RatingTo   *int32
+	// Name matches products whose name contains the value, case-insensitively.
+	Name string
+	// Specification filters must all match.
+	Specification []SpecFilter
+}
+
+type Options struct {
+	Filter   Filter
+	SortBy   SortField
+	SortDesc bool
+	Limit    uint64
+	Offset   uint64
+}
+
+func (f Filter) apply(query sq.SelectBuilder) sq.SelectBuilder {
+	if f.CategoryId != nil {
+		query = query.Where(sq.Eq{"category_id": *f.CategoryId})
+	}
+	if f.CurrencyId != nil {
+		query = query.Where(sq.Eq{"currency_id": *f.CurrencyId})
+	}
+	if f.PriceFrom != nil {
+		query = query.Where(sq.GtOrEq{"price": *f.PriceFrom})
+	}
+	if f.PriceTo != nil {
+		query = query.Where(sq.LtOrEq{"price": *f.PriceTo})
+	}
+	if f.RatingFrom != nil {
+		query = query.Where(sq.GtOrEq{"rating": *f.RatingFrom})
+	}
+	if f.RatingTo != nil {
+		query = query.Where(sq.LtOrEq{"rating": *f.RatingTo})
+	}
+	if f.Name != "" {
+		// lower(name) rather than ILIKE, so that product_name_trgm_idx serves the match
+		query = query.Where(sq.Like{"lower(name)": "%" + likeEscaper.Replace(strings.ToLower(f.Name)) + "%"})
+	}
+	for _, spec := range f.Specification {
+		query = query.Where(spec)
+	}
+	return query
+}
+
+func (o Options) apply(query sq.SelectBuilder) (sq.SelectBuilder, error) {
+	if o.SortBy == SortByRelevance {
+		return query, fmt.Errorf("sort field %q needs a search", o.SortBy)
+	}
+	return o.applySorted(query, SortByCreatedAt)
+}
+
+// applySorted applies the options with sortBy as the default sort field.
+func (o Options) applySorted(query sq.SelectBuilder, sortBy SortField) (sq.SelectBuilder, error) {
+	query = o.Filter.apply(query)
+
+	if o.SortBy != "" {
+		sortBy = o.SortBy
+	}
+	if !sortBy.Valid() {
+		return query, fmt.Errorf("unknown sort field %q", sortBy)
+	}
+	order := "ASC"
+	if o.SortDesc {
+		order = "DESC"
+	}
+	if sortBy == SortByRelevance {
+		// the most relevant always come first
+		query = query.OrderBy("rank DESC", "id ASC")
+	} else {
+		query = query.OrderBy(fmt.Sprintf("%s %s", sortBy, order), "id "+order)
+	}
+
+	if o.Limit > 0 {
+		query = query.Limit(o.Limit)
+	}
+	if o.Offset > 0 {
+		query = query.Offset(o.Offset)
+	}
+	return query, nil
+}
@@ -0,0 +1,112 @@
+package storage
+
+import (
+	sq "github.com/Masterminds/squirrel"
+	"reflect"
+	"testing"
+)
+
+func int32Ptr(v int32) *int32 { return &v }
+func int64Ptr(v int64) *int64 { return &v }
+
+func TestOptionsApply(t *testing.T) {
+	base := sq.StatementBuilder.PlaceholderFormat(sq.Dollar).Select("id").From("public.product")
+
+	tests := []struct {
+		name     string
+		options  Options
+		wantSql  string
+		wantArgs []interface{}
+		wantErr  bool
+	}{
+		{
+			name:    "defaults",
+			wantSql: "SELECT id FROM public.product ORDER BY created_at ASC, id ASC",
+		},
+		{
+			name: "filters",
+			options: Options{Filter: Filter{
+				CategoryId: int32Ptr(1),
+				CurrencyId: int32Ptr(2),
+				PriceFrom:  int64Ptr(100),
+				PriceTo:    int64Ptr(200),
+				RatingFrom: int32Ptr(3),
+				RatingTo:   int32Ptr(5),
+			}},
+			wantSql: "SELECT id FROM public.product WHERE category_id = $1 AND currency_id = $2 AND price >= $3 " +
+				"AND price <= $4 AND rating >= $5 AND rating <= $6 ORDER BY created_at ASC, id ASC",
+			wantArgs: []interface{}{int32(1), int32(2), int64(100), int64(200), int32(3), int32(5)},
+		},
+		{
+			name:     "name is lowered",
+			options:  Options{Filter: Filter{Name: "Phone"}},
+			wantSql:  "SELECT id FROM public.product WHERE lower(name) LIKE $1 ORDER BY created_at ASC, id ASC",
+			wantArgs: []interface{}{"%phone%"},
+		},
+		{
+			name:     "name wildcards match literally",
+			options:  Options{Filter: Filter{Name: `100%_\`}},
+			wantSql:  "SELECT id FROM public.product WHERE lower(name) LIKE $1 ORDER BY created_at ASC, id ASC",
+			wantArgs: []interface{}{`%100\%\_\\%`},
+		},
+		{
+			name:    "sort, limit and offset",
+			options: Options{SortBy: SortByPrice, SortDesc: true, Limit: 10, Offset: 20},
+			wantSql: "SELECT id FROM public.product ORDER BY price DESC, id DESC LIMIT 10 OFFSET 20",
+		},
+		{
+			name:    "relevance needs a search",
+			options: Options{SortBy: SortByRelevance},
+			wantErr: true,
+		},
+		{
+			name:    "unknown sort field",
+			options: Options{SortBy: "id; DROP TABLE product"},
+			wantErr: true,
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			query, err := tt.options.apply(base)
+			if tt.wantErr {
+				if err == nil {
+					t.Fatal("expected an error")
+				}
+				return
+			}
+			if err != nil {
+				t.Fatal(err)
+			}
+
+			sql, args, err := query.ToSql()
+			if err != nil {
+				t.Fatal(err)
+			}
+			if sql != tt.wantSql {
+				t.Errorf("sql = %q, want %q", sql, tt.wantSql)
+			}
+			if len(args) != 0 || len(tt.wantArgs) != 0 {
+				if !reflect.DeepEqual(args, tt.wantArgs) {
+					t.Errorf("args = %#v, want %#v", args, tt.wantArgs)
+				}
+			}
+		})
+	}
+}
+
+func TestOptionsApplySortedByRelevance(t *testing.T) {
+	base := sq.StatementBuilder.Select("id").From("public.product")
+
+	query, err := Options{}.applySorted(base, SortByRelevance)
+	if err != nil {
+		t.Fatal(err)
+	}
+	sql, _, err := query.ToSql()
+	if err != nil {
+		t.Fatal(err)
+	}
+	if want := "SELECT id FROM public.product ORDER BY rank DESC, id ASC"; sql != want {
+		t.Errorf("sql = %q, want %q", sql, want)
+	}
+}
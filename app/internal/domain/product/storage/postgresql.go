@@ -2,10 +2,15 @@ package storage
 
 import (
 	"context"
+	"errors"
+	"fmt"
 	sq "github.com/Masterminds/squirrel"
 	"prod/internal/domain/product/model"
+	"prod/pkg/apperror"
 	"prod/pkg/client/postgresql"
 	db "prod/pkg/client/postgresql/model"
+	"strings"
+	"unicode"
 )
 
 type ProductStorage struct {
@@ -25,30 +30,53 @@ const (
 	table  = "product"
 )
 
-func (s *ProductStorage) All(ctx context.Context) ([]model.Product, error) {
-	query := s.queryBuilder.Select("id").
-		Column("name").
-		Column("description").
-		Column("image_id").
-		Column("price").
-		Column("currency_id").
-		Column("rating").
-		Column("category_id").
-		Column("specification").
-		Column("created_at").
-		Column("updated_at").
-		From(scheme + "." + table)
-
-	// TODO filtering and sorting
+var columns = []string{
+	"id", "name", "description", "image_id", "price", "currency_id", "rating", "category_id",
+	"specification", "created_at", "updated_at", "version",
+}
+
+// AnyVersion skips the version check of Update and Delete.
+const AnyVersion int32 = 0
+
+type scanner interface {
+	Scan(dest ...interface{}) error
+}
+
+// scanProduct scans the columns of the product followed by extra ones.
+func scanProduct(row scanner, p *model.Product, extra ...interface{}) error {
+	var specification []byte
+	dest := append([]interface{}{
+		&p.Id, &p.Name, &p.Description, &p.ImageId, &p.Price, &p.CurrencyId, &p.Rating, &p.CategoryId,
+		&specification, &p.CreatedAt, &p.UpdatedAt, &p.Version,
+	}, extra...)
+	err := row.Scan(dest...)
+	p.Specification = specification
+	return err
+}
+
+// specificationArg stores a missing or null specification as SQL NULL rather than a JSON null.
+func specificationArg(p model.Product) interface{} {
+	if len(p.Specification) == 0 || string(p.Specification) == "null" {
+		return nil
+	}
+	return []byte(p.Specification)
+}
+
+func (s *ProductStorage) All(ctx context.Context, options Options) ([]model.Product, error) {
+	query, err := options.apply(s.queryBuilder.Select(columns...).
+		From(scheme + "." + table))
+	if err != nil {
+		return nil, db.ErrCreateQuery(err)
+	}
 
 	sql, args, err := query.ToSql()
 	if err != nil {
-		return nil, err
+		return nil, db.ErrCreateQuery(err)
 	}
 
 	rows, err := s.client.Query(ctx, sql, args...)
 	if err != nil {
-		return nil, err
+		return nil, db.ErrDoQuery(postgresql.ClassifyError(err))
 	}
 
 	defer rows.Close()
@@ -56,15 +84,241 @@ func (s *ProductStorage) All(ctx context.Context) ([]model.Product, error) {
 	list := make([]model.Product, 0)
 	for rows.Next() {
 		p := model.Product{}
-		if err = rows.Scan(
-			&p.Id, &p.Name, &p.Description, &p.ImageId, &p.Price, &p.CurrencyId, &p.Rating, &p.CategoryId,
-			&p.Specification, &p.CreatedAt, &p.UpdatedAt,
-		); err != nil {
-			err = db.ErrScan(postgresql.ParsePgError(err))
+		if err = scanProduct(rows, &p); err != nil {
+			err = db.ErrScan(postgresql.ClassifyError(err))
 			return nil, err
 		}
 		list = append(list, p)
 	}
+	if err = rows.Err(); err != nil {
+		return nil, db.ErrDoQuery(postgresql.ClassifyError(err))
+	}
+
+	return list, nil
+}
+
+// headlineOptions mark the matched words, snippets are at most two fragments of the description.
+const (
+	nameHeadlineOptions    = "HighlightAll=true, StartSel=<mark>, StopSel=</mark>"
+	snippetHeadlineOptions = "StartSel=<mark>, StopSel=</mark>, MaxWords=35, MinWords=15, MaxFragments=2"
+)
+
+// htmlEscapes are applied in order, & goes first so that the entities of the others are kept as they are.
+var htmlEscapes = [][2]string{
+	{"&", "&amp;"},
+	{"<", "&lt;"},
+	{">", "&gt;"},
+	{`"`, "&quot;"},
+	{"''", "&#39;"},
+}
+
+// escapeHTML returns the SQL expression of column with the HTML special characters escaped. ts_headline gets the
+// escaped text, so the <mark> tags it adds are the only markup of the highlights.
+func escapeHTML(column string) string {
+	expr := column
+	for _, e := range htmlEscapes {
+		expr = fmt.Sprintf("replace(%s, '%s', '%s')", expr, e[0], e[1])
+	}
+	return expr
+}
+
+// Search finds products matching the web search syntax of text, e.g. `leather -black "running shoes"`,
+// combined with the filter of options and ranked by relevance unless another sort field is given.
+func (s *ProductStorage) Search(ctx context.Context, text string, options Options) ([]model.SearchHit, error) {
+	config := TextSearchConfig(text)
+	query, err := options.applySorted(s.queryBuilder.Select(columns...).
+		Column("ts_rank_cd(search_vector, query) AS rank").
+		Column("ts_headline(?::regconfig, "+escapeHTML("name")+", query, ?)", config, nameHeadlineOptions).
+		Column("ts_headline(?::regconfig, "+escapeHTML("description")+", query, ?)", config, snippetHeadlineOptions).
+		From(scheme+"."+table).
+		CrossJoin("websearch_to_tsquery(?::regconfig, ?) AS query", config, text).
+		Where("search_vector @@ query"), SortByRelevance)
+	if err != nil {
+		return nil, db.ErrCreateQuery(err)
+	}
+
+	sql, args, err := query.ToSql()
+	if err != nil {
+		return nil, db.ErrCreateQuery(err)
+	}
+
+	rows, err := s.client.Query(ctx, sql, args...)
+	if err != nil {
+		return nil, db.ErrDoQuery(postgresql.ClassifyError(err))
+	}
+
+	defer rows.Close()
+
+	list := make([]model.SearchHit, 0)
+	for rows.Next() {
+		h := model.SearchHit{}
+		if err = scanProduct(rows, &h.Product, &h.Rank, &h.NameHighlight, &h.Snippet); err != nil {
+			return nil, db.ErrScan(postgresql.ClassifyError(err))
+		}
+		list = append(list, h)
+	}
+	if err = rows.Err(); err != nil {
+		return nil, db.ErrDoQuery(postgresql.ClassifyError(err))
+	}
 
 	return list, nil
 }
+
+// Suggest completes the product names starting with prefix, names containing a word similar to prefix
+// follow, so that misspellings are still completed. categoryId narrows the suggestions when set.
+func (s *ProductStorage) Suggest(ctx context.Context, prefix string, categoryId *int32, limit uint64) ([]model.Suggestion, error) {
+	prefix = strings.ToLower(prefix)
+	like := likeEscaper.Replace(prefix) + "%"
+
+	query := s.queryBuilder.Select("p.id", "p.name", "p.category_id", "c.name").
+		Column("CASE WHEN lower(p.name) LIKE ? THEN 1 ELSE word_similarity(?, lower(p.name)) END AS score", like, prefix).
+		From(scheme+"."+table+" p").
+		LeftJoin(scheme+".category c ON c.id = p.category_id").
+		Where(sq.Or{
+			sq.Expr("lower(p.name) LIKE ?", like),
+			sq.Expr("? <% lower(p.name)", prefix),
+		}).
+		OrderBy("score DESC", "p.name", "p.id").
+		Limit(limit)
+	if categoryId != nil {
+		query = query.Where(sq.Eq{"p.category_id": *categoryId})
+	}
+
+	sql, args, err := query.ToSql()
+	if err != nil {
+		return nil, db.ErrCreateQuery(err)
+	}
+
+	rows, err := s.client.Query(ctx, sql, args...)
+	if err != nil {
+		return nil, db.ErrDoQuery(postgresql.ClassifyError(err))
+	}
+
+	defer rows.Close()
+
+	list := make([]model.Suggestion, 0)
+	for rows.Next() {
+		sg := model.Suggestion{}
+		if err = rows.Scan(&sg.Id, &sg.Name, &sg.CategoryId, &sg.CategoryName, &sg.Score); err != nil {
+			return nil, db.ErrScan(postgresql.ClassifyError(err))
+		}
+		list = append(list, sg)
+	}
+	if err = rows.Err(); err != nil {
+		return nil, db.ErrDoQuery(postgresql.ClassifyError(err))
+	}
+
+	return list, nil
+}
+
+// likeEscaper makes user input match literally in LIKE patterns.
+var likeEscaper = strings.NewReplacer(`\`, `\\`, "%", `\%`, "_", `\_`)
+
+// TextSearchConfig picks the configuration that stems the words of text: russian when it has Cyrillic letters.
+// Products are indexed with both, so either finds them.
+func TextSearchConfig(text string) string {
+	for _, r := range text {
+		if unicode.Is(unicode.Cyrillic, r) {
+			return "russian"
+		}
+	}
+	return "english"
+}
+
+func (s *ProductStorage) One(ctx context.Context, id string) (model.Product, error) {
+	query := s.queryBuilder.Select(columns...).
+		From(scheme + "." + table).
+		Where(sq.Eq{"id": id})
+
+	return s.queryOne(ctx, query)
+}
+
+func (s *ProductStorage) Create(ctx context.Context, p model.Product) (model.Product, error) {
+	query := s.queryBuilder.Insert(scheme+"."+table).
+		Columns("name", "description", "image_id", "price", "currency_id", "rating", "category_id",
+			"specification").
+		Values(p.Name, p.Description, p.ImageId, p.Price, p.CurrencyId, p.Rating, p.CategoryId,
+			specificationArg(p)).
+		Suffix("RETURNING " + strings.Join(columns, ", "))
+
+	return s.queryOne(ctx, query)
+}
+
+// Update saves p if its stored version is still version, the version is incremented by a trigger.
+// A changed product fails with apperror.ErrPreconditionFailed.
+func (s *ProductStorage) Update(ctx context.Context, p model.Product, version int32) (model.Product, error) {
+	query := s.queryBuilder.Update(scheme+"."+table).
+		Set("name", p.Name).
+		Set("description", p.Description).
+		Set("image_id", p.ImageId).
+		Set("price", p.Price).
+		Set("currency_id", p.CurrencyId).
+		Set("rating", p.Rating).
+		Set("category_id", p.CategoryId).
+		Set("specification", specificationArg(p)).
+		Where(versioned(p.Id, version)).
+		Suffix("RETURNING " + strings.Join(columns, ", "))
+
+	updated, err := s.queryOne(ctx, query)
+	if errors.Is(err, postgresql.ErrNotFound) {
+		return updated, s.conflict(ctx, p.Id, err)
+	}
+	return updated, err
+}
+
+// Delete removes the product if its stored version is still version, like Update.
+func (s *ProductStorage) Delete(ctx context.Context, id string, version int32) error {
+	query := s.queryBuilder.Delete(scheme + "." + table).
+		Where(versioned(id, version)).
+		Suffix("RETURNING id")
+
+	sql, args, err := query.ToSql()
+	if err != nil {
+		return db.ErrCreateQuery(err)
+	}
+
+	var deleted string
+	if err = s.client.QueryRow(ctx, sql, args...).Scan(&deleted); err != nil {
+		err = db.ErrDoQuery(postgresql.ClassifyError(err))
+		if errors.Is(err, postgresql.ErrNotFound) {
+			return s.conflict(ctx, id, err)
+		}
+		return err
+	}
+
+	return nil
+}
+
+func versioned(id string, version int32) sq.Sqlizer {
+	if version == AnyVersion {
+		return sq.Eq{"id": id}
+	}
+	return sq.Eq{"id": id, "version": version}
+}
+
+// conflict tells apart a missing product, reported as notFound, from one that has a different version.
+func (s *ProductStorage) conflict(ctx context.Context, id string, notFound error) error {
+	p, err := s.One(ctx, id)
+	switch {
+	case errors.Is(err, postgresql.ErrNotFound):
+		return notFound
+	case err != nil:
+		return err
+	}
+	return apperror.PreconditionFailed("the product has been changed, its current version is %d", p.Version)
+}
+
+func (s *ProductStorage) queryOne(ctx context.Context, query sq.Sqlizer) (model.Product, error) {
+	p := model.Product{}
+
+	sql, args, err := query.ToSql()
+	if err != nil {
+		return p, db.ErrCreateQuery(err)
+	}
+
+	if err = scanProduct(s.client.QueryRow(ctx, sql, args...), &p); err != nil {
+		return p, db.ErrDoQuery(postgresql.ClassifyError(err))
+	}
+
+	return p, nil
+}
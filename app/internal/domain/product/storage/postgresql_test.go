@@ -0,0 +1,43 @@
+package storage
+
+import (
+	"context"
+	"github.com/jackc/pgx/v4/pgxpool"
+	"os"
+	"testing"
+)
+
+func TestEscapeHTML(t *testing.T) {
+	want := `replace(replace(replace(replace(replace(name, '&', '&amp;'), '<', '&lt;'), '>', '&gt;'), '"', '&quot;'), '''', '&#39;')`
+	if got := escapeHTML("name"); got != want {
+		t.Errorf("escapeHTML() = %s, want %s", got, want)
+	}
+}
+
+// TestHeadlineEscapesHTML runs against the database from TEST_POSTGRES_URL, it is skipped when it is not set.
+func TestHeadlineEscapesHTML(t *testing.T) {
+	url := os.Getenv("TEST_POSTGRES_URL")
+	if url == "" {
+		t.Skip("TEST_POSTGRES_URL is not set")
+	}
+	ctx := context.Background()
+	pool, err := pgxpool.Connect(ctx, url)
+	if err != nil {
+		t.Fatal(err)
+	}
+	t.Cleanup(pool.Close)
+
+	var got string
+	err = pool.QueryRow(ctx,
+		"SELECT ts_headline('english', "+escapeHTML("$1::text")+", websearch_to_tsquery('english', 'shoes'), $2)",
+		`<img src=x onerror="alert(1)"> shoes & 'socks'`, nameHeadlineOptions,
+	).Scan(&got)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	want := `&lt;img src=x onerror=&quot;alert(1)&quot;&gt; <mark>shoes</mark> &amp; &#39;socks&#39;`
+	if got != want {
+		t.Errorf("headline = %s, want %s", got, want)
+	}
+}
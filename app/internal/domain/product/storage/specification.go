@@ -0,0 +1,171 @@
+package storage
+
+import (
+	"encoding/json"
+	"fmt"
+	sq "github.com/Masterminds/squirrel"
+	"math"
+	"regexp"
+	"strconv"
+	"strings"
+)
+
+type SpecOperator string
+
+const (
+	SpecEq       SpecOperator = "="
+	SpecNotEq    SpecOperator = "!="
+	SpecGt       SpecOperator = ">"
+	SpecGtOrEq   SpecOperator = ">="
+	SpecLt       SpecOperator = "<"
+	SpecLtOrEq   SpecOperator = "<="
+	SpecContains SpecOperator = "contains"
+)
+
+// specOperators list longer operators before their prefixes, the first of them found at a position wins.
+var specOperators = []SpecOperator{SpecContains, SpecNotEq, SpecGtOrEq, SpecLtOrEq, SpecEq, SpecGt, SpecLt}
+
+// specKeyRegexp keeps keys to plain JSON object keys, so they are safe inside JSON paths.
+var specKeyRegexp = regexp.MustCompile(`^[A-Za-z0-9_-]{1,64}$`)
+
+// SpecFilter matches products by a value of the specification, Path addresses nested objects.
+type SpecFilter struct {
+	Path     []string
+	Operator SpecOperator
+	Value    string
+}
+
+// ParseSpecFilter parses expressions like "spec.color=red", "spec.ram_gb>=16", "spec.size.width<30"
+// or "spec.tags contains x". Values that look like numbers or booleans are compared as such.
+func ParseSpecFilter(expr string) (SpecFilter, error) {
+	rest, ok := strings.CutPrefix(strings.TrimSpace(expr), "spec.")
+	if !ok {
+		return SpecFilter{}, fmt.Errorf("%q must start with spec.", expr)
+	}
+
+	// the first operator in the expression wins, values may contain operators themselves
+	op, at, sep := SpecOperator(""), -1, ""
+	for _, candidate := range specOperators {
+		candidateSep := string(candidate)
+		if candidate == SpecContains {
+			candidateSep = " " + candidateSep + " "
+		}
+		if i := strings.Index(rest, candidateSep); i >= 0 && (at < 0 || i < at) {
+			op, at, sep = candidate, i, candidateSep
+		}
+	}
+	if at < 0 {
+		return SpecFilter{}, fmt.Errorf("%q has no operator, use =, !=, >, >=, <, <= or contains", expr)
+	}
+
+	f := SpecFilter{
+		Path:     strings.Split(strings.TrimSpace(rest[:at]), "."),
+		Operator: op,
+		Value:    strings.TrimSpace(rest[at+len(sep):]),
+	}
+	for _, k := range f.Path {
+		if !specKeyRegexp.MatchString(k) {
+			return SpecFilter{}, fmt.Errorf("%q: %q is not a valid key", expr, k)
+		}
+	}
+	if f.Value == "" {
+		return SpecFilter{}, fmt.Errorf("%q: the value is missing", expr)
+	}
+	if f.ordered() {
+		if _, ok := parseNumber(f.Value); !ok {
+			return SpecFilter{}, fmt.Errorf("%q: %s needs a number", expr, op)
+		}
+	}
+	return f, nil
+}
+
+func (f SpecFilter) ordered() bool {
+	switch f.Operator {
+	case SpecGt, SpecGtOrEq, SpecLt, SpecLtOrEq:
+		return true
+	}
+	return false
+}
+
+// ToSql translates the filter into containment, served by the GIN index, or a JSON path predicate
+// for the comparisons. Keys are validated and values are passed as arguments.
+func (f SpecFilter) ToSql() (string, []interface{}, error) {
+	switch f.Operator {
+	case SpecEq:
+		return f.containment(f.Value).ToSql()
+	case SpecNotEq:
+		eq, args, err := f.containment(f.Value).ToSql()
+		if err != nil {
+			return "", nil, err
+		}
+		return "(specification #> ? IS NOT NULL AND NOT " + eq + ")", append([]interface{}{f.Path}, args...), nil
+	case SpecContains:
+		return f.containment([]interface{}{typed(f.Value)}).ToSql()
+	default:
+		// ?? is the jsonb path match operator, escaped from the placeholders
+		return "specification @?? ?::jsonpath", []interface{}{f.jsonPath()}, nil
+	}
+}
+
+// containment matches the value with its detected type or, failing that, as a string: "16" and 16 are equal.
+func (f SpecFilter) containment(value interface{}) sq.Sqlizer {
+	variants := []interface{}{value}
+	if s, ok := value.(string); ok {
+		variants[0] = typed(s)
+		if _, isString := variants[0].(string); !isString {
+			variants = append(variants, s)
+		}
+	} else if list, ok := value.([]interface{}); ok {
+		if _, isString := list[0].(string); !isString {
+			variants = append(variants, []interface{}{f.Value})
+		}
+	}
+
+	or := sq.Or{}
+	for _, v := range variants {
+		or = append(or, sq.Expr("specification @> ?::jsonb", nest(f.Path, v)))
+	}
+	return or
+}
+
+func (f SpecFilter) jsonPath() string {
+	keys := make([]string, 0, len(f.Path))
+	for _, k := range f.Path {
+		keys = append(keys, strconv.Quote(k))
+	}
+	number, _ := parseNumber(f.Value)
+	return fmt.Sprintf("$.%s ? (@ %s %s)", strings.Join(keys, "."), f.Operator, strconv.FormatFloat(number, 'g', -1, 64))
+}
+
+// typed returns the number or boolean s stands for, s itself otherwise.
+func typed(s string) interface{} {
+	switch s {
+	case "true":
+		return true
+	case "false":
+		return false
+	}
+	if n, ok := parseNumber(s); ok {
+		return json.Number(strconv.FormatFloat(n, 'g', -1, 64))
+	}
+	return s
+}
+
+// parseNumber accepts the numbers JSON can hold, NaN and infinities are not among them.
+func parseNumber(s string) (float64, bool) {
+	n, err := strconv.ParseFloat(s, 64)
+	if err != nil || math.IsNaN(n) || math.IsInf(n, 0) {
+		return 0, false
+	}
+	return n, true
+}
+
+// nest builds the JSON document {"a": {"b": value}} for the path a.b.
+func nest(path []string, value interface{}) string {
+	var doc interface{} = value
+	for i := len(path) - 1; i >= 0; i-- {
+		doc = map[string]interface{}{path[i]: doc}
+	}
+	b, _ := json.Marshal(doc)
+	return string(b)
+}
@@ -0,0 +1,180 @@
+package storage
+
+import (
+	sq "github.com/Masterminds/squirrel"
+	"reflect"
+	"strings"
+	"testing"
+)
+
+func TestParseSpecFilter(t *testing.T) {
+	tests := []struct {
+		expr    string
+		want    SpecFilter
+		wantErr bool
+	}{
+		{expr: "spec.color=red", want: SpecFilter{Path: []string{"color"}, Operator: SpecEq, Value: "red"}},
+		{expr: "spec.color!=red", want: SpecFilter{Path: []string{"color"}, Operator: SpecNotEq, Value: "red"}},
+		{expr: "spec.ram_gb>16", want: SpecFilter{Path: []string{"ram_gb"}, Operator: SpecGt, Value: "16"}},
+		{expr: "spec.ram_gb>=16", want: SpecFilter{Path: []string{"ram_gb"}, Operator: SpecGtOrEq, Value: "16"}},
+		{expr: "spec.ram_gb<16", want: SpecFilter{Path: []string{"ram_gb"}, Operator: SpecLt, Value: "16"}},
+		{expr: "spec.ram_gb<=16.5", want: SpecFilter{Path: []string{"ram_gb"}, Operator: SpecLtOrEq, Value: "16.5"}},
+		{expr: "spec.tags contains 5g", want: SpecFilter{Path: []string{"tags"}, Operator: SpecContains, Value: "5g"}},
+		{expr: " spec.color = red ", want: SpecFilter{Path: []string{"color"}, Operator: SpecEq, Value: "red"}},
+		{expr: "spec.size.width<30", want: SpecFilter{Path: []string{"size", "width"}, Operator: SpecLt, Value: "30"}},
+		{expr: "spec.a.b-c.d_e=1", want: SpecFilter{Path: []string{"a", "b-c", "d_e"}, Operator: SpecEq, Value: "1"}},
+		{expr: "spec.note=a>=b", want: SpecFilter{Path: []string{"note"}, Operator: SpecEq, Value: "a>=b"}},
+		{expr: "spec.note!=x=y", want: SpecFilter{Path: []string{"note"}, Operator: SpecNotEq, Value: "x=y"}},
+		{expr: "spec.note=a contains b", want: SpecFilter{Path: []string{"note"}, Operator: SpecEq, Value: "a contains b"}},
+		{expr: "spec.tags contains a=b", want: SpecFilter{Path: []string{"tags"}, Operator: SpecContains, Value: "a=b"}},
+		{expr: "spec.containers=2", want: SpecFilter{Path: []string{"containers"}, Operator: SpecEq, Value: "2"}},
+		{expr: "spec.x>-1e3", want: SpecFilter{Path: []string{"x"}, Operator: SpecGt, Value: "-1e3"}},
+		{expr: "color=red", wantErr: true},
+		{expr: "spec.color", wantErr: true},
+		{expr: "spec.tags contains", wantErr: true},
+		{expr: "spec.color=", wantErr: true},
+		{expr: "spec.=red", wantErr: true},
+		{expr: "spec.size..width=1", wantErr: true},
+		{expr: "spec.size.=1", wantErr: true},
+		{expr: "spec.co lor=red", wantErr: true},
+		{expr: `spec.co"lor=red`, wantErr: true},
+		{expr: "spec.color'--=red", wantErr: true},
+		{expr: "spec.$.color=red", wantErr: true},
+		{expr: "spec." + strings.Repeat("k", 65) + "=1", wantErr: true},
+		{expr: "spec.ram_gb>=many", wantErr: true},
+		{expr: "spec.ram_gb<NaN", wantErr: true},
+		{expr: "spec.ram_gb>Inf", wantErr: true},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.expr, func(t *testing.T) {
+			got, err := ParseSpecFilter(tt.expr)
+			if (err != nil) != tt.wantErr {
+				t.Fatalf("ParseSpecFilter() error = %v, wantErr %t", err, tt.wantErr)
+			}
+			if !tt.wantErr && !reflect.DeepEqual(got, tt.want) {
+				t.Fatalf("ParseSpecFilter() = %#v, want %#v", got, tt.want)
+			}
+		})
+	}
+}
+
+func TestSpecFilterToSql(t *testing.T) {
+	tests := []struct {
+		expr     string
+		wantSql  string
+		wantArgs []interface{}
+	}{
+		{
+			expr:     "spec.color=red",
+			wantSql:  "(specification @> ?::jsonb)",
+			wantArgs: []interface{}{`{"color":"red"}`},
+		},
+		{
+			expr:     "spec.ram_gb=16.0",
+			wantSql:  "(specification @> ?::jsonb OR specification @> ?::jsonb)",
+			wantArgs: []interface{}{`{"ram_gb":16}`, `{"ram_gb":"16.0"}`},
+		},
+		{
+			expr:     "spec.wifi=true",
+			wantSql:  "(specification @> ?::jsonb OR specification @> ?::jsonb)",
+			wantArgs: []interface{}{`{"wifi":true}`, `{"wifi":"true"}`},
+		},
+		{
+			expr:     "spec.x=NaN",
+			wantSql:  "(specification @> ?::jsonb)",
+			wantArgs: []interface{}{`{"x":"NaN"}`},
+		},
+		{
+			expr:     `spec.name=Tom "Jerry" \o/`,
+			wantSql:  "(specification @> ?::jsonb)",
+			wantArgs: []interface{}{`{"name":"Tom \"Jerry\" \\o/"}`},
+		},
+		{
+			expr:     "spec.size.width=7",
+			wantSql:  "(specification @> ?::jsonb OR specification @> ?::jsonb)",
+			wantArgs: []interface{}{`{"size":{"width":7}}`, `{"size":{"width":"7"}}`},
+		},
+		{
+			expr:     "spec.color!=red",
+			wantSql:  "(specification #> ? IS NOT NULL AND NOT (specification @> ?::jsonb))",
+			wantArgs: []interface{}{[]string{"color"}, `{"color":"red"}`},
+		},
+		{
+			expr:     "spec.size.width!=7",
+			wantSql:  "(specification #> ? IS NOT NULL AND NOT (specification @> ?::jsonb OR specification @> ?::jsonb))",
+			wantArgs: []interface{}{[]string{"size", "width"}, `{"size":{"width":7}}`, `{"size":{"width":"7"}}`},
+		},
+		{
+			expr:     "spec.tags contains x",
+			wantSql:  "(specification @> ?::jsonb)",
+			wantArgs: []interface{}{`{"tags":["x"]}`},
+		},
+		{
+			expr:     "spec.tags contains 5",
+			wantSql:  "(specification @> ?::jsonb OR specification @> ?::jsonb)",
+			wantArgs: []interface{}{`{"tags":[5]}`, `{"tags":["5"]}`},
+		},
+		{
+			expr:     "spec.ram_gb>16",
+			wantSql:  "specification @?? ?::jsonpath",
+			wantArgs: []interface{}{`$."ram_gb" ? (@ > 16)`},
+		},
+		{
+			expr:     "spec.ram_gb>=16",
+			wantSql:  "specification @?? ?::jsonpath",
+			wantArgs: []interface{}{`$."ram_gb" ? (@ >= 16)`},
+		},
+		{
+			expr:     "spec.ram_gb<1e3",
+			wantSql:  "specification @?? ?::jsonpath",
+			wantArgs: []interface{}{`$."ram_gb" ? (@ < 1000)`},
+		},
+		{
+			expr:     "spec.size.width<=16.50",
+			wantSql:  "specification @?? ?::jsonpath",
+			wantArgs: []interface{}{`$."size"."width" ? (@ <= 16.5)`},
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.expr, func(t *testing.T) {
+			f, err := ParseSpecFilter(tt.expr)
+			if err != nil {
+				t.Fatalf("ParseSpecFilter() error = %v", err)
+			}
+			sql, args, err := f.ToSql()
+			if err != nil {
+				t.Fatalf("ToSql() error = %v", err)
+			}
+			if sql != tt.wantSql {
+				t.Fatalf("ToSql() sql = %s, want %s", sql, tt.wantSql)
+			}
+			if !reflect.DeepEqual(args, tt.wantArgs) {
+				t.Fatalf("ToSql() args = %#v, want %#v", args, tt.wantArgs)
+			}
+		})
+	}
+}
+
+func TestSpecFilterInQuery(t *testing.T) {
+	filter := Filter{Specification: []SpecFilter{
+		{Path: []string{"color"}, Operator: SpecNotEq, Value: "red"},
+		{Path: []string{"ram_gb"}, Operator: SpecGtOrEq, Value: "16"},
+	}}
+	query := filter.apply(sq.StatementBuilder.PlaceholderFormat(sq.Dollar).Select("id").From("public.product"))
+
+	sql, args, err := query.ToSql()
+	if err != nil {
+		t.Fatalf("ToSql() error = %v", err)
+	}
+	wantSql := "SELECT id FROM public.product WHERE (specification #> $1 IS NOT NULL AND NOT (specification @> $2::jsonb)) " +
+		"AND specification @? $3::jsonpath"
+	if sql != wantSql {
+		t.Fatalf("ToSql() sql = %s, want %s", sql, wantSql)
+	}
+	wantArgs := []interface{}{[]string{"color"}, `{"color":"red"}`, `$."ram_gb" ? (@ >= 16)`}
+	if !reflect.DeepEqual(args, wantArgs) {
+		t.Fatalf("ToSql() args = %#v, want %#v", args, wantArgs)
+	}
+}
@@ -0,0 +1,59 @@
+package suggest
+
+import (
+	"context"
+	"prod/internal/domain/product/model"
+	"prod/pkg/cache"
+	"strings"
+	"time"
+)
+
+// Storage finds the suggestions, see storage.ProductStorage.Suggest.
+type Storage interface {
+	Suggest(ctx context.Context, prefix string, categoryId *int32, limit uint64) ([]model.Suggestion, error)
+}
+
+type key struct {
+	prefix string
+	// hasCategory tells the suggestions of category 0 from those of all categories.
+	hasCategory bool
+	categoryId  int32
+	limit       uint64
+}
+
+// Suggester answers hot prefixes from an in-process cache, the search box asks on every keystroke.
+// The product handlers purge the cache on changes, those made through other instances may be missed
+// for the cache TTL.
+type Suggester struct {
+	storage Storage
+	cache   *cache.LRU[key, []model.Suggestion]
+}
+
+func NewSuggester(storage Storage, cacheSize int, cacheTTL time.Duration) *Suggester {
+	return &Suggester{
+		storage: storage,
+		cache:   cache.NewLRU[key, []model.Suggestion](cacheSize, cacheTTL),
+	}
+}
+
+func (s *Suggester) Suggest(ctx context.Context, prefix string, categoryId *int32, limit uint64) ([]model.Suggestion, error) {
+	k := key{prefix: strings.ToLower(prefix), limit: limit}
+	if categoryId != nil {
+		k.hasCategory, k.categoryId = true, *categoryId
+	}
+	if list, ok := s.cache.Get(k); ok {
+		return list, nil
+	}
+
+	list, err := s.storage.Suggest(ctx, prefix, categoryId, limit)
+	if err != nil {
+		return nil, err
+	}
+	s.cache.Set(k, list)
+	return list, nil
+}
+
+// Purge drops the cached suggestions after the catalog has changed.
+func (s *Suggester) Purge() {
+	s.cache.Purge()
+}
@@ -0,0 +1,65 @@
+package suggest
+
+import (
+	"context"
+	"prod/internal/domain/product/model"
+	"strconv"
+	"testing"
+	"time"
+)
+
+type fakeStorage struct {
+	calls int
+}
+
+func (s *fakeStorage) Suggest(ctx context.Context, prefix string, categoryId *int32, limit uint64) ([]model.Suggestion, error) {
+	s.calls++
+	category := "all"
+	if categoryId != nil {
+		category = strconv.Itoa(int(*categoryId))
+	}
+	return []model.Suggestion{{Name: prefix + " in " + category}}, nil
+}
+
+func TestSuggesterCache(t *testing.T) {
+	zero, one := int32(0), int32(1)
+
+	tests := []struct {
+		name       string
+		prefix     string
+		categoryId *int32
+		limit      uint64
+		want       string
+		wantCalls  int
+	}{
+		{name: "first", prefix: "Pho", want: "Pho in all", wantCalls: 1},
+		{name: "cached regardless of case", prefix: "pHO", want: "Pho in all", wantCalls: 1},
+		{name: "category 0 is not all categories", prefix: "pho", categoryId: &zero, want: "pho in 0", wantCalls: 2},
+		{name: "category 0 is cached", prefix: "pho", categoryId: &zero, want: "pho in 0", wantCalls: 2},
+		{name: "other category", prefix: "pho", categoryId: &one, want: "pho in 1", wantCalls: 3},
+		{name: "other limit", prefix: "pho", limit: 5, want: "pho in all", wantCalls: 4},
+	}
+
+	storage := &fakeStorage{}
+	s := NewSuggester(storage, 10, time.Minute)
+	for _, tt := range tests {
+		list, err := s.Suggest(context.Background(), tt.prefix, tt.categoryId, tt.limit)
+		if err != nil {
+			t.Fatalf("%s: Suggest() error = %v", tt.name, err)
+		}
+		if len(list) != 1 || list[0].Name != tt.want {
+			t.Fatalf("%s: Suggest() = %+v, want %q", tt.name, list, tt.want)
+		}
+		if storage.calls != tt.wantCalls {
+			t.Fatalf("%s: storage calls = %d, want %d", tt.name, storage.calls, tt.wantCalls)
+		}
+	}
+
+	s.Purge()
+	if _, err := s.Suggest(context.Background(), "pho", nil, 0); err != nil {
+		t.Fatalf("Suggest() error = %v", err)
+	}
+	if storage.calls != 5 {
+		t.Fatalf("storage calls after Purge = %d, want 5", storage.calls)
+	}
+}
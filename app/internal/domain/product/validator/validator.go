@@ -0,0 +1,65 @@
+package validator
+
+import (
+	"context"
+	"prod/internal/domain/product/model"
+	"prod/pkg/apperror"
+	"strconv"
+)
+
+// ReferenceChecker reports whether a referenced record exists.
+type ReferenceChecker interface {
+	Exists(ctx context.Context, id string) (bool, error)
+}
+
+type reference struct {
+	field   string
+	id      string
+	checker ReferenceChecker
+}
+
+// Validator checks incoming products for every entry point, HTTP and gRPC alike.
+type Validator struct {
+	categories ReferenceChecker
+	currencies ReferenceChecker
+	images     ReferenceChecker
+}
+
+func NewValidator(categories ReferenceChecker, currencies ReferenceChecker, images ReferenceChecker) *Validator {
+	return &Validator{
+		categories: categories,
+		currencies: currencies,
+		images:     images,
+	}
+}
+
+// Validate checks the product fields and, once they are well-formed, that the referenced records exist.
+func (v *Validator) Validate(ctx context.Context, p model.Product) error {
+	if err := p.Validate(); err != nil {
+		return err
+	}
+
+	fields := make([]apperror.FieldError, 0)
+	refs := []reference{
+		{"category_id", strconv.Itoa(int(p.CategoryId)), v.categories},
+		{"currency_id", strconv.Itoa(int(p.CurrencyId)), v.currencies},
+	}
+	if p.ImageId != nil {
+		refs = append(refs, reference{"image_id", *p.ImageId, v.images})
+	}
+
+	for _, ref := range refs {
+		exists, err := ref.checker.Exists(ctx, ref.id)
+		if err != nil {
+			return err
+		}
+		if !exists {
+			fields = append(fields, apperror.Field(ref.field, "does not exist"))
+		}
+	}
+
+	if len(fields) > 0 {
+		return apperror.Invalid(fields...)
+	}
+	return nil
+}
@@ -0,0 +1,90 @@
+package validator
+
+import (
+	"context"
+	"errors"
+	"prod/internal/domain/product/model"
+	"prod/pkg/apperror"
+	"reflect"
+	"testing"
+)
+
+type fakeChecker struct {
+	ids   map[string]bool
+	err   error
+	calls int
+}
+
+func (c *fakeChecker) Exists(ctx context.Context, id string) (bool, error) {
+	c.calls++
+	return c.ids[id], c.err
+}
+
+func validProduct() model.Product {
+	return model.Product{Name: "phone", Price: 100, CurrencyId: 1, Rating: 5, CategoryId: 2}
+}
+
+func TestValidatorValidate(t *testing.T) {
+	imageId := "0f8fad5b-d9cb-469f-a165-70867728950e"
+	missingImageId := "1f8fad5b-d9cb-469f-a165-70867728950e"
+
+	tests := []struct {
+		name       string
+		modify     func(p *model.Product)
+		want       []apperror.FieldError
+		wantLookup bool
+	}{
+		{name: "valid", modify: func(p *model.Product) {}, wantLookup: true},
+		{name: "valid with image", modify: func(p *model.Product) { p.ImageId = &imageId }, wantLookup: true},
+		{
+			name:       "missing references",
+			modify:     func(p *model.Product) { p.CategoryId = 3; p.CurrencyId = 4; p.ImageId = &missingImageId },
+			want:       []apperror.FieldError{{Field: "category_id", Message: "does not exist"}, {Field: "currency_id", Message: "does not exist"}, {Field: "image_id", Message: "does not exist"}},
+			wantLookup: true,
+		},
+		{
+			name:   "malformed fields skip the lookups",
+			modify: func(p *model.Product) { p.Name = ""; p.Rating = 6 },
+			want:   []apperror.FieldError{{Field: "name", Message: "is required"}, {Field: "rating", Message: "must be at most 5"}},
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			categories := &fakeChecker{ids: map[string]bool{"2": true}}
+			currencies := &fakeChecker{ids: map[string]bool{"1": true}}
+			images := &fakeChecker{ids: map[string]bool{imageId: true}}
+			v := NewValidator(categories, currencies, images)
+
+			p := validProduct()
+			tt.modify(&p)
+			err := v.Validate(context.Background(), p)
+
+			if got := categories.calls > 0; got != tt.wantLookup {
+				t.Fatalf("references looked up = %t, want %t", got, tt.wantLookup)
+			}
+			if tt.want == nil {
+				if err != nil {
+					t.Fatalf("Validate() = %v, want nil", err)
+				}
+				return
+			}
+			var appErr *apperror.Error
+			if !errors.As(err, &appErr) || !errors.Is(err, apperror.ErrInvalidInput) {
+				t.Fatalf("Validate() = %v, want an invalid input error", err)
+			}
+			if !reflect.DeepEqual(appErr.Fields, tt.want) {
+				t.Fatalf("fields = %#v, want %#v", appErr.Fields, tt.want)
+			}
+		})
+	}
+}
+
+func TestValidatorCheckerError(t *testing.T) {
+	boom := errors.New("boom")
+	v := NewValidator(&fakeChecker{err: boom}, &fakeChecker{}, &fakeChecker{})
+
+	if err := v.Validate(context.Background(), validProduct()); !errors.Is(err, boom) {
+		t.Fatalf("Validate() = %v, want %v", err, boom)
+	}
+}
@@ -0,0 +1,155 @@
+package handler
+
+import (
+	"context"
+	"encoding/json"
+	"github.com/julienschmidt/httprouter"
+	"net/http"
+	"prod/internal/domain/role/model"
+	"prod/internal/domain/role/storage"
+	"prod/internal/rbac"
+	"prod/pkg/apperror"
+	"prod/pkg/auth"
+	"prod/pkg/client/postgresql"
+	"prod/pkg/httperr"
+	"prod/pkg/response"
+)
+
+const (
+	URL    = "/api/admin/roles"
+	oneURL = "/api/admin/roles/:name"
+)
+
+type Handler struct {
+	storage   *storage.RoleStorage
+	txManager *postgresql.TxManager
+	guard     auth.Guard
+}
+
+func NewHandler(storage *storage.RoleStorage, txManager *postgresql.TxManager, guard auth.Guard) *Handler {
+	return &Handler{
+		storage:   storage,
+		txManager: txManager,
+		guard:     guard,
+	}
+}
+
+func (h *Handler) Register(router *httprouter.Router) {
+	manage := h.guard(rbac.UsersManage)
+	router.Handler(http.MethodGet, URL, manage(http.HandlerFunc(h.List)))
+	router.Handler(http.MethodGet, oneURL, manage(http.HandlerFunc(h.One)))
+	router.Handler(http.MethodPut, oneURL, manage(http.HandlerFunc(h.Save)))
+	router.Handler(http.MethodDelete, oneURL, manage(http.HandlerFunc(h.Delete)))
+}
+
+type SaveRequest struct {
+	Description string            `json:"description"`
+	Permissions []auth.Permission `json:"permissions"`
+}
+
+// List
+// @Summary List roles with their permissions
+// @Tags Admin
+// @Produce json
+// @Security BearerAuth
+// @Success 200 {array} model.Role
+// @Failure 401 {object} httperr.Problem
+// @Failure 403 {object} httperr.Problem
+// @Router /api/admin/roles [get]
+func (h *Handler) List(w http.ResponseWriter, r *http.Request) {
+	list, err := h.storage.All(r.Context())
+	if err != nil {
+		httperr.Write(w, r, err)
+		return
+	}
+
+	response.JSON(w, http.StatusOK, list)
+}
+
+// One
+// @Summary Get a role
+// @Tags Admin
+// @Produce json
+// @Security BearerAuth
+// @Param name path string true "Role name"
+// @Success 200 {object} model.Role
+// @Failure 401 {object} httperr.Problem
+// @Failure 403 {object} httperr.Problem
+// @Failure 404 {object} httperr.Problem
+// @Router /api/admin/roles/{name} [get]
+func (h *Handler) One(w http.ResponseWriter, r *http.Request) {
+	role, err := h.storage.One(r.Context(), httprouter.ParamsFromContext(r.Context()).ByName("name"))
+	if err != nil {
+		httperr.Write(w, r, err)
+		return
+	}
+
+	response.JSON(w, http.StatusOK, role)
+}
+
+// Save
+// @Summary Create a role or replace its permissions
+// @Tags Admin
+// @Accept json
+// @Produce json
+// @Security BearerAuth
+// @Param name path string true "Role name"
+// @Param role body SaveRequest true "Role"
+// @Success 200 {object} model.Role
+// @Failure 400 {object} httperr.Problem
+// @Failure 401 {object} httperr.Problem
+// @Failure 403 {object} httperr.Problem
+// @Router /api/admin/roles/{name} [put]
+func (h *Handler) Save(w http.ResponseWriter, r *http.Request) {
+	req := SaveRequest{}
+	if err := json.NewDecoder(r.Body).Decode(&req); err != nil {
+		httperr.Write(w, r, apperror.InvalidInput("malformed request body"))
+		return
+	}
+	for _, p := range req.Permissions {
+		if !rbac.Known(p) {
+			httperr.Write(w, r, apperror.InvalidInput("unknown permission %q", p))
+			return
+		}
+	}
+
+	role := model.Role{
+		Name:        httprouter.ParamsFromContext(r.Context()).ByName("name"),
+		Description: req.Description,
+		Permissions: req.Permissions,
+	}
+	err := h.txManager.WithinTx(r.Context(), func(ctx context.Context) error {
+		if err := h.storage.Save(ctx, role); err != nil {
+			return err
+		}
+		var err error
+		role, err = h.storage.One(ctx, role.Name)
+		return err
+	})
+	if err != nil {
+		httperr.Write(w, r, err)
+		return
+	}
+
+	response.JSON(w, http.StatusOK, role)
+}
+
+// Delete
+// @Summary Delete a role
+// @Tags Admin
+// @Security BearerAuth
+// @Param name path string true "Role name"
+// @Success 204
+// @Failure 401 {object} httperr.Problem
+// @Failure 403 {object} httperr.Problem
+// @Failure 404 {object} httperr.Problem
+// @Failure 409 {object} httperr.Problem "The role is assigned to users"
+// @Router /api/admin/roles/{name} [delete]
+func (h *Handler) Delete(w http.ResponseWriter, r *http.Request) {
+	if err := h.storage.Delete(r.Context(), httprouter.ParamsFromContext(r.Context()).ByName("name")); err != nil {
+		httperr.Write(w, r, err)
+		return
+	}
+
+	response.NoContent(w)
+}
@@ -0,0 +1,9 @@
+package model
+
+import "prod/pkg/auth"
+
+type Role struct {
+	Name        string            `json:"name"`
+	Description string            `json:"description"`
+	Permissions []auth.Permission `json:"permissions"`
+}
@@ -0,0 +1,213 @@
+package storage
+
+import (
+	"context"
+	sq "github.com/Masterminds/squirrel"
+	"github.com/jackc/pgx/v4"
+	"prod/internal/domain/role/model"
+	"prod/pkg/auth"
+	"prod/pkg/client/postgresql"
+	db "prod/pkg/client/postgresql/model"
+)
+
+type RoleStorage struct {
+	queryBuilder sq.StatementBuilderType
+	client       postgresql.Client
+}
+
+func NewRoleStorage(client postgresql.Client) *RoleStorage {
+	return &RoleStorage{
+		queryBuilder: sq.StatementBuilder.PlaceholderFormat(sq.Dollar),
+		client:       client,
+	}
+}
+
+const (
+	scheme               = "public"
+	table                = "role"
+	permissionTable      = "role_permission"
+	userRoleTable        = "user_role"
+	permissionsAggregate = "coalesce(array_agg(rp.permission ORDER BY rp.permission) FILTER (WHERE rp.permission IS NOT NULL), '{}')"
+)
+
+func (s *RoleStorage) selectRoles() sq.SelectBuilder {
+	return s.queryBuilder.Select("r.name", "r.description", permissionsAggregate).
+		From(scheme + "." + table + " r").
+		LeftJoin(scheme + "." + permissionTable + " rp ON rp.role = r.name").
+		GroupBy("r.name").
+		OrderBy("r.name")
+}
+
+func (s *RoleStorage) All(ctx context.Context) ([]model.Role, error) {
+	sql, args, err := s.selectRoles().ToSql()
+	if err != nil {
+		return nil, db.ErrCreateQuery(err)
+	}
+
+	rows, err := s.client.Query(ctx, sql, args...)
+	if err != nil {
+		return nil, db.ErrDoQuery(postgresql.ClassifyError(err))
+	}
+
+	defer rows.Close()
+
+	list := make([]model.Role, 0)
+	for rows.Next() {
+		r, err := scanRole(rows)
+		if err != nil {
+			return nil, db.ErrScan(postgresql.ClassifyError(err))
+		}
+		list = append(list, r)
+	}
+	if err = rows.Err(); err != nil {
+		return nil, db.ErrDoQuery(postgresql.ClassifyError(err))
+	}
+
+	return list, nil
+}
+
+func (s *RoleStorage) One(ctx context.Context, name string) (model.Role, error) {
+	sql, args, err := s.selectRoles().Where(sq.Eq{"r.name": name}).ToSql()
+	if err != nil {
+		return model.Role{}, db.ErrCreateQuery(err)
+	}
+
+	r, err := scanRole(s.client.QueryRow(ctx, sql, args...))
+	if err != nil {
+		return r, db.ErrDoQuery(postgresql.ClassifyError(err))
+	}
+
+	return r, nil
+}
+
+func scanRole(row pgx.Row) (model.Role, error) {
+	r := model.Role{}
+	var permissions []string
+	if err := row.Scan(&r.Name, &r.Description, &permissions); err != nil {
+		return r, err
+	}
+
+	r.Permissions = make([]auth.Permission, 0, len(permissions))
+	for _, p := range permissions {
+		r.Permissions = append(r.Permissions, auth.Permission(p))
+	}
+	return r, nil
+}
+
+// Save creates the role or replaces the description and permissions of an existing one, call it within a transaction.
+func (s *RoleStorage) Save(ctx context.Context, r model.Role) error {
+	upsert := s.queryBuilder.Insert(scheme+"."+table).
+		Columns("name", "description").
+		Values(r.Name, r.Description).
+		Suffix("ON CONFLICT (name) DO UPDATE SET description = EXCLUDED.description")
+	if err := s.exec(ctx, upsert); err != nil {
+		return err
+	}
+
+	clear := s.queryBuilder.Delete(scheme + "." + permissionTable).
+		Where(sq.Eq{"role": r.Name})
+	if err := s.exec(ctx, clear); err != nil {
+		return err
+	}
+
+	if len(r.Permissions) == 0 {
+		return nil
+	}
+	insert := s.queryBuilder.Insert(scheme+"."+permissionTable).
+		Columns("role", "permission")
+	for _, p := range r.Permissions {
+		insert = insert.Values(r.Name, p)
+	}
+	return s.exec(ctx, insert)
+}
+
+// Delete removes the role, roles still assigned to users can't be deleted.
+func (s *RoleStorage) Delete(ctx context.Context, name string) error {
+	query := s.queryBuilder.Delete(scheme + "." + table).
+		Where(sq.Eq{"name": name}).
+		Suffix("RETURNING name")
+
+	sql, args, err := query.ToSql()
+	if err != nil {
+		return db.ErrCreateQuery(err)
+	}
+
+	if err = s.client.QueryRow(ctx, sql, args...).Scan(&name); err != nil {
+		return db.ErrDoQuery(postgresql.ClassifyError(err))
+	}
+
+	return nil
+}
+
+// Permissions implements auth.PermissionSource.
+func (s *RoleStorage) Permissions(ctx context.Context, userID string) ([]auth.Permission, error) {
+	query := s.queryBuilder.Select("DISTINCT rp.permission").
+		From(scheme + "." + userRoleTable + " ur").
+		Join(scheme + "." + permissionTable + " rp ON rp.role = ur.role").
+		Where(sq.Eq{"ur.user_id": userID})
+
+	sql, args, err := query.ToSql()
+	if err != nil {
+		return nil, db.ErrCreateQuery(err)
+	}
+
+	rows, err := s.client.Query(ctx, sql, args...)
+	if err != nil {
+		return nil, db.ErrDoQuery(postgresql.ClassifyError(err))
+	}
+
+	defer rows.Close()
+
+	list := make([]auth.Permission, 0)
+	for rows.Next() {
+		var p string
+		if err = rows.Scan(&p); err != nil {
+			return nil, db.ErrScan(postgresql.ClassifyError(err))
+		}
+		list = append(list, auth.Permission(p))
+	}
+	if err = rows.Err(); err != nil {
+		return nil, db.ErrDoQuery(postgresql.ClassifyError(err))
+	}
+
+	return list, nil
+}
+
+// AssignRole grants the role to the user, granting it again is a no-op.
+func (s *RoleStorage) AssignRole(ctx context.Context, userID string, role string) error {
+	query := s.queryBuilder.Insert(scheme+"."+userRoleTable).
+		Columns("user_id", "role").
+		Values(userID, role).
+		Suffix("ON CONFLICT DO NOTHING")
+
+	return s.exec(ctx, query)
+}
+
+// SetUserRoles replaces all roles of the user, call it within a transaction.
+func (s *RoleStorage) SetUserRoles(ctx context.Context, userID string, roles []string) error {
+	clear := s.queryBuilder.Delete(scheme + "." + userRoleTable).
+		Where(sq.Eq{"user_id": userID})
+	if err := s.exec(ctx, clear); err != nil {
+		return err
+	}
+
+	for _, role := range roles {
+		if err := s.AssignRole(ctx, userID, role); err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
+func (s *RoleStorage) exec(ctx context.Context, query sq.Sqlizer) error {
+	sql, args, err := query.ToSql()
+	if err != nil {
+		return db.ErrCreateQuery(err)
+	}
+
+	if _, err = s.client.Exec(ctx, sql, args...); err != nil {
+		return db.ErrDoQuery(postgresql.ClassifyError(err))
+	}
+
+	return nil
+}
@@ -0,0 +1,264 @@
+package handler
+
+import (
+	"context"
+	"encoding/json"
+	"errors"
+	"github.com/julienschmidt/httprouter"
+	"net/http"
+	roleStorage "prod/internal/domain/role/storage"
+	"prod/internal/domain/user/model"
+	"prod/internal/domain/user/storage"
+	"prod/internal/rbac"
+	"prod/pkg/apperror"
+	"prod/pkg/auth"
+	"prod/pkg/client/postgresql"
+	"prod/pkg/httperr"
+	"prod/pkg/password"
+	"prod/pkg/response"
+)
+
+const (
+	loginURL     = "/api/auth/login"
+	refreshURL   = "/api/auth/refresh"
+	usersURL     = "/api/admin/users"
+	userRolesURL = "/api/admin/users/:id/roles"
+)
+
+type Handler struct {
+	users     *storage.UserStorage
+	roles     *roleStorage.RoleStorage
+	txManager *postgresql.TxManager
+	tokens    *auth.TokenManager
+	guard     auth.Guard
+}
+
+func NewHandler(
+	users *storage.UserStorage,
+	roles *roleStorage.RoleStorage,
+	txManager *postgresql.TxManager,
+	tokens *auth.TokenManager,
+	guard auth.Guard,
+) *Handler {
+	return &Handler{
+		users:     users,
+		roles:     roles,
+		txManager: txManager,
+		tokens:    tokens,
+		guard:     guard,
+	}
+}
+
+func (h *Handler) Register(router *httprouter.Router) {
+	router.HandlerFunc(http.MethodPost, loginURL, h.Login)
+	router.HandlerFunc(http.MethodPost, refreshURL, h.Refresh)
+
+	manage := h.guard(rbac.UsersManage)
+	router.Handler(http.MethodGet, usersURL, manage(http.HandlerFunc(h.List)))
+	router.Handler(http.MethodPost, usersURL, manage(http.HandlerFunc(h.Create)))
+	router.Handler(http.MethodPut, userRolesURL, manage(http.HandlerFunc(h.SetRoles)))
+}
+
+type LoginRequest struct {
+	Email    string `json:"email"`
+	Password string `json:"password"`
+}
+
+type RefreshRequest struct {
+	RefreshToken string `json:"refresh_token"`
+}
+
+type CreateRequest struct {
+	Email    string   `json:"email"`
+	Password string   `json:"password"`
+	Roles    []string `json:"roles"`
+}
+
+type RolesRequest struct {
+	Roles []string `json:"roles"`
+}
+
+// Login
+// @Summary Issue access and refresh tokens
+// @Tags Auth
+// @Accept json
+// @Produce json
+// @Param credentials body LoginRequest true "User credentials"
+// @Success 200 {object} auth.TokenPair
+// @Failure 400 {object} httperr.Problem
+// @Failure 401 {object} httperr.Problem
+// @Router /api/auth/login [post]
+func (h *Handler) Login(w http.ResponseWriter, r *http.Request) {
+	req := LoginRequest{}
+	if err := json.NewDecoder(r.Body).Decode(&req); err != nil {
+		httperr.Write(w, r, apperror.InvalidInput("malformed request body"))
+		return
+	}
+
+	user, err := h.users.ByEmail(r.Context(), req.Email)
+	switch {
+	case errors.Is(err, postgresql.ErrNotFound):
+		httperr.Write(w, r, apperror.Unauthorized("invalid email or password"))
+		return
+	case err != nil:
+		httperr.Write(w, r, err)
+		return
+	}
+
+	if err = password.Compare(user.PasswordHash, req.Password); err != nil {
+		httperr.Write(w, r, apperror.Unauthorized("invalid email or password"))
+		return
+	}
+
+	tokens, err := h.tokens.Issue(user.Id, user.Email)
+	if err != nil {
+		httperr.Write(w, r, err)
+		return
+	}
+
+	response.JSON(w, http.StatusOK, tokens)
+}
+
+// Refresh
+// @Summary Exchange a refresh token for a new token pair
+// @Tags Auth
+// @Accept json
+// @Produce json
+// @Param token body RefreshRequest true "Refresh token"
+// @Success 200 {object} auth.TokenPair
+// @Failure 400 {object} httperr.Problem
+// @Failure 401 {object} httperr.Problem
+// @Router /api/auth/refresh [post]
+func (h *Handler) Refresh(w http.ResponseWriter, r *http.Request) {
+	req := RefreshRequest{}
+	if err := json.NewDecoder(r.Body).Decode(&req); err != nil {
+		httperr.Write(w, r, apperror.InvalidInput("malformed request body"))
+		return
+	}
+
+	claims, err := h.tokens.Parse(req.RefreshToken, auth.RefreshToken)
+	if err != nil {
+		httperr.Write(w, r, err)
+		return
+	}
+
+	user, err := h.users.One(r.Context(), claims.Subject)
+	switch {
+	case errors.Is(err, postgresql.ErrNotFound):
+		httperr.Write(w, r, apperror.Unauthorized("user does not exist"))
+		return
+	case err != nil:
+		httperr.Write(w, r, err)
+		return
+	}
+
+	tokens, err := h.tokens.Issue(user.Id, user.Email)
+	if err != nil {
+		httperr.Write(w, r, err)
+		return
+	}
+
+	response.JSON(w, http.StatusOK, tokens)
+}
+
+// List
+// @Summary List users with their roles
+// @Tags Admin
+// @Produce json
+// @Security BearerAuth
+// @Success 200 {array} model.User
+// @Failure 401 {object} httperr.Problem
+// @Failure 403 {object} httperr.Problem
+// @Router /api/admin/users [get]
+func (h *Handler) List(w http.ResponseWriter, r *http.Request) {
+	list, err := h.users.All(r.Context())
+	if err != nil {
+		httperr.Write(w, r, err)
+		return
+	}
+
+	response.JSON(w, http.StatusOK, list)
+}
+
+// Create
+// @Summary Create a user with roles
+// @Tags Admin
+// @Accept json
+// @Produce json
+// @Security BearerAuth
+// @Param user body CreateRequest true "User"
+// @Success 201 {object} model.User
+// @Failure 400 {object} httperr.Problem
+// @Failure 401 {object} httperr.Problem
+// @Failure 403 {object} httperr.Problem
+// @Failure 409 {object} httperr.Problem "The email is taken or a role does not exist"
+// @Router /api/admin/users [post]
+func (h *Handler) Create(w http.ResponseWriter, r *http.Request) {
+	req := CreateRequest{}
+	if err := json.NewDecoder(r.Body).Decode(&req); err != nil {
+		httperr.Write(w, r, apperror.InvalidInput("malformed request body"))
+		return
+	}
+	if req.Email == "" || req.Password == "" {
+		httperr.Write(w, r, apperror.InvalidInput("email and password are required"))
+		return
+	}
+
+	hash, err := password.Hash(req.Password)
+	if err != nil {
+		httperr.Write(w, r, err)
+		return
+	}
+
+	user := model.User{}
+	err = h.txManager.WithinTx(r.Context(), func(ctx context.Context) error {
+		var err error
+		if user, err = h.users.Create(ctx, req.Email, hash); err != nil {
+			return err
+		}
+		user.Roles = req.Roles
+		return h.roles.SetUserRoles(ctx, user.Id, req.Roles)
+	})
+	if err != nil {
+		httperr.Write(w, r, err)
+		return
+	}
+
+	response.JSON(w, http.StatusCreated, user)
+}
+
+// SetRoles
+// @Summary Replace the roles of a user
+// @Tags Admin
+// @Accept json
+// @Security BearerAuth
+// @Param id path string true "User ID"
+// @Param roles body RolesRequest true "Roles"
+// @Success 204
+// @Failure 400 {object} httperr.Problem
+// @Failure 401 {object} httperr.Problem
+// @Failure 403 {object} httperr.Problem
+// @Failure 404 {object} httperr.Problem
+// @Failure 409 {object} httperr.Problem "A role does not exist"
+// @Router /api/admin/users/{id}/roles [put]
+func (h *Handler) SetRoles(w http.ResponseWriter, r *http.Request) {
+	req := RolesRequest{}
+	if err := json.NewDecoder(r.Body).Decode(&req); err != nil {
+		httperr.Write(w, r, apperror.InvalidInput("malformed request body"))
+		return
+	}
+
+	id := httprouter.ParamsFromContext(r.Context()).ByName("id")
+	err := h.txManager.WithinTx(r.Context(), func(ctx context.Context) error {
+		if _, err := h.users.One(ctx, id); err != nil {
+			return err
+		}
+		return h.roles.SetUserRoles(ctx, id, req.Roles)
+	})
+	if err != nil {
+		httperr.Write(w, r, err)
+		return
+	}
+
+	response.NoContent(w)
+}
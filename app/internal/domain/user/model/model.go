@@ -0,0 +1,12 @@
+package model
+
+import "time"
+
+type User struct {
+	Id           string     `json:"id"`
+	Email        string     `json:"email"`
+	PasswordHash string     `json:"-"`
+	Roles        []string   `json:"roles,omitempty"`
+	CreatedAt    time.Time  `json:"created_at"`
+	UpdatedAt    *time.Time `json:"updated_at"`
+}
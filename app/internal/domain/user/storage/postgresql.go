@@ -0,0 +1,123 @@
+package storage
+
+import (
+	"context"
+	sq "github.com/Masterminds/squirrel"
+	"prod/internal/domain/user/model"
+	"prod/pkg/client/postgresql"
+	db "prod/pkg/client/postgresql/model"
+	"strings"
+)
+
+type UserStorage struct {
+	queryBuilder sq.StatementBuilderType
+	client       postgresql.Client
+}
+
+func NewUserStorage(client postgresql.Client) *UserStorage {
+	return &UserStorage{
+		queryBuilder: sq.StatementBuilder.PlaceholderFormat(sq.Dollar),
+		client:       client,
+	}
+}
+
+const (
+	scheme = "public"
+	table  = "users"
+)
+
+var columns = []string{"id", "email", "password_hash", "created_at", "updated_at"}
+
+// All lists users with the names of their roles.
+func (s *UserStorage) All(ctx context.Context) ([]model.User, error) {
+	query := s.queryBuilder.Select("u.id", "u.email", "u.created_at", "u.updated_at",
+		"coalesce(array_agg(ur.role ORDER BY ur.role) FILTER (WHERE ur.role IS NOT NULL), '{}')").
+		From(scheme + "." + table + " u").
+		LeftJoin(scheme + ".user_role ur ON ur.user_id = u.id").
+		GroupBy("u.id").
+		OrderBy("u.email")
+
+	sql, args, err := query.ToSql()
+	if err != nil {
+		return nil, db.ErrCreateQuery(err)
+	}
+
+	rows, err := s.client.Query(ctx, sql, args...)
+	if err != nil {
+		return nil, db.ErrDoQuery(postgresql.ClassifyError(err))
+	}
+
+	defer rows.Close()
+
+	list := make([]model.User, 0)
+	for rows.Next() {
+		u := model.User{}
+		if err = rows.Scan(&u.Id, &u.Email, &u.CreatedAt, &u.UpdatedAt, &u.Roles); err != nil {
+			return nil, db.ErrScan(postgresql.ClassifyError(err))
+		}
+		list = append(list, u)
+	}
+	if err = rows.Err(); err != nil {
+		return nil, db.ErrDoQuery(postgresql.ClassifyError(err))
+	}
+
+	return list, nil
+}
+
+func (s *UserStorage) ByEmail(ctx context.Context, email string) (model.User, error) {
+	query := s.queryBuilder.Select(columns...).
+		From(scheme + "." + table).
+		Where(sq.Eq{"email": normalizeEmail(email)})
+
+	return s.queryOne(ctx, query)
+}
+
+func (s *UserStorage) One(ctx context.Context, id string) (model.User, error) {
+	query := s.queryBuilder.Select(columns...).
+		From(scheme + "." + table).
+		Where(sq.Eq{"id": id})
+
+	return s.queryOne(ctx, query)
+}
+
+// Create fails with postgresql.ErrUniqueViolation when the email is taken.
+func (s *UserStorage) Create(ctx context.Context, email string, passwordHash string) (model.User, error) {
+	query := s.queryBuilder.Insert(scheme+"."+table).
+		Columns("email", "password_hash").
+		Values(normalizeEmail(email), passwordHash).
+		Suffix("RETURNING " + strings.Join(columns, ", "))
+
+	return s.queryOne(ctx, query)
+}
+
+// Upsert creates the user or replaces the password of an existing one with the same email.
+func (s *UserStorage) Upsert(ctx context.Context, email string, passwordHash string) (model.User, error) {
+	query := s.queryBuilder.Insert(scheme+"."+table).
+		Columns("email", "password_hash").
+		Values(normalizeEmail(email), passwordHash).
+		Suffix("ON CONFLICT (email) DO UPDATE SET password_hash = EXCLUDED.password_hash, updated_at = now()").
+		Suffix("RETURNING " + strings.Join(columns, ", "))
+
+	return s.queryOne(ctx, query)
+}
+
+func (s *UserStorage) queryOne(ctx context.Context, query sq.Sqlizer) (model.User, error) {
+	u := model.User{}
+
+	sql, args, err := query.ToSql()
+	if err != nil {
+		return u, db.ErrCreateQuery(err)
+	}
+
+	err = s.client.QueryRow(ctx, sql, args...).
+		Scan(&u.Id, &u.Email, &u.PasswordHash, &u.CreatedAt, &u.UpdatedAt)
+	if err != nil {
+		return u, db.ErrDoQuery(postgresql.ClassifyError(err))
+	}
+
+	return u, nil
+}
+
+func normalizeEmail(email string) string {
+	return strings.ToLower(strings.TrimSpace(email))
+}
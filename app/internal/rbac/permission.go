@@ -0,0 +1,42 @@
+package rbac
+
+import "prod/pkg/auth"
+
+// Permissions granted to roles, they must match the values stored in public.role_permission. They gate the changes
+// of the catalog and user management only: reading the catalog is public, so the viewer role holds no permission
+// and API key scopes are write permissions as well.
+const (
+	ProductCreate  auth.Permission = "product:create"
+	ProductUpdate  auth.Permission = "product:update"
+	ProductDelete  auth.Permission = "product:delete"
+	CategoryCreate auth.Permission = "category:create"
+	CategoryUpdate auth.Permission = "category:update"
+	CategoryDelete auth.Permission = "category:delete"
+	CurrencyCreate auth.Permission = "currency:create"
+	CurrencyUpdate auth.Permission = "currency:update"
+	CurrencyDelete auth.Permission = "currency:delete"
+	ImageCreate    auth.Permission = "image:create"
+	ImageUpdate    auth.Permission = "image:update"
+	ImageDelete    auth.Permission = "image:delete"
+	UsersManage    auth.Permission = "users:manage"
+)
+
+// AdminRole is granted to the admin user bootstrapped from the config.
+const AdminRole = "admin"
+
+var Permissions = []auth.Permission{
+	ProductCreate, ProductUpdate, ProductDelete,
+	CategoryCreate, CategoryUpdate, CategoryDelete,
+	CurrencyCreate, CurrencyUpdate, CurrencyDelete,
+	ImageCreate, ImageUpdate, ImageDelete,
+	UsersManage,
+}
+
+func Known(p auth.Permission) bool {
+	for _, known := range Permissions {
+		if p == known {
+			return true
+		}
+	}
+	return false
+}
@@ -0,0 +1,5 @@
+DROP TABLE public.product;
+
+DROP TABLE public.category;
+
+DROP TABLE public.currency;
@@ -1,5 +1,3 @@
-BEGIN;
-
 CREATE EXTENSION IF NOT EXISTS pgcrypto;
 
 CREATE TABLE public.currency
@@ -35,5 +33,3 @@ VALUES ('рубль', 'Р');
 
 INSERT INTO public.currency (name, symbol)
 VALUES ('dollar', '$');
-
-COMMIT;
\ No newline at end of file
@@ -0,0 +1,7 @@
+CREATE TABLE public.image
+(
+    id UUID PRIMARY KEY DEFAULT gen_random_uuid(),
+    name TEXT NOT NULL,
+    size BIGINT NOT NULL,
+    bytes BYTEA NOT NULL
+);
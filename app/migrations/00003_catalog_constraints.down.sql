@@ -0,0 +1,32 @@
+DROP TRIGGER product_set_updated_at ON public.product;
+
+DROP FUNCTION public.set_updated_at();
+
+DROP INDEX public.product_category_id_idx;
+DROP INDEX public.product_currency_id_idx;
+DROP INDEX public.product_image_id_idx;
+DROP INDEX public.product_price_idx;
+DROP INDEX public.product_rating_idx;
+DROP INDEX public.product_created_at_idx;
+DROP INDEX public.product_name_idx;
+
+ALTER TABLE public.product
+    DROP CONSTRAINT product_image_id_fkey,
+    DROP CONSTRAINT product_category_id_fkey,
+    DROP CONSTRAINT product_currency_id_fkey,
+    DROP CONSTRAINT product_specification_check,
+    DROP CONSTRAINT product_rating_check,
+    DROP CONSTRAINT product_price_check,
+    ALTER COLUMN created_at DROP DEFAULT,
+    ALTER COLUMN created_at DROP NOT NULL,
+    ALTER COLUMN rating DROP DEFAULT,
+    ALTER COLUMN rating DROP NOT NULL,
+    ALTER COLUMN currency_id DROP NOT NULL,
+    ALTER COLUMN price DROP NOT NULL;
+
+ALTER TABLE public.category
+    ALTER COLUMN name DROP NOT NULL;
+
+ALTER TABLE public.currency
+    ALTER COLUMN symbol DROP NOT NULL,
+    ALTER COLUMN name DROP NOT NULL;
@@ -0,0 +1,51 @@
+UPDATE public.currency SET name = '' WHERE name IS NULL;
+UPDATE public.currency SET symbol = '' WHERE symbol IS NULL;
+UPDATE public.category SET name = '' WHERE name IS NULL;
+
+ALTER TABLE public.currency
+    ALTER COLUMN name SET NOT NULL,
+    ALTER COLUMN symbol SET NOT NULL;
+
+ALTER TABLE public.category
+    ALTER COLUMN name SET NOT NULL;
+
+UPDATE public.product SET price = 0 WHERE price IS NULL;
+UPDATE public.product SET rating = 0 WHERE rating IS NULL;
+UPDATE public.product SET created_at = now() WHERE created_at IS NULL;
+UPDATE public.product SET currency_id = (SELECT min(id) FROM public.currency) WHERE currency_id IS NULL;
+UPDATE public.product SET image_id = NULL WHERE image_id NOT IN (SELECT id FROM public.image);
+
+ALTER TABLE public.product
+    ALTER COLUMN price SET NOT NULL,
+    ALTER COLUMN currency_id SET NOT NULL,
+    ALTER COLUMN rating SET NOT NULL,
+    ALTER COLUMN rating SET DEFAULT 0,
+    ALTER COLUMN created_at SET NOT NULL,
+    ALTER COLUMN created_at SET DEFAULT now(),
+    ADD CONSTRAINT product_price_check CHECK (price >= 0),
+    ADD CONSTRAINT product_rating_check CHECK (rating BETWEEN 0 AND 5),
+    ADD CONSTRAINT product_specification_check CHECK (specification IS NULL OR jsonb_typeof(specification) = 'object'),
+    ADD CONSTRAINT product_currency_id_fkey FOREIGN KEY (currency_id) REFERENCES public.currency (id) ON DELETE RESTRICT,
+    ADD CONSTRAINT product_category_id_fkey FOREIGN KEY (category_id) REFERENCES public.category (id) ON DELETE RESTRICT,
+    ADD CONSTRAINT product_image_id_fkey FOREIGN KEY (image_id) REFERENCES public.image (id) ON DELETE SET NULL;
+
+CREATE INDEX product_category_id_idx ON public.product (category_id);
+CREATE INDEX product_currency_id_idx ON public.product (currency_id);
+CREATE INDEX product_image_id_idx ON public.product (image_id);
+CREATE INDEX product_price_idx ON public.product (price);
+CREATE INDEX product_rating_idx ON public.product (rating);
+CREATE INDEX product_created_at_idx ON public.product (created_at);
+CREATE INDEX product_name_idx ON public.product (name);
+
+CREATE FUNCTION public.set_updated_at() RETURNS TRIGGER AS
+$$
+BEGIN
+    NEW.updated_at = now();
+    RETURN NEW;
+END;
+$$ LANGUAGE plpgsql;
+
+CREATE TRIGGER product_set_updated_at
+    BEFORE UPDATE ON public.product
+    FOR EACH ROW
+EXECUTE FUNCTION public.set_updated_at();
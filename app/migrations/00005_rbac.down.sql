@@ -0,0 +1,3 @@
+DROP TABLE public.user_role;
+DROP TABLE public.role_permission;
+DROP TABLE public.role;
@@ -0,0 +1,54 @@
+CREATE TABLE public.role
+(
+    name TEXT PRIMARY KEY,
+    description TEXT NOT NULL DEFAULT ''
+);
+
+CREATE TABLE public.role_permission
+(
+    role TEXT NOT NULL REFERENCES public.role (name) ON UPDATE CASCADE ON DELETE CASCADE,
+    permission TEXT NOT NULL,
+    PRIMARY KEY (role, permission)
+);
+
+CREATE TABLE public.user_role
+(
+    user_id UUID NOT NULL REFERENCES public.users (id) ON DELETE CASCADE,
+    role TEXT NOT NULL REFERENCES public.role (name) ON UPDATE CASCADE ON DELETE RESTRICT,
+    PRIMARY KEY (user_id, role)
+);
+
+CREATE INDEX user_role_role_idx ON public.user_role (role);
+
+INSERT INTO public.role (name, description)
+VALUES ('viewer', 'Read-only access'),
+       ('editor', 'Edits products, categories and images'),
+       ('admin', 'Full access including currencies and user management');
+
+INSERT INTO public.role_permission (role, permission)
+VALUES ('editor', 'product:create'),
+       ('editor', 'product:update'),
+       ('editor', 'product:delete'),
+       ('editor', 'category:create'),
+       ('editor', 'category:update'),
+       ('editor', 'image:create'),
+       ('editor', 'image:update'),
+       ('editor', 'image:delete'),
+       ('admin', 'product:create'),
+       ('admin', 'product:update'),
+       ('admin', 'product:delete'),
+       ('admin', 'category:create'),
+       ('admin', 'category:update'),
+       ('admin', 'category:delete'),
+       ('admin', 'currency:create'),
+       ('admin', 'currency:update'),
+       ('admin', 'currency:delete'),
+       ('admin', 'image:create'),
+       ('admin', 'image:update'),
+       ('admin', 'image:delete'),
+       ('admin', 'users:manage');
+
+-- before roles existed every user was an administrator
+INSERT INTO public.user_role (user_id, role)
+SELECT id, 'admin'
+FROM public.users;
@@ -0,0 +1,6 @@
+DROP TRIGGER product_increment_version ON public.product;
+
+DROP FUNCTION public.increment_version();
+
+ALTER TABLE public.product
+    DROP COLUMN version;
@@ -0,0 +1,15 @@
+ALTER TABLE public.product
+    ADD COLUMN version INTEGER NOT NULL DEFAULT 1;
+
+CREATE FUNCTION public.increment_version() RETURNS TRIGGER AS
+$$
+BEGIN
+    NEW.version = OLD.version + 1;
+    RETURN NEW;
+END;
+$$ LANGUAGE plpgsql;
+
+CREATE TRIGGER product_increment_version
+    BEFORE UPDATE ON public.product
+    FOR EACH ROW
+EXECUTE FUNCTION public.increment_version();
@@ -0,0 +1,12 @@
+CREATE TABLE public.idempotency_key
+(
+    key TEXT PRIMARY KEY,
+    fingerprint BYTEA NOT NULL,
+    status INTEGER,
+    header JSONB,
+    body BYTEA,
+    created_at TIMESTAMPTZ NOT NULL DEFAULT now(),
+    expires_at TIMESTAMPTZ NOT NULL
+);
+
+CREATE INDEX idempotency_key_expires_at_idx ON public.idempotency_key (expires_at);
@@ -0,0 +1,4 @@
+DROP INDEX public.product_search_vector_idx;
+
+ALTER TABLE public.product
+    DROP COLUMN search_vector;
@@ -0,0 +1,13 @@
+-- Names and descriptions are stemmed with both configurations, so that either language finds them.
+-- Only the string values of the specification are indexed, numbers and flags are left to the filters.
+ALTER TABLE public.product
+    ADD COLUMN search_vector TSVECTOR GENERATED ALWAYS AS (
+        setweight(to_tsvector('english'::regconfig, coalesce(name, '')), 'A') ||
+        setweight(to_tsvector('russian'::regconfig, coalesce(name, '')), 'A') ||
+        setweight(to_tsvector('english'::regconfig, coalesce(description, '')), 'B') ||
+        setweight(to_tsvector('russian'::regconfig, coalesce(description, '')), 'B') ||
+        setweight(jsonb_to_tsvector('english'::regconfig, coalesce(specification, '{}'::jsonb), '["string"]'), 'C') ||
+        setweight(jsonb_to_tsvector('russian'::regconfig, coalesce(specification, '{}'::jsonb), '["string"]'), 'C')
+    ) STORED;
+
+CREATE INDEX product_search_vector_idx ON public.product USING GIN (search_vector);
@@ -0,0 +1,5 @@
+CREATE INDEX product_name_idx ON public.product (name);
+
+DROP INDEX public.product_name_trgm_idx;
+
+-- the extension may be used by others, it is left installed
@@ -0,0 +1,7 @@
+CREATE EXTENSION IF NOT EXISTS pg_trgm;
+
+-- serves both the prefix LIKE and the word similarity operator of autocomplete
+CREATE INDEX product_name_trgm_idx ON public.product USING GIN (lower(name) gin_trgm_ops);
+
+-- a btree can not serve the substring match of the name filter, it uses product_name_trgm_idx instead
+DROP INDEX public.product_name_idx;
@@ -0,0 +1,2 @@
+DROP INDEX public.product_specification_idx;
+DROP INDEX public.product_specification_path_idx;
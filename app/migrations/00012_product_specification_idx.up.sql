@@ -0,0 +1,3 @@
+-- jsonb_path_ops serves the containment of specification filters, jsonb_ops the key existence of facets
+CREATE INDEX product_specification_path_idx ON public.product USING GIN (specification jsonb_path_ops);
+CREATE INDEX product_specification_idx ON public.product USING GIN (specification);
@@ -0,0 +1,3 @@
+UPDATE public.role
+SET description = 'Read-only access'
+WHERE name = 'viewer';
@@ -0,0 +1,4 @@
+-- permissions only gate changes, reading the catalog needs no role
+UPDATE public.role
+SET description = 'Signs in without changing the catalog, reading it is public'
+WHERE name = 'viewer';
@@ -0,0 +1,111 @@
+package apperror
+
+import (
+	"errors"
+	"fmt"
+	"strings"
+	"time"
+)
+
+var (
+	ErrInvalidInput     = errors.New("invalid input")
+	ErrUnauthorized     = errors.New("unauthorized")
+	ErrForbidden        = errors.New("forbidden")
+	ErrNotFound         = errors.New("not found")
+	ErrMethodNotAllowed = errors.New("method not allowed")
+	ErrRateLimited      = errors.New("rate limited")
+	// ErrPreconditionFailed means the resource has changed since the client read it.
+	ErrPreconditionFailed   = errors.New("precondition failed")
+	ErrPreconditionRequired = errors.New("precondition required")
+	// ErrUnprocessable means the input is well-formed but cannot be processed in the current state.
+	ErrUnprocessable = errors.New("unprocessable")
+	// ErrConflict means a concurrent request holds the resource, the request may be retried later.
+	ErrConflict = errors.New("conflict")
+)
+
+// FieldError describes a problem with a single input field, Field is the JSON path, e.g. "specification.color".
+type FieldError struct {
+	Field   string `json:"field"`
+	Message string `json:"message"`
+}
+
+// Error is a domain error safe to show to the client, it matches its Kind sentinel.
+type Error struct {
+	Kind    error
+	Message string
+	Fields  []FieldError
+	// RetryAfter tells the client when the request may succeed, zero when unknown.
+	RetryAfter time.Duration
+}
+
+func (e *Error) Error() string {
+	msg := e.Kind.Error()
+	if e.Message != "" {
+		msg += ": " + e.Message
+	}
+	if len(e.Fields) > 0 {
+		fields := make([]string, 0, len(e.Fields))
+		for _, f := range e.Fields {
+			fields = append(fields, f.Field+" "+f.Message)
+		}
+		msg += " (" + strings.Join(fields, ", ") + ")"
+	}
+	return msg
+}
+
+func (e *Error) Unwrap() error {
+	return e.Kind
+}
+
+func newError(kind error, format string, args []interface{}) error {
+	return &Error{Kind: kind, Message: fmt.Sprintf(format, args...)}
+}
+
+func InvalidInput(format string, args ...interface{}) error {
+	return newError(ErrInvalidInput, format, args)
+}
+
+// Invalid reports field-level problems of the input.
+func Invalid(fields ...FieldError) error {
+	return &Error{Kind: ErrInvalidInput, Message: "the input has invalid fields", Fields: fields}
+}
+
+func Field(field string, format string, args ...interface{}) FieldError {
+	return FieldError{Field: field, Message: fmt.Sprintf(format, args...)}
+}
+
+func Unauthorized(format string, args ...interface{}) error {
+	return newError(ErrUnauthorized, format, args)
+}
+
+func Forbidden(format string, args ...interface{}) error {
+	return newError(ErrForbidden, format, args)
+}
+
+func NotFound(format string, args ...interface{}) error {
+	return newError(ErrNotFound, format, args)
+}
+
+func MethodNotAllowed(format string, args ...interface{}) error {
+	return newError(ErrMethodNotAllowed, format, args)
+}
+
+func RateLimited(retryAfter time.Duration, format string, args ...interface{}) error {
+	return &Error{Kind: ErrRateLimited, Message: fmt.Sprintf(format, args...), RetryAfter: retryAfter}
+}
+
+func PreconditionFailed(format string, args ...interface{}) error {
+	return newError(ErrPreconditionFailed, format, args)
+}
+
+func PreconditionRequired(format string, args ...interface{}) error {
+	return newError(ErrPreconditionRequired, format, args)
+}
+
+func Unprocessable(format string, args ...interface{}) error {
+	return newError(ErrUnprocessable, format, args)
+}
+
+func Conflict(retryAfter time.Duration, format string, args ...interface{}) error {
+	return &Error{Kind: ErrConflict, Message: fmt.Sprintf(format, args...), RetryAfter: retryAfter}
+}
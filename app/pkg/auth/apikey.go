@@ -0,0 +1,98 @@
+package auth
+
+import (
+	"context"
+	"crypto/rand"
+	"crypto/sha256"
+	"crypto/subtle"
+	"encoding/base64"
+	"encoding/hex"
+	"errors"
+	"prod/pkg/apperror"
+	"prod/pkg/logging"
+	"strings"
+	"time"
+)
+
+// API keys look like "gp_<prefix>_<secret>", the prefix is stored in clear to find the key,
+// the whole key only as a SHA-256 hash. Keys are random, so a slow password hash isn't needed.
+const apiKeyTag = "gp"
+
+// APIKey is what is stored about a key to verify it.
+type APIKey struct {
+	ID        string
+	Hash      string
+	Scopes    []Permission
+	ExpiresAt *time.Time
+	RevokedAt *time.Time
+}
+
+// APIKeyStore finds keys by prefix, Credentials must return an error matching apperror.ErrNotFound for unknown keys.
+type APIKeyStore interface {
+	Credentials(ctx context.Context, prefix string) (APIKey, error)
+	Touch(ctx context.Context, id string) error
+}
+
+// NewAPIKey generates a key, only the prefix and the hash are to be stored.
+func NewAPIKey() (key string, prefix string, hash string, err error) {
+	p := make([]byte, 6)
+	if _, err = rand.Read(p); err != nil {
+		return "", "", "", err
+	}
+	secret := make([]byte, 32)
+	if _, err = rand.Read(secret); err != nil {
+		return "", "", "", err
+	}
+
+	prefix = hex.EncodeToString(p)
+	key = apiKeyTag + "_" + prefix + "_" + base64.RawURLEncoding.EncodeToString(secret)
+	return key, prefix, HashAPIKey(key), nil
+}
+
+func HashAPIKey(key string) string {
+	sum := sha256.Sum256([]byte(key))
+	return hex.EncodeToString(sum[:])
+}
+
+func apiKeyPrefix(key string) (string, bool) {
+	parts := strings.SplitN(key, "_", 3)
+	if len(parts) != 3 || parts[0] != apiKeyTag || parts[1] == "" || parts[2] == "" {
+		return "", false
+	}
+	return parts[1], true
+}
+
+func (m *Middleware) apiKeyPrincipal(ctx context.Context, key string) (Principal, error) {
+	if m.keys == nil {
+		return Principal{}, apperror.Unauthorized("api keys are not accepted")
+	}
+	prefix, ok := apiKeyPrefix(key)
+	if !ok {
+		return Principal{}, apperror.Unauthorized("invalid api key")
+	}
+
+	stored, err := m.keys.Credentials(ctx, prefix)
+	switch {
+	case errors.Is(err, apperror.ErrNotFound):
+		return Principal{}, apperror.Unauthorized("invalid api key")
+	case err != nil:
+		return Principal{}, err
+	}
+
+	if subtle.ConstantTimeCompare([]byte(HashAPIKey(key)), []byte(stored.Hash)) != 1 {
+		return Principal{}, apperror.Unauthorized("invalid api key")
+	}
+	if stored.RevokedAt != nil {
+		return Principal{}, apperror.Unauthorized("api key is revoked")
+	}
+	if stored.ExpiresAt != nil && !stored.ExpiresAt.After(time.Now()) {
+		return Principal{}, apperror.Unauthorized("api key is expired")
+	}
+
+	if err = m.keys.Touch(ctx, stored.ID); err != nil {
+		// a failed usage mark must not fail the request
+		logging.GetLogger(ctx).WithError(err).Warningln("failed to mark api key usage")
+	}
+
+	return Principal{Subject: stored.ID, APIKey: true, Scopes: stored.Scopes}, nil
+}
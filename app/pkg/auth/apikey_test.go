@@ -0,0 +1,129 @@
+package auth
+
+import (
+	"context"
+	"errors"
+	"prod/pkg/apperror"
+	"strings"
+	"testing"
+	"time"
+)
+
+type fakeKeys struct {
+	keys    map[string]APIKey
+	touched []string
+}
+
+func (f *fakeKeys) Credentials(ctx context.Context, prefix string) (APIKey, error) {
+	key, ok := f.keys[prefix]
+	if !ok {
+		return APIKey{}, apperror.NotFound("api key not found")
+	}
+	return key, nil
+}
+
+func (f *fakeKeys) Touch(ctx context.Context, id string) error {
+	f.touched = append(f.touched, id)
+	return nil
+}
+
+func TestNewAPIKey(t *testing.T) {
+	key, prefix, hash, err := NewAPIKey()
+	if err != nil {
+		t.Fatal(err)
+	}
+	if !strings.HasPrefix(key, apiKeyTag+"_"+prefix+"_") {
+		t.Errorf("key %q does not start with its prefix %q", key, prefix)
+	}
+	if got, ok := apiKeyPrefix(key); !ok || got != prefix {
+		t.Errorf("apiKeyPrefix(%q) = %q, %v, want %q", key, got, ok, prefix)
+	}
+	if hash != HashAPIKey(key) || strings.Contains(hash, key) {
+		t.Errorf("hash = %q, want the SHA-256 of the key", hash)
+	}
+
+	other, _, _, err := NewAPIKey()
+	if err != nil {
+		t.Fatal(err)
+	}
+	if other == key {
+		t.Error("two generated keys are equal")
+	}
+}
+
+func TestAPIKeyPrefix(t *testing.T) {
+	tests := []struct {
+		key    string
+		want   string
+		wantOk bool
+	}{
+		{key: "gp_abc_secret", want: "abc", wantOk: true},
+		{key: "gp_abc_sec_ret", want: "abc", wantOk: true},
+		{key: "xx_abc_secret"},
+		{key: "gp_abc"},
+		{key: "gp__secret"},
+		{key: "gp_abc_"},
+		{key: ""},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.key, func(t *testing.T) {
+			got, ok := apiKeyPrefix(tt.key)
+			if got != tt.want || ok != tt.wantOk {
+				t.Errorf("apiKeyPrefix(%q) = %q, %v, want %q, %v", tt.key, got, ok, tt.want, tt.wantOk)
+			}
+		})
+	}
+}
+
+func TestMiddlewareAPIKeyPrincipal(t *testing.T) {
+	key, prefix, hash, err := NewAPIKey()
+	if err != nil {
+		t.Fatal(err)
+	}
+	past := time.Now().Add(-time.Hour)
+	future := time.Now().Add(time.Hour)
+	scopes := []Permission{readPermission}
+
+	tests := []struct {
+		name    string
+		stored  APIKey
+		key     string
+		wantMsg string
+	}{
+		{name: "valid", stored: APIKey{ID: "key-1", Hash: hash, Scopes: scopes}, key: key},
+		{name: "valid until later", stored: APIKey{ID: "key-1", Hash: hash, Scopes: scopes, ExpiresAt: &future}, key: key},
+		{name: "wrong secret", stored: APIKey{ID: "key-1", Hash: hash}, key: key + "x", wantMsg: "invalid api key"},
+		{name: "unknown prefix", stored: APIKey{ID: "key-1", Hash: hash}, key: "gp_unknown_secret", wantMsg: "invalid api key"},
+		{name: "malformed", stored: APIKey{ID: "key-1", Hash: hash}, key: "secret", wantMsg: "invalid api key"},
+		{name: "revoked", stored: APIKey{ID: "key-1", Hash: hash, RevokedAt: &past}, key: key, wantMsg: "api key is revoked"},
+		{name: "expired", stored: APIKey{ID: "key-1", Hash: hash, ExpiresAt: &past}, key: key, wantMsg: "api key is expired"},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			keys := &fakeKeys{keys: map[string]APIKey{prefix: tt.stored}}
+			m := NewMiddleware(newTestTokens(), nil, keys)
+
+			p, err := m.apiKeyPrincipal(context.Background(), tt.key)
+			if tt.wantMsg != "" {
+				if !errors.Is(err, apperror.ErrUnauthorized) || !strings.Contains(err.Error(), tt.wantMsg) {
+					t.Fatalf("err = %v, want unauthorized %q", err, tt.wantMsg)
+				}
+				if len(keys.touched) != 0 {
+					t.Errorf("a rejected key was marked as used")
+				}
+				return
+			}
+			if err != nil {
+				t.Fatal(err)
+			}
+			if !p.APIKey || p.Subject != "key-1" || p.Key() != "apikey:key-1" || len(p.Scopes) != 1 {
+				t.Errorf("principal = %+v", p)
+			}
+			if len(keys.touched) != 1 || keys.touched[0] != "key-1" {
+				t.Errorf("touched = %v, want [key-1]", keys.touched)
+			}
+		})
+	}
+}
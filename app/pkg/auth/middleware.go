@@ -0,0 +1,105 @@
+package auth
+
+import (
+	"context"
+	"net/http"
+	"prod/pkg/apperror"
+	"prod/pkg/httperr"
+	"strings"
+)
+
+// Principal is the authenticated caller, a user or, when APIKey is set, an API key limited to Scopes.
+type Principal struct {
+	Subject string
+	Email   string
+	APIKey  bool
+	Scopes  []Permission
+}
+
+// Key identifies the caller across requests, e.g. "user:<id>" or "apikey:<id>".
+func (p Principal) Key() string {
+	if p.APIKey {
+		return "apikey:" + p.Subject
+	}
+	return "user:" + p.Subject
+}
+
+type ctxPrincipal struct{}
+
+func ContextWithPrincipal(ctx context.Context, p Principal) context.Context {
+	return context.WithValue(ctx, ctxPrincipal{}, p)
+}
+
+func PrincipalFromContext(ctx context.Context) (Principal, bool) {
+	p, ok := ctx.Value(ctxPrincipal{}).(Principal)
+	return p, ok
+}
+
+type Middleware struct {
+	tokens      *TokenManager
+	permissions PermissionSource
+	keys        APIKeyStore
+}
+
+// NewMiddleware creates the middleware, keys may be nil to accept user tokens only.
+func NewMiddleware(tokens *TokenManager, permissions PermissionSource, keys APIKeyStore) *Middleware {
+	return &Middleware{
+		tokens:      tokens,
+		permissions: permissions,
+		keys:        keys,
+	}
+}
+
+// Authenticate rejects requests without a valid "Authorization: Bearer <access token>"
+// or "Authorization: ApiKey <key>" header.
+func (m *Middleware) Authenticate(next http.Handler) http.Handler {
+	return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		if _, ok := PrincipalFromContext(r.Context()); ok {
+			next.ServeHTTP(w, r)
+			return
+		}
+
+		principal, err := m.principal(r.Context(), r.Header.Get("Authorization"))
+		if err != nil {
+			httperr.Write(w, r, err)
+			return
+		}
+
+		next.ServeHTTP(w, r.WithContext(ContextWithPrincipal(r.Context(), principal)))
+	})
+}
+
+// Identify attaches the principal of valid credentials without rejecting anonymous or invalid requests,
+// so that outer middlewares like rate limiting can tell clients apart. Authenticate still rejects them later.
+func (m *Middleware) Identify(next http.Handler) http.Handler {
+	return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		if authorization := r.Header.Get("Authorization"); authorization != "" {
+			if principal, err := m.principal(r.Context(), authorization); err == nil {
+				r = r.WithContext(ContextWithPrincipal(r.Context(), principal))
+			}
+		}
+		next.ServeHTTP(w, r)
+	})
+}
+
+// principal authenticates the value of an Authorization header.
+func (m *Middleware) principal(ctx context.Context, authorization string) (Principal, error) {
+	scheme, credentials, _ := strings.Cut(authorization, " ")
+	credentials = strings.TrimSpace(credentials)
+	if credentials == "" {
+		return Principal{}, apperror.Unauthorized("bearer token or api key is required")
+	}
+
+	switch {
+	case strings.EqualFold(scheme, "Bearer"):
+		claims, err := m.tokens.Parse(credentials, AccessToken)
+		if err != nil {
+			return Principal{}, err
+		}
+		return Principal{Subject: claims.Subject, Email: claims.Email}, nil
+	case strings.EqualFold(scheme, "ApiKey"):
+		return m.apiKeyPrincipal(ctx, credentials)
+	default:
+		return Principal{}, apperror.Unauthorized("unsupported authorization scheme %q", scheme)
+	}
+}
@@ -0,0 +1,89 @@
+package auth
+
+import (
+	"net/http"
+	"net/http/httptest"
+	"testing"
+)
+
+func TestMiddlewareAuthenticate(t *testing.T) {
+	tokens := newTestTokens()
+	pair, err := tokens.Issue("user-1", "user@example.com")
+	if err != nil {
+		t.Fatal(err)
+	}
+	m := NewMiddleware(tokens, nil, nil)
+
+	tests := []struct {
+		name          string
+		authorization string
+		wantStatus    int
+		wantSubject   string
+	}{
+		{name: "bearer", authorization: "Bearer " + pair.AccessToken, wantStatus: http.StatusOK, wantSubject: "user-1"},
+		{name: "scheme is case-insensitive", authorization: "bearer  " + pair.AccessToken, wantStatus: http.StatusOK, wantSubject: "user-1"},
+		{name: "refresh token", authorization: "Bearer " + pair.RefreshToken, wantStatus: http.StatusUnauthorized},
+		{name: "missing", wantStatus: http.StatusUnauthorized},
+		{name: "scheme only", authorization: "Bearer", wantStatus: http.StatusUnauthorized},
+		{name: "basic", authorization: "Basic dXNlcjpwYXNz", wantStatus: http.StatusUnauthorized},
+		{name: "api keys not accepted", authorization: "ApiKey gp_abc_def", wantStatus: http.StatusUnauthorized},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			var subject string
+			handler := m.Authenticate(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+				p, _ := PrincipalFromContext(r.Context())
+				subject = p.Subject
+			}))
+
+			r := httptest.NewRequest(http.MethodGet, "/", nil)
+			if tt.authorization != "" {
+				r.Header.Set("Authorization", tt.authorization)
+			}
+			w := httptest.NewRecorder()
+			handler.ServeHTTP(w, r)
+
+			if w.Code != tt.wantStatus {
+				t.Errorf("status = %d, want %d", w.Code, tt.wantStatus)
+			}
+			if subject != tt.wantSubject {
+				t.Errorf("subject = %q, want %q", subject, tt.wantSubject)
+			}
+		})
+	}
+}
+
+func TestMiddlewareIdentify(t *testing.T) {
+	tokens := newTestTokens()
+	pair, err := tokens.Issue("user-1", "user@example.com")
+	if err != nil {
+		t.Fatal(err)
+	}
+	m := NewMiddleware(tokens, nil, nil)
+
+	for authorization, wantKey := range map[string]string{
+		"Bearer " + pair.AccessToken: "user:user-1",
+		"Bearer invalid":             "",
+		"":                           "",
+	} {
+		var key string
+		handler := m.Identify(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+			if p, ok := PrincipalFromContext(r.Context()); ok {
+				key = p.Key()
+			}
+		}))
+
+		r := httptest.NewRequest(http.MethodGet, "/", nil)
+		r.Header.Set("Authorization", authorization)
+		w := httptest.NewRecorder()
+		handler.ServeHTTP(w, r)
+
+		if w.Code != http.StatusOK {
+			t.Errorf("%q: status = %d, Identify must not reject requests", authorization, w.Code)
+		}
+		if key != wantKey {
+			t.Errorf("%q: principal = %q, want %q", authorization, key, wantKey)
+		}
+	}
+}
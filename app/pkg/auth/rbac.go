@@ -0,0 +1,55 @@
+package auth
+
+import (
+	"context"
+	"net/http"
+	"prod/pkg/apperror"
+	"prod/pkg/httperr"
+)
+
+type Permission string
+
+// PermissionSource returns the permissions granted to a user through its roles.
+type PermissionSource interface {
+	Permissions(ctx context.Context, userID string) ([]Permission, error)
+}
+
+// Guard wraps a handler so that it is only served to principals holding the permission.
+type Guard func(permission Permission) func(http.Handler) http.Handler
+
+// Authorize checks that the principal in ctx holds the permission. User permissions are read on every call
+// so that role changes apply to already issued tokens, API keys are limited to their scopes.
+func (m *Middleware) Authorize(ctx context.Context, permission Permission) error {
+	principal, ok := PrincipalFromContext(ctx)
+	if !ok {
+		return apperror.Unauthorized("authentication is required")
+	}
+
+	granted := principal.Scopes
+	if !principal.APIKey {
+		var err error
+		if granted, err = m.permissions.Permissions(ctx, principal.Subject); err != nil {
+			return err
+		}
+	}
+	for _, p := range granted {
+		if p == permission {
+			return nil
+		}
+	}
+
+	return apperror.Forbidden("%s permission is required", permission)
+}
+
+// Require authenticates the request and checks the permission.
+func (m *Middleware) Require(permission Permission) func(http.Handler) http.Handler {
+	return func(next http.Handler) http.Handler {
+		return m.Authenticate(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+			if err := m.Authorize(r.Context(), permission); err != nil {
+				httperr.Write(w, r, err)
+				return
+			}
+			next.ServeHTTP(w, r)
+		}))
+	}
+}
@@ -0,0 +1,129 @@
+package auth
+
+import (
+	"context"
+	"errors"
+	"net/http"
+	"net/http/httptest"
+	"prod/pkg/apperror"
+	"testing"
+)
+
+const (
+	readPermission  Permission = "product:read"
+	writePermission Permission = "product:write"
+)
+
+// fakePermissions grants permissions by user id, like roles in the database.
+type fakePermissions map[string][]Permission
+
+func (f fakePermissions) Permissions(ctx context.Context, userID string) ([]Permission, error) {
+	if userID == "broken" {
+		return nil, errors.New("database is down")
+	}
+	return f[userID], nil
+}
+
+func TestMiddlewareAuthorize(t *testing.T) {
+	m := NewMiddleware(newTestTokens(), fakePermissions{
+		"editor": {readPermission, writePermission},
+		"viewer": {readPermission},
+	}, nil)
+
+	tests := []struct {
+		name       string
+		principal  *Principal
+		permission Permission
+		wantErr    error
+		// wantSourceErr expects the error of the permission source rather than a kind of apperror
+		wantSourceErr bool
+	}{
+		{name: "anonymous", permission: readPermission, wantErr: apperror.ErrUnauthorized},
+		{name: "granted by role", principal: &Principal{Subject: "editor"}, permission: writePermission},
+		{name: "missing in role", principal: &Principal{Subject: "viewer"}, permission: writePermission, wantErr: apperror.ErrForbidden},
+		{name: "user without roles", principal: &Principal{Subject: "nobody"}, permission: readPermission, wantErr: apperror.ErrForbidden},
+		{
+			name:       "api key scope",
+			principal:  &Principal{Subject: "key", APIKey: true, Scopes: []Permission{writePermission}},
+			permission: writePermission,
+		},
+		{
+			name:       "api key is limited to its scopes",
+			principal:  &Principal{Subject: "editor", APIKey: true, Scopes: []Permission{readPermission}},
+			permission: writePermission,
+			wantErr:    apperror.ErrForbidden,
+		},
+		{name: "permission source fails", principal: &Principal{Subject: "broken"}, permission: readPermission, wantSourceErr: true},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			ctx := context.Background()
+			if tt.principal != nil {
+				ctx = ContextWithPrincipal(ctx, *tt.principal)
+			}
+
+			err := m.Authorize(ctx, tt.permission)
+			if tt.wantSourceErr {
+				if err == nil || errors.Is(err, apperror.ErrForbidden) {
+					t.Errorf("err = %v, want the source error", err)
+				}
+				return
+			}
+			if (tt.wantErr == nil && err != nil) || !errors.Is(err, tt.wantErr) {
+				t.Errorf("Authorize() = %v, want %v", err, tt.wantErr)
+			}
+		})
+	}
+}
+
+func TestMiddlewareAuthorizeReadsRolesOnEveryCall(t *testing.T) {
+	permissions := fakePermissions{"user-1": {readPermission}}
+	m := NewMiddleware(newTestTokens(), permissions, nil)
+	ctx := ContextWithPrincipal(context.Background(), Principal{Subject: "user-1"})
+
+	if err := m.Authorize(ctx, writePermission); !errors.Is(err, apperror.ErrForbidden) {
+		t.Fatalf("Authorize() = %v, want %v", err, apperror.ErrForbidden)
+	}
+	permissions["user-1"] = append(permissions["user-1"], writePermission)
+	if err := m.Authorize(ctx, writePermission); err != nil {
+		t.Errorf("Authorize() after a role change = %v, want nil", err)
+	}
+}
+
+func TestMiddlewareRequire(t *testing.T) {
+	tokens := newTestTokens()
+	m := NewMiddleware(tokens, fakePermissions{"viewer": {readPermission}}, nil)
+	pair, err := tokens.Issue("viewer", "viewer@example.com")
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	tests := []struct {
+		name          string
+		authorization string
+		permission    Permission
+		wantStatus    int
+	}{
+		{name: "allowed", authorization: "Bearer " + pair.AccessToken, permission: readPermission, wantStatus: http.StatusOK},
+		{name: "forbidden", authorization: "Bearer " + pair.AccessToken, permission: writePermission, wantStatus: http.StatusForbidden},
+		{name: "anonymous", permission: readPermission, wantStatus: http.StatusUnauthorized},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			handler := m.Require(tt.permission)(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {}))
+
+			r := httptest.NewRequest(http.MethodPost, "/api/products", nil)
+			if tt.authorization != "" {
+				r.Header.Set("Authorization", tt.authorization)
+			}
+			w := httptest.NewRecorder()
+			handler.ServeHTTP(w, r)
+
+			if w.Code != tt.wantStatus {
+				t.Errorf("status = %d, want %d", w.Code, tt.wantStatus)
+			}
+		})
+	}
+}
@@ -0,0 +1,149 @@
+package auth
+
+import (
+	"errors"
+	"github.com/golang-jwt/jwt/v5"
+	"prod/pkg/apperror"
+	"strings"
+	"testing"
+	"time"
+)
+
+var testSecret = []byte("0123456789abcdef0123456789abcdef")
+
+func newTestTokens() *TokenManager {
+	return NewTokenManager(testSecret, "go-prod", 15*time.Minute, time.Hour)
+}
+
+func signed(t *testing.T, method jwt.SigningMethod, key interface{}, claims Claims) string {
+	t.Helper()
+	token, err := jwt.NewWithClaims(method, claims).SignedString(key)
+	if err != nil {
+		t.Fatal(err)
+	}
+	return token
+}
+
+func claimsFor(typ TokenType, issuer string, expiresAt time.Time) Claims {
+	return Claims{
+		RegisteredClaims: jwt.RegisteredClaims{
+			Issuer:    issuer,
+			Subject:   "user-1",
+			ExpiresAt: jwt.NewNumericDate(expiresAt),
+		},
+		Email: "user@example.com",
+		Type:  typ,
+	}
+}
+
+func TestTokenManagerIssue(t *testing.T) {
+	m := newTestTokens()
+	pair, err := m.Issue("user-1", "user@example.com")
+	if err != nil {
+		t.Fatal(err)
+	}
+	if pair.TokenType != "Bearer" || pair.ExpiresIn != 900 {
+		t.Errorf("pair = %+v, want a Bearer pair expiring in 900s", pair)
+	}
+
+	claims, err := m.Parse(pair.AccessToken, AccessToken)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if claims.Subject != "user-1" || claims.Email != "user@example.com" || claims.Issuer != "go-prod" {
+		t.Errorf("claims = %+v", claims)
+	}
+	if _, err = m.Parse(pair.RefreshToken, RefreshToken); err != nil {
+		t.Errorf("refresh token: %v", err)
+	}
+}
+
+func TestTokenManagerParse(t *testing.T) {
+	m := newTestTokens()
+	future := time.Now().Add(time.Hour)
+
+	tests := []struct {
+		name    string
+		token   string
+		typ     TokenType
+		wantMsg string
+	}{
+		{
+			name:  "valid",
+			token: signed(t, jwt.SigningMethodHS256, testSecret, claimsFor(AccessToken, "go-prod", future)),
+			typ:   AccessToken,
+		},
+		{
+			name:    "refresh token used as access token",
+			token:   signed(t, jwt.SigningMethodHS256, testSecret, claimsFor(RefreshToken, "go-prod", future)),
+			typ:     AccessToken,
+			wantMsg: "access token expected",
+		},
+		{
+			name:    "expired",
+			token:   signed(t, jwt.SigningMethodHS256, testSecret, claimsFor(AccessToken, "go-prod", time.Now().Add(-time.Minute))),
+			typ:     AccessToken,
+			wantMsg: "token is expired",
+		},
+		{
+			name: "without expiry",
+			token: signed(t, jwt.SigningMethodHS256, testSecret, Claims{
+				RegisteredClaims: jwt.RegisteredClaims{Issuer: "go-prod", Subject: "user-1"},
+				Type:             AccessToken,
+			}),
+			typ:     AccessToken,
+			wantMsg: "invalid token",
+		},
+		{
+			name:    "other issuer",
+			token:   signed(t, jwt.SigningMethodHS256, testSecret, claimsFor(AccessToken, "someone-else", future)),
+			typ:     AccessToken,
+			wantMsg: "invalid token",
+		},
+		{
+			name:    "other secret",
+			token:   signed(t, jwt.SigningMethodHS256, []byte("another-secret-another-secret-xx"), claimsFor(AccessToken, "go-prod", future)),
+			typ:     AccessToken,
+			wantMsg: "invalid token",
+		},
+		{
+			name:    "other algorithm",
+			token:   signed(t, jwt.SigningMethodHS512, testSecret, claimsFor(AccessToken, "go-prod", future)),
+			typ:     AccessToken,
+			wantMsg: "invalid token",
+		},
+		{
+			name:    "unsigned",
+			token:   signed(t, jwt.SigningMethodNone, jwt.UnsafeAllowNoneSignatureType, claimsFor(AccessToken, "go-prod", future)),
+			typ:     AccessToken,
+			wantMsg: "invalid token",
+		},
+		{
+			name:    "garbage",
+			token:   "not.a.token",
+			typ:     AccessToken,
+			wantMsg: "invalid token",
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			claims, err := m.Parse(tt.token, tt.typ)
+			if tt.wantMsg == "" {
+				if err != nil {
+					t.Fatal(err)
+				}
+				if claims.Subject != "user-1" {
+					t.Errorf("subject = %q, want user-1", claims.Subject)
+				}
+				return
+			}
+			if !errors.Is(err, apperror.ErrUnauthorized) {
+				t.Fatalf("err = %v, want %v", err, apperror.ErrUnauthorized)
+			}
+			if !strings.Contains(err.Error(), tt.wantMsg) {
+				t.Errorf("err = %v, want %q", err, tt.wantMsg)
+			}
+		})
+	}
+}
@@ -0,0 +1,144 @@
+package cache
+
+import (
+	"testing"
+	"time"
+)
+
+func TestLRUEviction(t *testing.T) {
+	tests := []struct {
+		name    string
+		size    int
+		actions func(c *LRU[string, int])
+		want    map[string]int
+		missing []string
+	}{
+		{
+			name: "oldest is evicted",
+			size: 2,
+			actions: func(c *LRU[string, int]) {
+				c.Set("a", 1)
+				c.Set("b", 2)
+				c.Set("c", 3)
+			},
+			want:    map[string]int{"b": 2, "c": 3},
+			missing: []string{"a"},
+		},
+		{
+			name: "get refreshes the order",
+			size: 2,
+			actions: func(c *LRU[string, int]) {
+				c.Set("a", 1)
+				c.Set("b", 2)
+				c.Get("a")
+				c.Set("c", 3)
+			},
+			want:    map[string]int{"a": 1, "c": 3},
+			missing: []string{"b"},
+		},
+		{
+			name: "set of an existing key refreshes the order",
+			size: 2,
+			actions: func(c *LRU[string, int]) {
+				c.Set("a", 1)
+				c.Set("b", 2)
+				c.Set("a", 10)
+				c.Set("c", 3)
+			},
+			want:    map[string]int{"a": 10, "c": 3},
+			missing: []string{"b"},
+		},
+		{
+			name: "missing get does not change the order",
+			size: 2,
+			actions: func(c *LRU[string, int]) {
+				c.Set("a", 1)
+				c.Set("b", 2)
+				c.Get("x")
+				c.Set("c", 3)
+			},
+			want:    map[string]int{"b": 2, "c": 3},
+			missing: []string{"a", "x"},
+		},
+		{
+			name: "zero size caches nothing",
+			size: 0,
+			actions: func(c *LRU[string, int]) {
+				c.Set("a", 1)
+			},
+			missing: []string{"a"},
+		},
+		{
+			name: "purge drops everything",
+			size: 2,
+			actions: func(c *LRU[string, int]) {
+				c.Set("a", 1)
+				c.Set("b", 2)
+				c.Purge()
+				c.Set("c", 3)
+			},
+			want:    map[string]int{"c": 3},
+			missing: []string{"a", "b"},
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			c := NewLRU[string, int](tt.size, time.Minute)
+			tt.actions(c)
+
+			for key, want := range tt.want {
+				if got, ok := c.Get(key); !ok || got != want {
+					t.Fatalf("Get(%q) = %d, %t, want %d", key, got, ok, want)
+				}
+			}
+			for _, key := range tt.missing {
+				if got, ok := c.Get(key); ok {
+					t.Fatalf("Get(%q) = %d, want a miss", key, got)
+				}
+			}
+			if c.order.Len() != len(c.items) || len(c.items) > tt.size && tt.size > 0 {
+				t.Fatalf("%d items in the list, %d in the map, size %d", c.order.Len(), len(c.items), tt.size)
+			}
+		})
+	}
+}
+
+func TestLRUExpiry(t *testing.T) {
+	now := time.Date(2024, 5, 1, 12, 0, 0, 0, time.UTC)
+	c := NewLRU[string, int](10, time.Minute)
+	c.now = func() time.Time { return now }
+
+	c.Set("a", 1)
+	now = now.Add(30 * time.Second)
+	c.Set("b", 2)
+
+	now = now.Add(30 * time.Second)
+	if got, ok := c.Get("a"); !ok || got != 1 {
+		t.Fatalf("Get(a) at the TTL = %d, %t, want a hit", got, ok)
+	}
+
+	now = now.Add(time.Nanosecond)
+	if _, ok := c.Get("a"); ok {
+		t.Fatal("Get(a) after the TTL is a hit")
+	}
+	if _, ok := c.items["a"]; ok {
+		t.Fatal("expired a is still stored")
+	}
+	if got, ok := c.Get("b"); !ok || got != 2 {
+		t.Fatalf("Get(b) = %d, %t, want a hit", got, ok)
+	}
+
+	// a get does not extend the TTL, a set does
+	now = now.Add(30 * time.Second)
+	if _, ok := c.Get("b"); ok {
+		t.Fatal("Get(b) after the TTL is a hit")
+	}
+	c.Set("c", 3)
+	now = now.Add(45 * time.Second)
+	c.Set("c", 30)
+	now = now.Add(45 * time.Second)
+	if got, ok := c.Get("c"); !ok || got != 30 {
+		t.Fatalf("Get(c) = %d, %t, want the refreshed value", got, ok)
+	}
+}
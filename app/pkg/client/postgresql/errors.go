@@ -0,0 +1,121 @@
+package postgresql
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"github.com/jackc/pgconn"
+	"github.com/jackc/pgx/v4"
+)
+
+var (
+	ErrNotFound             = errors.New("record not found")
+	ErrUniqueViolation      = errors.New("unique violation")
+	ErrForeignKeyViolation  = errors.New("foreign key violation")
+	ErrNotNullViolation     = errors.New("not null violation")
+	ErrCheckViolation       = errors.New("check violation")
+	ErrSerializationFailure = errors.New("serialization failure")
+	ErrDeadlock             = errors.New("deadlock detected")
+	ErrQueryCanceled        = errors.New("query canceled")
+
+	ErrInvalidTextRepresentation = errors.New("invalid text representation")
+)
+
+const (
+	codeUniqueViolation      = "23505"
+	codeForeignKeyViolation  = "23503"
+	codeNotNullViolation     = "23502"
+	codeCheckViolation       = "23514"
+	codeSerializationFailure = "40001"
+	codeDeadlockDetected     = "40P01"
+	codeQueryCanceled        = "57014"
+
+	codeInvalidTextRepresentation = "22P02"
+)
+
+var kinds = map[string]error{
+	codeUniqueViolation:      ErrUniqueViolation,
+	codeForeignKeyViolation:  ErrForeignKeyViolation,
+	codeNotNullViolation:     ErrNotNullViolation,
+	codeCheckViolation:       ErrCheckViolation,
+	codeSerializationFailure: ErrSerializationFailure,
+	codeDeadlockDetected:     ErrDeadlock,
+	codeQueryCanceled:        ErrQueryCanceled,
+
+	codeInvalidTextRepresentation: ErrInvalidTextRepresentation,
+}
+
+// Error is a classified database error, it matches both its Kind sentinel and the original error.
+type Error struct {
+	Kind       error
+	Code       string
+	Message    string
+	Detail     string
+	Table      string
+	Column     string
+	Constraint string
+	Err        error
+}
+
+func (e *Error) Error() string {
+	msg := fmt.Sprintf("%s: %s", e.Kind, e.Message)
+	if e.Constraint != "" {
+		msg += fmt.Sprintf(" (constraint: %s)", e.Constraint)
+	}
+	if e.Column != "" {
+		msg += fmt.Sprintf(" (column: %s)", e.Column)
+	}
+	return msg
+}
+
+func (e *Error) Unwrap() []error {
+	return []error{e.Kind, e.Err}
+}
+
+// ClassifyError maps pgx.ErrNoRows and the known SQLSTATE codes to the sentinel errors of this package.
+// Unknown errors are returned as is.
+func ClassifyError(err error) error {
+	if err == nil {
+		return nil
+	}
+
+	var classified *Error
+	if errors.As(err, &classified) {
+		return err
+	}
+
+	if errors.Is(err, pgx.ErrNoRows) {
+		return &Error{Kind: ErrNotFound, Message: err.Error(), Err: err}
+	}
+
+	var pgErr *pgconn.PgError
+	if !errors.As(err, &pgErr) {
+		return err
+	}
+
+	kind, ok := kinds[pgErr.Code]
+	if !ok {
+		return fmt.Errorf("database error message: %s, detail: %s, where: %s, sqlstate: %s: %w",
+			pgErr.Message, pgErr.Detail, pgErr.Where, pgErr.SQLState(), err)
+	}
+
+	return &Error{
+		Kind:       kind,
+		Code:       pgErr.Code,
+		Message:    pgErr.Message,
+		Detail:     pgErr.Detail,
+		Table:      pgErr.TableName,
+		Column:     pgErr.ColumnName,
+		Constraint: pgErr.ConstraintName,
+		Err:        err,
+	}
+}
+
+// IsRetryable reports whether the transaction that produced err can be safely repeated.
+func IsRetryable(err error) bool {
+	if errors.Is(err, context.Canceled) || errors.Is(err, context.DeadlineExceeded) {
+		return false
+	}
+	err = ClassifyError(err)
+	return errors.Is(err, ErrSerializationFailure) || errors.Is(err, ErrDeadlock)
+}
@@ -0,0 +1,99 @@
+package postgresql
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"github.com/jackc/pgconn"
+	"github.com/jackc/pgx/v4"
+	"testing"
+)
+
+func TestClassifyError(t *testing.T) {
+	other := errors.New("other")
+
+	tests := []struct {
+		name     string
+		err      error
+		wantKind error
+	}{
+		{name: "nil", err: nil, wantKind: nil},
+		{name: "no rows", err: pgx.ErrNoRows, wantKind: ErrNotFound},
+		{name: "wrapped no rows", err: fmt.Errorf("scan: %w", pgx.ErrNoRows), wantKind: ErrNotFound},
+		{name: "unique", err: &pgconn.PgError{Code: codeUniqueViolation, ConstraintName: "product_name_key"}, wantKind: ErrUniqueViolation},
+		{name: "foreign key", err: &pgconn.PgError{Code: codeForeignKeyViolation}, wantKind: ErrForeignKeyViolation},
+		{name: "not null", err: &pgconn.PgError{Code: codeNotNullViolation}, wantKind: ErrNotNullViolation},
+		{name: "check", err: &pgconn.PgError{Code: codeCheckViolation}, wantKind: ErrCheckViolation},
+		{name: "serialization", err: &pgconn.PgError{Code: codeSerializationFailure}, wantKind: ErrSerializationFailure},
+		{name: "deadlock", err: &pgconn.PgError{Code: codeDeadlockDetected}, wantKind: ErrDeadlock},
+		{name: "canceled", err: &pgconn.PgError{Code: codeQueryCanceled}, wantKind: ErrQueryCanceled},
+		{name: "invalid text", err: &pgconn.PgError{Code: codeInvalidTextRepresentation}, wantKind: ErrInvalidTextRepresentation},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			got := ClassifyError(tt.err)
+			if tt.wantKind == nil {
+				if got != nil {
+					t.Fatalf("ClassifyError(nil) = %v", got)
+				}
+				return
+			}
+			if !errors.Is(got, tt.wantKind) {
+				t.Errorf("ClassifyError(%v) = %v, want kind %v", tt.err, got, tt.wantKind)
+			}
+			if !errors.Is(got, tt.err) && !errors.Is(got, pgx.ErrNoRows) {
+				t.Errorf("ClassifyError(%v) lost the original error", tt.err)
+			}
+			if errors.Is(got, other) {
+				t.Errorf("ClassifyError(%v) matches an unrelated error", tt.err)
+			}
+		})
+	}
+}
+
+func TestClassifyErrorUnknown(t *testing.T) {
+	plain := errors.New("plain")
+	if got := ClassifyError(plain); got != plain {
+		t.Errorf("ClassifyError(plain) = %v, want it unchanged", got)
+	}
+
+	pgErr := &pgconn.PgError{Code: "42P01", Message: "relation does not exist"}
+	got := ClassifyError(pgErr)
+	var classified *Error
+	if errors.As(got, &classified) {
+		t.Errorf("ClassifyError(%v) = %v, want an unclassified error", pgErr, got)
+	}
+	if !errors.Is(got, pgErr) {
+		t.Errorf("ClassifyError(%v) lost the original error", pgErr)
+	}
+}
+
+func TestClassifyErrorIdempotent(t *testing.T) {
+	once := ClassifyError(&pgconn.PgError{Code: codeUniqueViolation})
+	if twice := ClassifyError(once); twice != once {
+		t.Errorf("ClassifyError of a classified error = %v, want it unchanged", twice)
+	}
+}
+
+func TestIsRetryable(t *testing.T) {
+	tests := []struct {
+		name string
+		err  error
+		want bool
+	}{
+		{name: "serialization", err: &pgconn.PgError{Code: codeSerializationFailure}, want: true},
+		{name: "deadlock", err: fmt.Errorf("commit: %w", &pgconn.PgError{Code: codeDeadlockDetected}), want: true},
+		{name: "unique", err: &pgconn.PgError{Code: codeUniqueViolation}, want: false},
+		{name: "canceled", err: errors.Join(&pgconn.PgError{Code: codeSerializationFailure}, context.Canceled), want: false},
+		{name: "plain", err: errors.New("plain"), want: false},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			if got := IsRetryable(tt.err); got != tt.want {
+				t.Errorf("IsRetryable(%v) = %v, want %v", tt.err, got, tt.want)
+			}
+		})
+	}
+}
@@ -2,76 +2,179 @@ package postgresql
 
 import (
 	"context"
+	"errors"
 	"fmt"
+	"github.com/jackc/pgconn"
+	"github.com/jackc/pgx/v4"
 	"github.com/jackc/pgx/v4/log/logrusadapter"
 	"github.com/jackc/pgx/v4/pgxpool"
-	"log"
+	"net"
+	"net/url"
 	"prod/pkg/logging"
+	"prod/pkg/retry"
+	"strconv"
 	"time"
 )
 
-type pgConfig struct {
+type Client interface {
+	Begin(ctx context.Context) (pgx.Tx, error)
+	BeginTx(ctx context.Context, txOptions pgx.TxOptions) (pgx.Tx, error)
+	BeginFunc(ctx context.Context, f func(pgx.Tx) error) error
+	BeginTxFunc(ctx context.Context, txOptions pgx.TxOptions, f func(pgx.Tx) error) error
+	Query(ctx context.Context, sql string, args ...interface{}) (pgx.Rows, error)
+	QueryRow(ctx context.Context, sql string, args ...interface{}) pgx.Row
+	Exec(ctx context.Context, sql string, args ...interface{}) (pgconn.CommandTag, error)
+}
+
+type Config struct {
 	Host     string
 	Port     string
 	Username string
 	Password string
 	Database string
+	// PasswordFunc, when set, supplies the password for every new connection so that rotated passwords are used.
+	PasswordFunc func() string
+
+	SSLMode     string
+	SSLRootCert string
+
+	MaxConns          int32
+	MinConns          int32
+	MaxConnLifetime   time.Duration
+	MaxConnIdleTime   time.Duration
+	HealthCheckPeriod time.Duration
+
+	ApplicationName  string
+	StatementTimeout time.Duration
+	SearchPath       string
+
+	ConnectAttempts   int
+	ConnectDelay      time.Duration
+	ConnectMaxDelay   time.Duration
+	ConnectMaxElapsed time.Duration
+	ConnectTimeout    time.Duration
 }
 
-func NewPgConfig(host string, port string, username string, password string, database string) *pgConfig {
-	return &pgConfig{
-		Host:     host,
-		Port:     port,
-		Username: username,
-		Password: password,
-		Database: database,
+// DSN builds a connection string with user credentials and parameters escaped.
+func (c *Config) DSN() string {
+	query := url.Values{}
+	if c.SSLMode != "" {
+		query.Set("sslmode", c.SSLMode)
 	}
+	if c.SSLRootCert != "" {
+		query.Set("sslrootcert", c.SSLRootCert)
+	}
+	if c.ConnectTimeout > 0 {
+		query.Set("connect_timeout", strconv.Itoa(int(c.ConnectTimeout.Seconds())))
+	}
+
+	dsn := url.URL{
+		Scheme:   "postgresql",
+		User:     url.UserPassword(c.Username, c.Password),
+		Host:     net.JoinHostPort(c.Host, c.Port),
+		Path:     "/" + c.Database,
+		RawQuery: query.Encode(),
+	}
+
+	return dsn.String()
 }
 
-func NewClient(ctx context.Context, maxAttempts int, maxDelay time.Duration, cfg *pgConfig) (pool *pgxpool.Pool, err error) {
-	dsn := fmt.Sprintf(
-		"postgresql://%s:%s@%s:%s/%s",
-		cfg.Username, cfg.Password,
-		cfg.Host, cfg.Port, cfg.Database,
-	)
+func (c *Config) poolConfig(ctx context.Context) (*pgxpool.Config, error) {
+	pgxCfg, err := pgxpool.ParseConfig(c.DSN())
+	if err != nil {
+		return nil, fmt.Errorf("unable to parse config: %w", err)
+	}
 
-	err = DoWithAttempts(func() error {
-		ctx, cancel := context.WithTimeout(ctx, 5*time.Second)
-		defer cancel()
+	if c.MaxConns > 0 {
+		pgxCfg.MaxConns = c.MaxConns
+	}
+	if c.MinConns > 0 {
+		pgxCfg.MinConns = c.MinConns
+	}
+	if c.MaxConnLifetime > 0 {
+		pgxCfg.MaxConnLifetime = c.MaxConnLifetime
+	}
+	if c.MaxConnIdleTime > 0 {
+		pgxCfg.MaxConnIdleTime = c.MaxConnIdleTime
+	}
+	if c.HealthCheckPeriod > 0 {
+		pgxCfg.HealthCheckPeriod = c.HealthCheckPeriod
+	}
 
-		pgxCfg, err := pgxpool.ParseConfig(dsn)
-		if err != nil {
-			log.Fatalf("Unable to parse config: $v\n", err)
-		}
+	params := pgxCfg.ConnConfig.RuntimeParams
+	if c.ApplicationName != "" {
+		params["application_name"] = c.ApplicationName
+	}
+	if c.StatementTimeout > 0 {
+		params["statement_timeout"] = strconv.FormatInt(c.StatementTimeout.Milliseconds(), 10)
+	}
+	if c.SearchPath != "" {
+		params["search_path"] = c.SearchPath
+	}
 
-		pgxCfg.ConnConfig.Logger = logrusadapter.NewLogger(logging.GetLogger(ctx))
-		pool, err = pgxpool.ConnectConfig(ctx, pgxCfg)
-		if err != nil {
-			log.Println("Failed to connect to postgres... Going to do the next attempt")
-			return err
+	if c.PasswordFunc != nil {
+		pgxCfg.BeforeConnect = func(_ context.Context, cc *pgx.ConnConfig) error {
+			cc.Password = c.PasswordFunc()
+			return nil
 		}
+	}
 
-		return nil
-	}, maxAttempts, maxDelay)
+	pgxCfg.ConnConfig.Logger = logrusadapter.NewLogger(logging.GetLogger(ctx))
 
-	if err != nil {
-		log.Fatal("All attempts are exceeded. Unable to connect to postgres")
+	return pgxCfg, nil
+}
+
+func (c *Config) retryPolicy(ctx context.Context) retry.Policy {
+	return retry.Policy{
+		MaxAttempts:    c.ConnectAttempts,
+		InitialDelay:   c.ConnectDelay,
+		MaxDelay:       c.ConnectMaxDelay,
+		Multiplier:     2,
+		Jitter:         0.2,
+		MaxElapsedTime: c.ConnectMaxElapsed,
+		Retryable:      isRetryableConnectError,
+		OnRetry: func(attempt int, err error, delay time.Duration) {
+			logging.GetLogger(ctx).WithError(err).Warningf("Failed to connect to postgres (attempt %d), next attempt in %s", attempt, delay)
+		},
 	}
+}
 
-	return pool, nil
+// isRetryableConnectError rejects errors another attempt can not fix, such as bad credentials or a missing database.
+func isRetryableConnectError(err error) bool {
+	if errors.Is(err, context.Canceled) {
+		return false
+	}
+	var pgErr *pgconn.PgError
+	if errors.As(err, &pgErr) && len(pgErr.Code) >= 2 {
+		switch pgErr.Code[:2] {
+		case "28", "3D":
+			return false
+		}
+	}
+	return true
 }
 
-func DoWithAttempts(fn func() error, maxAttempts int, delay time.Duration) error {
-	var err error
+func NewClient(ctx context.Context, cfg *Config) (pool *pgxpool.Pool, err error) {
+	pgxCfg, err := cfg.poolConfig(ctx)
+	if err != nil {
+		return nil, err
+	}
 
-	for maxAttempts > 0 {
-		if err = fn(); err != nil {
-			time.Sleep(delay)
-			maxAttempts--
-			continue
-		}
-		return nil
+	connectTimeout := cfg.ConnectTimeout
+	if connectTimeout <= 0 {
+		connectTimeout = 5 * time.Second
+	}
+
+	err = retry.Do(ctx, cfg.retryPolicy(ctx), func(ctx context.Context) error {
+		ctx, cancel := context.WithTimeout(ctx, connectTimeout)
+		defer cancel()
+
+		pool, err = pgxpool.ConnectConfig(ctx, pgxCfg)
+		return err
+	})
+	if err != nil {
+		return nil, fmt.Errorf("unable to connect to postgres: %w", err)
 	}
 
-	return err
+	return pool, nil
 }
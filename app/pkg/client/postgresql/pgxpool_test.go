@@ -0,0 +1,77 @@
+package postgresql
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"github.com/jackc/pgconn"
+	"prod/pkg/secret"
+	"testing"
+)
+
+func TestIsRetryableConnectError(t *testing.T) {
+	tests := []struct {
+		name string
+		err  error
+		want bool
+	}{
+		{name: "network error", err: errors.New("connection refused"), want: true},
+		{name: "canceled", err: fmt.Errorf("connect: %w", context.Canceled), want: false},
+		{name: "invalid password", err: &pgconn.PgError{Code: "28P01"}, want: false},
+		{name: "missing database", err: &pgconn.PgError{Code: "3D000"}, want: false},
+		{name: "too many connections", err: &pgconn.PgError{Code: "53300"}, want: true},
+		{name: "short code", err: &pgconn.PgError{Code: "2"}, want: true},
+		{name: "empty code", err: &pgconn.PgError{}, want: true},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			if got := isRetryableConnectError(tt.err); got != tt.want {
+				t.Errorf("isRetryableConnectError(%v) = %v, want %v", tt.err, got, tt.want)
+			}
+		})
+	}
+}
+
+// rotatingProvider serves whatever password is current, like a secret store after a rotation.
+type rotatingProvider struct {
+	password string
+}
+
+func (p *rotatingProvider) Get(ctx context.Context, ref secret.Ref) (string, error) {
+	return p.password, nil
+}
+
+func TestPoolConfigRotatedPassword(t *testing.T) {
+	ctx := context.Background()
+	provider := &rotatingProvider{password: "first"}
+	value, err := secret.NewValue(ctx, provider, secret.Ref{Path: "db", Key: "password"})
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	cfg := &Config{Host: "localhost", Port: "5432", Username: "app", Password: "static", Database: "app"}
+	cfg.PasswordFunc = value.Get
+
+	pgxCfg, err := cfg.poolConfig(ctx)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if pgxCfg.BeforeConnect == nil {
+		t.Fatal("BeforeConnect is not set")
+	}
+
+	for _, want := range []string{"first", "rotated"} {
+		provider.password = want
+		if err = value.Refresh(ctx); err != nil {
+			t.Fatal(err)
+		}
+		cc := pgxCfg.ConnConfig.Copy()
+		if err = pgxCfg.BeforeConnect(ctx, cc); err != nil {
+			t.Fatal(err)
+		}
+		if cc.Password != want {
+			t.Errorf("password of a new connection = %q, want %q", cc.Password, want)
+		}
+	}
+}
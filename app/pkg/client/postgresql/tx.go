@@ -0,0 +1,191 @@
+package postgresql
+
+import (
+	"context"
+	"errors"
+	"github.com/jackc/pgconn"
+	"github.com/jackc/pgx/v4"
+	db "prod/pkg/client/postgresql/model"
+	"prod/pkg/logging"
+	"prod/pkg/retry"
+	"time"
+)
+
+type txKey struct{}
+
+func ContextWithTx(ctx context.Context, tx pgx.Tx) context.Context {
+	return context.WithValue(ctx, txKey{}, tx)
+}
+
+func TxFromContext(ctx context.Context) (pgx.Tx, bool) {
+	tx, ok := ctx.Value(txKey{}).(pgx.Tx)
+	return tx, ok
+}
+
+// TxClient runs queries in the transaction stored in the context, or directly on the pool when there is none.
+// Storages built on it take part in transactions of the TxManager without knowing about them.
+type TxClient struct {
+	pool Client
+}
+
+func NewTxClient(pool Client) *TxClient {
+	return &TxClient{pool: pool}
+}
+
+func (c *TxClient) Begin(ctx context.Context) (pgx.Tx, error) {
+	if tx, ok := TxFromContext(ctx); ok {
+		return tx.Begin(ctx)
+	}
+	return c.pool.Begin(ctx)
+}
+
+func (c *TxClient) BeginTx(ctx context.Context, txOptions pgx.TxOptions) (pgx.Tx, error) {
+	if tx, ok := TxFromContext(ctx); ok {
+		return tx.Begin(ctx)
+	}
+	return c.pool.BeginTx(ctx, txOptions)
+}
+
+func (c *TxClient) BeginFunc(ctx context.Context, f func(pgx.Tx) error) error {
+	if tx, ok := TxFromContext(ctx); ok {
+		return tx.BeginFunc(ctx, f)
+	}
+	return c.pool.BeginFunc(ctx, f)
+}
+
+func (c *TxClient) BeginTxFunc(ctx context.Context, txOptions pgx.TxOptions, f func(pgx.Tx) error) error {
+	if tx, ok := TxFromContext(ctx); ok {
+		return tx.BeginFunc(ctx, f)
+	}
+	return c.pool.BeginTxFunc(ctx, txOptions, f)
+}
+
+func (c *TxClient) Query(ctx context.Context, sql string, args ...interface{}) (pgx.Rows, error) {
+	if tx, ok := TxFromContext(ctx); ok {
+		return tx.Query(ctx, sql, args...)
+	}
+	return c.pool.Query(ctx, sql, args...)
+}
+
+func (c *TxClient) QueryRow(ctx context.Context, sql string, args ...interface{}) pgx.Row {
+	if tx, ok := TxFromContext(ctx); ok {
+		return tx.QueryRow(ctx, sql, args...)
+	}
+	return c.pool.QueryRow(ctx, sql, args...)
+}
+
+func (c *TxClient) Exec(ctx context.Context, sql string, args ...interface{}) (pgconn.CommandTag, error) {
+	if tx, ok := TxFromContext(ctx); ok {
+		return tx.Exec(ctx, sql, args...)
+	}
+	return c.pool.Exec(ctx, sql, args...)
+}
+
+type txOptions struct {
+	pgx.TxOptions
+	retry retry.Policy
+}
+
+type TxOption func(*txOptions)
+
+func WithIsoLevel(level pgx.TxIsoLevel) TxOption {
+	return func(o *txOptions) {
+		o.IsoLevel = level
+	}
+}
+
+func ReadOnly() TxOption {
+	return func(o *txOptions) {
+		o.AccessMode = pgx.ReadOnly
+	}
+}
+
+// WithRetry overrides the policy used to repeat transactions failed with a serialization failure or a deadlock.
+func WithRetry(policy retry.Policy) TxOption {
+	return func(o *txOptions) {
+		o.retry = policy
+	}
+}
+
+func defaultTxRetryPolicy() retry.Policy {
+	return retry.Policy{
+		MaxAttempts:  3,
+		InitialDelay: 10 * time.Millisecond,
+		MaxDelay:     200 * time.Millisecond,
+		Multiplier:   2,
+		Jitter:       0.5,
+	}
+}
+
+type TxManager struct {
+	pool Client
+}
+
+func NewTxManager(pool Client) *TxManager {
+	return &TxManager{pool: pool}
+}
+
+// WithinTx runs fn in a transaction put into the context passed to fn.
+// A call inside another transaction creates a savepoint instead, its options are ignored and
+// only the outermost transaction is retried on serialization failures and deadlocks.
+func (m *TxManager) WithinTx(ctx context.Context, fn func(ctx context.Context) error, opts ...TxOption) error {
+	if tx, ok := TxFromContext(ctx); ok {
+		return m.savepoint(ctx, tx, fn)
+	}
+
+	o := txOptions{retry: defaultTxRetryPolicy()}
+	for _, opt := range opts {
+		opt(&o)
+	}
+
+	policy := o.retry
+	retryable := policy.Retryable
+	policy.Retryable = func(err error) bool {
+		if retryable != nil && !retryable(err) {
+			return false
+		}
+		return IsRetryable(err)
+	}
+	if policy.OnRetry == nil {
+		policy.OnRetry = func(attempt int, err error, delay time.Duration) {
+			logging.GetLogger(ctx).WithError(err).Warningf("transaction failed (attempt %d), retrying in %s", attempt, delay)
+		}
+	}
+
+	return retry.Do(ctx, policy, func(ctx context.Context) error {
+		tx, err := m.pool.BeginTx(ctx, o.TxOptions)
+		if err != nil {
+			return db.ErrCreateTx(ClassifyError(err))
+		}
+		return finish(ctx, tx, fn)
+	})
+}
+
+func (m *TxManager) savepoint(ctx context.Context, parent pgx.Tx, fn func(ctx context.Context) error) error {
+	tx, err := parent.Begin(ctx)
+	if err != nil {
+		return db.ErrCreateTx(ClassifyError(err))
+	}
+	return finish(ctx, tx, fn)
+}
+
+func finish(ctx context.Context, tx pgx.Tx, fn func(ctx context.Context) error) (err error) {
+	defer func() {
+		if p := recover(); p != nil {
+			_ = tx.Rollback(context.WithoutCancel(ctx))
+			panic(p)
+		}
+	}()
+
+	if err = fn(ContextWithTx(ctx, tx)); err != nil {
+		if rbErr := tx.Rollback(context.WithoutCancel(ctx)); rbErr != nil && !errors.Is(rbErr, pgx.ErrTxClosed) {
+			return errors.Join(err, db.ErrRollback(rbErr))
+		}
+		return err
+	}
+
+	if err = tx.Commit(ctx); err != nil {
+		return db.ErrCommit(ClassifyError(err))
+	}
+	return nil
+}
@@ -0,0 +1,222 @@
+package postgresql
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"github.com/jackc/pgconn"
+	"github.com/jackc/pgx/v4"
+	"prod/pkg/retry"
+	"reflect"
+	"testing"
+	"time"
+)
+
+// fakeTx records the calls made on a transaction and its savepoints, depth 0 is the top-level transaction.
+type fakeTx struct {
+	pgx.Tx
+	log   *[]string
+	depth int
+	done  bool
+}
+
+func (t *fakeTx) Begin(ctx context.Context) (pgx.Tx, error) {
+	*t.log = append(*t.log, fmt.Sprintf("savepoint %d", t.depth+1))
+	return &fakeTx{log: t.log, depth: t.depth + 1}, nil
+}
+
+func (t *fakeTx) Commit(ctx context.Context) error {
+	if t.done {
+		return pgx.ErrTxClosed
+	}
+	t.done = true
+	*t.log = append(*t.log, fmt.Sprintf("commit %d", t.depth))
+	return nil
+}
+
+func (t *fakeTx) Rollback(ctx context.Context) error {
+	if t.done {
+		return pgx.ErrTxClosed
+	}
+	t.done = true
+	*t.log = append(*t.log, fmt.Sprintf("rollback %d", t.depth))
+	return nil
+}
+
+func (t *fakeTx) Exec(ctx context.Context, sql string, args ...interface{}) (pgconn.CommandTag, error) {
+	*t.log = append(*t.log, fmt.Sprintf("exec %d: %s", t.depth, sql))
+	return nil, nil
+}
+
+type fakePool struct {
+	Client
+	log     []string
+	options []pgx.TxOptions
+}
+
+func (p *fakePool) BeginTx(ctx context.Context, txOptions pgx.TxOptions) (pgx.Tx, error) {
+	p.log = append(p.log, "begin")
+	p.options = append(p.options, txOptions)
+	return &fakeTx{log: &p.log}, nil
+}
+
+func (p *fakePool) Exec(ctx context.Context, sql string, args ...interface{}) (pgconn.CommandTag, error) {
+	p.log = append(p.log, "exec pool: "+sql)
+	return nil, nil
+}
+
+var errTest = errors.New("test")
+
+func fastRetry() TxOption {
+	return WithRetry(retry.Policy{MaxAttempts: 3, InitialDelay: time.Millisecond})
+}
+
+func TestTxManagerWithinTx(t *testing.T) {
+	tests := []struct {
+		name    string
+		fn      func(m *TxManager, c *TxClient) func(ctx context.Context) error
+		wantErr error
+		wantLog []string
+	}{
+		{
+			name: "commit",
+			fn: func(m *TxManager, c *TxClient) func(ctx context.Context) error {
+				return func(ctx context.Context) error {
+					_, err := c.Exec(ctx, "q1")
+					return err
+				}
+			},
+			wantLog: []string{"begin", "exec 0: q1", "commit 0"},
+		},
+		{
+			name: "rollback on error",
+			fn: func(m *TxManager, c *TxClient) func(ctx context.Context) error {
+				return func(ctx context.Context) error {
+					return errTest
+				}
+			},
+			wantErr: errTest,
+			wantLog: []string{"begin", "rollback 0"},
+		},
+		{
+			name: "nested call uses a savepoint",
+			fn: func(m *TxManager, c *TxClient) func(ctx context.Context) error {
+				return func(ctx context.Context) error {
+					return m.WithinTx(ctx, func(ctx context.Context) error {
+						_, err := c.Exec(ctx, "q1")
+						return err
+					})
+				}
+			},
+			wantLog: []string{"begin", "savepoint 1", "exec 1: q1", "commit 1", "commit 0"},
+		},
+		{
+			name: "failed savepoint keeps the outer transaction",
+			fn: func(m *TxManager, c *TxClient) func(ctx context.Context) error {
+				return func(ctx context.Context) error {
+					err := m.WithinTx(ctx, func(ctx context.Context) error {
+						return errTest
+					})
+					if !errors.Is(err, errTest) {
+						return fmt.Errorf("unexpected savepoint error: %w", err)
+					}
+					_, err = c.Exec(ctx, "q2")
+					return err
+				}
+			},
+			wantLog: []string{"begin", "savepoint 1", "rollback 1", "exec 0: q2", "commit 0"},
+		},
+		{
+			name: "serialization failure is retried",
+			fn: func(m *TxManager, c *TxClient) func(ctx context.Context) error {
+				attempt := 0
+				return func(ctx context.Context) error {
+					attempt++
+					if attempt == 1 {
+						return &pgconn.PgError{Code: codeSerializationFailure}
+					}
+					return nil
+				}
+			},
+			wantLog: []string{"begin", "rollback 0", "begin", "commit 0"},
+		},
+		{
+			name: "other errors are not retried",
+			fn: func(m *TxManager, c *TxClient) func(ctx context.Context) error {
+				return func(ctx context.Context) error {
+					return &pgconn.PgError{Code: codeUniqueViolation}
+				}
+			},
+			wantErr: ErrUniqueViolation,
+			wantLog: []string{"begin", "rollback 0"},
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			pool := &fakePool{}
+			m := NewTxManager(pool)
+			c := NewTxClient(pool)
+
+			err := m.WithinTx(context.Background(), tt.fn(m, c), fastRetry())
+			if tt.wantErr == nil && err != nil {
+				t.Fatalf("unexpected error: %v", err)
+			}
+			if tt.wantErr != nil && !errors.Is(ClassifyError(err), tt.wantErr) {
+				t.Fatalf("err = %v, want %v", err, tt.wantErr)
+			}
+			if !reflect.DeepEqual(pool.log, tt.wantLog) {
+				t.Errorf("log = %q, want %q", pool.log, tt.wantLog)
+			}
+		})
+	}
+}
+
+func TestTxManagerWithinTxPanic(t *testing.T) {
+	pool := &fakePool{}
+	m := NewTxManager(pool)
+
+	func() {
+		defer func() {
+			if p := recover(); p != "boom" {
+				t.Errorf("recovered %v, want boom", p)
+			}
+		}()
+		_ = m.WithinTx(context.Background(), func(ctx context.Context) error {
+			panic("boom")
+		})
+	}()
+
+	want := []string{"begin", "rollback 0"}
+	if !reflect.DeepEqual(pool.log, want) {
+		t.Errorf("log = %q, want %q", pool.log, want)
+	}
+}
+
+func TestTxManagerOptions(t *testing.T) {
+	pool := &fakePool{}
+	m := NewTxManager(pool)
+
+	err := m.WithinTx(context.Background(), func(ctx context.Context) error {
+		// options of a nested call are ignored
+		return m.WithinTx(ctx, func(ctx context.Context) error { return nil }, WithIsoLevel(pgx.ReadCommitted))
+	}, WithIsoLevel(pgx.Serializable), ReadOnly())
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	want := []pgx.TxOptions{{IsoLevel: pgx.Serializable, AccessMode: pgx.ReadOnly}}
+	if !reflect.DeepEqual(pool.options, want) {
+		t.Errorf("options = %+v, want %+v", pool.options, want)
+	}
+}
+
+func TestTxClientWithoutTx(t *testing.T) {
+	pool := &fakePool{}
+	if _, err := NewTxClient(pool).Exec(context.Background(), "q1"); err != nil {
+		t.Fatal(err)
+	}
+	if want := []string{"exec pool: q1"}; !reflect.DeepEqual(pool.log, want) {
+		t.Errorf("log = %q, want %q", pool.log, want)
+	}
+}
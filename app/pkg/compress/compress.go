@@ -0,0 +1,248 @@
+package compress
+
+import (
+	"compress/gzip"
+	"compress/zlib"
+	"io"
+	"mime"
+	"net/http"
+	"strconv"
+	"strings"
+	"sync"
+)
+
+// Brotli is not offered: there is no pure Go encoder among the dependencies.
+const (
+	Gzip    = "gzip"
+	Deflate = "deflate"
+)
+
+type encoder interface {
+	io.WriteCloser
+	Flush() error
+	Reset(w io.Writer)
+}
+
+var pools = map[string]*sync.Pool{
+	Gzip: {New: func() interface{} {
+		return gzip.NewWriter(io.Discard)
+	}},
+	// "deflate" in HTTP is the zlib format
+	Deflate: {New: func() interface{} {
+		return zlib.NewWriter(io.Discard)
+	}},
+}
+
+// Middleware compresses responses of at least minSize bytes with gzip or deflate, whichever the client prefers
+// in Accept-Encoding. Only textual content types are compressed. Strong ETags of compressed responses get
+// the encoding as a suffix, so they differ from the identity ones, and the suffix is dropped from If-None-Match
+// and If-Match before the handler compares them.
+func Middleware(minSize int) func(http.Handler) http.Handler {
+	return func(next http.Handler) http.Handler {
+		return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+			w.Header().Add("Vary", "Accept-Encoding")
+
+			encoding := Negotiate(r.Header.Get("Accept-Encoding"))
+			if encoding == "" || r.Method == http.MethodHead {
+				next.ServeHTTP(w, r)
+				return
+			}
+
+			cw := &responseWriter{ResponseWriter: w, encoding: encoding, minSize: minSize}
+			for _, name := range []string{"If-None-Match", "If-Match"} {
+				if v := r.Header.Get(name); v != "" {
+					stripped := stripETagSuffixes(v)
+					cw.suffixed = cw.suffixed || stripped != v
+					r.Header.Set(name, stripped)
+				}
+			}
+
+			next.ServeHTTP(cw, r)
+			cw.close()
+		})
+	}
+}
+
+// Negotiate picks the supported encoding with the highest quality, gzip wins ties. It returns an empty string
+// when the response should not be encoded.
+func Negotiate(acceptEncoding string) string {
+	best, bestQ := "", 0.0
+	for _, part := range strings.Split(acceptEncoding, ",") {
+		name, params, _ := strings.Cut(strings.TrimSpace(part), ";")
+		name = strings.ToLower(strings.TrimSpace(name))
+
+		q := 1.0
+		if v, ok := strings.CutPrefix(strings.TrimSpace(params), "q="); ok {
+			parsed, err := strconv.ParseFloat(v, 64)
+			if err != nil {
+				continue
+			}
+			q = parsed
+		}
+
+		candidates := []string{name}
+		if name == "*" {
+			candidates = []string{Gzip, Deflate}
+		}
+		for _, c := range candidates {
+			if _, ok := pools[c]; !ok || q <= 0 {
+				continue
+			}
+			if q > bestQ || (q == bestQ && c == Gzip) {
+				best, bestQ = c, q
+			}
+		}
+	}
+	return best
+}
+
+func compressible(contentType string) bool {
+	mediaType, _, err := mime.ParseMediaType(contentType)
+	if err != nil {
+		return false
+	}
+	switch {
+	case strings.HasPrefix(mediaType, "text/"),
+		strings.HasSuffix(mediaType, "json"),
+		strings.HasSuffix(mediaType, "+json"),
+		strings.HasSuffix(mediaType, "xml"),
+		mediaType == "application/javascript",
+		mediaType == "image/svg+xml":
+		return true
+	}
+	return false
+}
+
+func stripETagSuffixes(list string) string {
+	for _, encoding := range []string{Gzip, Deflate} {
+		list = strings.ReplaceAll(list, "-"+encoding+`"`, `"`)
+	}
+	return list
+}
+
+// responseWriter buffers the beginning of the body until it is known whether it is worth compressing.
+type responseWriter struct {
+	http.ResponseWriter
+	encoding string
+	minSize  int
+	// suffixed is set when the client sent ETags of compressed responses, a 304 must repeat them
+	suffixed bool
+
+	status  int
+	buf     []byte
+	decided bool
+	enc     encoder
+}
+
+func (w *responseWriter) WriteHeader(status int) {
+	if w.status != 0 {
+		return
+	}
+	w.status = status
+
+	h := w.Header()
+	bodyless := status < http.StatusOK || status == http.StatusNoContent || status == http.StatusNotModified
+	if status == http.StatusNotModified && w.suffixed {
+		w.suffixETag()
+	}
+	if bodyless || h.Get("Content-Encoding") != "" || !compressible(h.Get("Content-Type")) {
+		w.decide(false)
+	}
+}
+
+func (w *responseWriter) Write(b []byte) (int, error) {
+	if w.status == 0 {
+		w.WriteHeader(http.StatusOK)
+	}
+	if w.decided {
+		if w.enc != nil {
+			return w.enc.Write(b)
+		}
+		return w.ResponseWriter.Write(b)
+	}
+
+	w.buf = append(w.buf, b...)
+	if len(w.buf) >= w.minSize {
+		w.decide(true)
+		if err := w.flushBuffer(); err != nil {
+			return 0, err
+		}
+	}
+	return len(b), nil
+}
+
+// Flush sends what has been written so far, compressed or not.
+func (w *responseWriter) Flush() {
+	if !w.decided {
+		if w.status == 0 {
+			w.WriteHeader(http.StatusOK)
+		}
+		w.decide(len(w.buf) > 0)
+		_ = w.flushBuffer()
+	}
+	if w.enc != nil {
+		_ = w.enc.Flush()
+	}
+	_ = http.NewResponseController(w.ResponseWriter).Flush()
+}
+
+// Unwrap lets http.ResponseController reach the underlying writer.
+func (w *responseWriter) Unwrap() http.ResponseWriter {
+	return w.ResponseWriter
+}
+
+func (w *responseWriter) decide(compress bool) {
+	w.decided = true
+	if w.status == 0 {
+		w.status = http.StatusOK
+	}
+
+	if compress {
+		h := w.Header()
+		h.Del("Content-Length")
+		h.Set("Content-Encoding", w.encoding)
+		w.suffixETag()
+
+		w.enc = pools[w.encoding].Get().(encoder)
+		w.enc.Reset(w.ResponseWriter)
+	}
+	w.ResponseWriter.WriteHeader(w.status)
+}
+
+// suffixETag marks a strong ETag with the encoding, weak ones stay valid across encodings.
+func (w *responseWriter) suffixETag() {
+	if etag := w.Header().Get("ETag"); strings.HasPrefix(etag, `"`) {
+		w.Header().Set("ETag", strings.TrimSuffix(etag, `"`)+"-"+w.encoding+`"`)
+	}
+}
+
+func (w *responseWriter) flushBuffer() error {
+	if len(w.buf) == 0 {
+		return nil
+	}
+	buf := w.buf
+	w.buf = nil
+	if w.enc != nil {
+		_, err := w.enc.Write(buf)
+		return err
+	}
+	_, err := w.ResponseWriter.Write(buf)
+	return err
+}
+
+// close writes a body that stayed below minSize as is and finishes the compressed stream.
+func (w *responseWriter) close() {
+	if !w.decided {
+		if w.status == 0 {
+			w.status = http.StatusOK
+		}
+		w.decide(false)
+		_ = w.flushBuffer()
+	}
+	if w.enc != nil {
+		_ = w.enc.Close()
+		w.enc.Reset(io.Discard)
+		pools[w.encoding].Put(w.enc)
+		w.enc = nil
+	}
+}
@@ -0,0 +1,194 @@
+package compress
+
+import (
+	"compress/gzip"
+	"compress/zlib"
+	"io"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+)
+
+func TestNegotiate(t *testing.T) {
+	tests := []struct {
+		acceptEncoding string
+		want           string
+	}{
+		{acceptEncoding: "", want: ""},
+		{acceptEncoding: "identity", want: ""},
+		{acceptEncoding: "br", want: ""},
+		{acceptEncoding: "gzip", want: Gzip},
+		{acceptEncoding: "deflate", want: Deflate},
+		{acceptEncoding: "GZIP", want: Gzip},
+		{acceptEncoding: "deflate, gzip", want: Gzip},
+		{acceptEncoding: "gzip;q=0.5, deflate", want: Deflate},
+		{acceptEncoding: "gzip;q=0, deflate;q=0.1", want: Deflate},
+		{acceptEncoding: "gzip;q=0", want: ""},
+		{acceptEncoding: "gzip;q=abc, deflate;q=0.2", want: Deflate},
+		{acceptEncoding: "*", want: Gzip},
+		{acceptEncoding: "*;q=0.3, deflate;q=0.5", want: Deflate},
+		{acceptEncoding: "br;q=1.0, gzip;q=0.8", want: Gzip},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.acceptEncoding, func(t *testing.T) {
+			if got := Negotiate(tt.acceptEncoding); got != tt.want {
+				t.Fatalf("Negotiate(%q) = %q, want %q", tt.acceptEncoding, got, tt.want)
+			}
+		})
+	}
+}
+
+func decode(t *testing.T, encoding string, body io.Reader) string {
+	t.Helper()
+
+	var (
+		r   io.Reader
+		err error
+	)
+	switch encoding {
+	case Gzip:
+		r, err = gzip.NewReader(body)
+	case Deflate:
+		r, err = zlib.NewReader(body)
+	default:
+		r = body
+	}
+	if err != nil {
+		t.Fatalf("open %s body: %v", encoding, err)
+	}
+	b, err := io.ReadAll(r)
+	if err != nil {
+		t.Fatalf("read %s body: %v", encoding, err)
+	}
+	return string(b)
+}
+
+func TestMiddleware(t *testing.T) {
+	large := `{"items":"` + strings.Repeat("a", 100) + `"}`
+	small := `{}`
+
+	tests := []struct {
+		name           string
+		method         string
+		acceptEncoding string
+		contentType    string
+		etag           string
+		body           string
+		wantEncoding   string
+		wantETag       string
+	}{
+		{name: "gzip", method: http.MethodGet, acceptEncoding: "gzip", contentType: "application/json", etag: `"1"`, body: large, wantEncoding: Gzip, wantETag: `"1-gzip"`},
+		{name: "deflate", method: http.MethodGet, acceptEncoding: "deflate", contentType: "application/problem+json", etag: `"1"`, body: large, wantEncoding: Deflate, wantETag: `"1-deflate"`},
+		{name: "weak etag is kept", method: http.MethodGet, acceptEncoding: "gzip", contentType: "text/plain", etag: `W/"1"`, body: large, wantEncoding: Gzip, wantETag: `W/"1"`},
+		{name: "below min size", method: http.MethodGet, acceptEncoding: "gzip", contentType: "application/json", etag: `"1"`, body: small, wantETag: `"1"`},
+		{name: "binary content", method: http.MethodGet, acceptEncoding: "gzip", contentType: "image/png", etag: `"1"`, body: large, wantETag: `"1"`},
+		{name: "not accepted", method: http.MethodGet, contentType: "application/json", etag: `"1"`, body: large, wantETag: `"1"`},
+		{name: "head", method: http.MethodHead, acceptEncoding: "gzip", contentType: "application/json", etag: `"1"`, wantETag: `"1"`},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			handler := Middleware(64)(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+				w.Header().Set("Content-Type", tt.contentType)
+				w.Header().Set("ETag", tt.etag)
+				_, _ = io.WriteString(w, tt.body)
+			}))
+			r := httptest.NewRequest(tt.method, "/api/products", nil)
+			if tt.acceptEncoding != "" {
+				r.Header.Set("Accept-Encoding", tt.acceptEncoding)
+			}
+			w := httptest.NewRecorder()
+
+			handler.ServeHTTP(w, r)
+
+			if got := w.Header().Get("Content-Encoding"); got != tt.wantEncoding {
+				t.Fatalf("Content-Encoding = %q, want %q", got, tt.wantEncoding)
+			}
+			if got := w.Header().Get("ETag"); got != tt.wantETag {
+				t.Fatalf("ETag = %q, want %q", got, tt.wantETag)
+			}
+			if got := w.Header().Get("Vary"); got != "Accept-Encoding" {
+				t.Fatalf("Vary = %q", got)
+			}
+			if got := decode(t, tt.wantEncoding, w.Body); got != tt.body {
+				t.Fatalf("body = %q, want %q", got, tt.body)
+			}
+		})
+	}
+}
+
+func TestMiddlewareConditional(t *testing.T) {
+	tests := []struct {
+		name        string
+		ifNoneMatch string
+		wantHeader  string
+		wantStatus  int
+		wantETag    string
+	}{
+		{name: "compressed etag", ifNoneMatch: `"1-gzip"`, wantHeader: `"1"`, wantStatus: http.StatusNotModified, wantETag: `"1-gzip"`},
+		{name: "identity etag", ifNoneMatch: `"1"`, wantHeader: `"1"`, wantStatus: http.StatusNotModified, wantETag: `"1"`},
+		{name: "list", ifNoneMatch: `"0-deflate", "1-gzip"`, wantHeader: `"0", "1"`, wantStatus: http.StatusNotModified, wantETag: `"1-gzip"`},
+		{name: "stale", ifNoneMatch: `"0-gzip"`, wantHeader: `"0"`, wantStatus: http.StatusOK, wantETag: `"1-gzip"`},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			var gotHeader string
+			handler := Middleware(1)(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+				gotHeader = r.Header.Get("If-None-Match")
+				w.Header().Set("ETag", `"1"`)
+				if strings.Contains(gotHeader, `"1"`) {
+					w.WriteHeader(http.StatusNotModified)
+					return
+				}
+				w.Header().Set("Content-Type", "application/json")
+				_, _ = io.WriteString(w, `{"id":1}`)
+			}))
+			r := httptest.NewRequest(http.MethodGet, "/api/products/1", nil)
+			r.Header.Set("Accept-Encoding", "gzip")
+			r.Header.Set("If-None-Match", tt.ifNoneMatch)
+			w := httptest.NewRecorder()
+
+			handler.ServeHTTP(w, r)
+
+			if gotHeader != tt.wantHeader {
+				t.Fatalf("handler got If-None-Match %q, want %q", gotHeader, tt.wantHeader)
+			}
+			if w.Code != tt.wantStatus {
+				t.Fatalf("status = %d, want %d", w.Code, tt.wantStatus)
+			}
+			if got := w.Header().Get("ETag"); got != tt.wantETag {
+				t.Fatalf("ETag = %q, want %q", got, tt.wantETag)
+			}
+			if tt.wantStatus == http.StatusNotModified && (w.Body.Len() != 0 || w.Header().Get("Content-Encoding") != "") {
+				t.Fatalf("304 has a body %q or Content-Encoding %q", w.Body.String(), w.Header().Get("Content-Encoding"))
+			}
+		})
+	}
+}
+
+func TestMiddlewareFlush(t *testing.T) {
+	handler := Middleware(1024)(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		w.Header().Set("Content-Type", "text/event-stream")
+		_, _ = io.WriteString(w, "data: 1\n\n")
+		_ = http.NewResponseController(w).Flush()
+		_, _ = io.WriteString(w, "data: 2\n\n")
+	}))
+	r := httptest.NewRequest(http.MethodGet, "/events", nil)
+	r.Header.Set("Accept-Encoding", "gzip")
+	w := httptest.NewRecorder()
+
+	handler.ServeHTTP(w, r)
+
+	if !w.Flushed {
+		t.Fatal("response was not flushed")
+	}
+	if got := w.Header().Get("Content-Encoding"); got != Gzip {
+		t.Fatalf("Content-Encoding = %q, want %q", got, Gzip)
+	}
+	if got := decode(t, Gzip, w.Body); got != "data: 1\n\ndata: 2\n\n" {
+		t.Fatalf("body = %q", got)
+	}
+}
@@ -0,0 +1,77 @@
+package grpcerr
+
+import (
+	"context"
+	"google.golang.org/genproto/googleapis/rpc/errdetails"
+	"google.golang.org/grpc/codes"
+	"google.golang.org/grpc/status"
+	"google.golang.org/protobuf/protoadapt"
+	"google.golang.org/protobuf/types/known/durationpb"
+	"prod/pkg/logging"
+	"prod/pkg/problem"
+	"prod/pkg/requestid"
+	"strings"
+)
+
+// Domain is the ErrorInfo domain of errors raised by this service.
+const Domain = "go-prod"
+
+// Code translates domain and classified database errors into a gRPC status code.
+func Code(err error) codes.Code {
+	if err == nil {
+		return codes.OK
+	}
+	return problem.KindOf(err).Code
+}
+
+// Error converts err into a gRPC status error mirroring the HTTP problem: ErrorInfo carries the problem type,
+// BadRequest the field errors, RequestInfo the request ID and RetryInfo when to retry.
+// Internal details are hidden from the client.
+func Error(ctx context.Context, err error) error {
+	if err == nil {
+		return nil
+	}
+	if _, ok := status.FromError(err); ok {
+		return err
+	}
+
+	if problem.KindOf(err) == problem.Internal {
+		logging.GetLogger(ctx).WithError(err).WithField("request_id", requestid.FromContext(ctx)).Errorln("call failed")
+	}
+	return Status(ctx, err).Err()
+}
+
+// Status builds the status of Error without logging, for callers that have already logged err.
+func Status(ctx context.Context, err error) *status.Status {
+	kind := problem.KindOf(err)
+	st := status.New(kind.Code, problem.Detail(err, kind))
+	details := []protoadapt.MessageV1{
+		&errdetails.ErrorInfo{
+			Reason:   strings.ToUpper(strings.ReplaceAll(kind.Slug, "-", "_")),
+			Domain:   Domain,
+			Metadata: map[string]string{"type": kind.Type(), "title": kind.Title},
+		},
+	}
+	if fields := problem.Fields(err); len(fields) > 0 {
+		br := &errdetails.BadRequest{}
+		for _, f := range fields {
+			br.FieldViolations = append(br.FieldViolations, &errdetails.BadRequest_FieldViolation{
+				Field:       f.Field,
+				Description: f.Message,
+			})
+		}
+		details = append(details, br)
+	}
+	if id := requestid.FromContext(ctx); id != "" {
+		details = append(details, &errdetails.RequestInfo{RequestId: id})
+	}
+	if retryAfter := problem.RetryAfter(err, kind); retryAfter > 0 {
+		details = append(details, &errdetails.RetryInfo{RetryDelay: durationpb.New(retryAfter)})
+	}
+
+	withDetails, detailsErr := st.WithDetails(details...)
+	if detailsErr != nil {
+		return st
+	}
+	return withDetails
+}
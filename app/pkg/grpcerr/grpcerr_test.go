@@ -0,0 +1,110 @@
+package grpcerr
+
+import (
+	"context"
+	"errors"
+	"google.golang.org/genproto/googleapis/rpc/errdetails"
+	"google.golang.org/grpc/codes"
+	"google.golang.org/grpc/status"
+	"prod/pkg/apperror"
+	"prod/pkg/requestid"
+	"testing"
+	"time"
+)
+
+func TestCode(t *testing.T) {
+	tests := []struct {
+		name string
+		err  error
+		want codes.Code
+	}{
+		{name: "nil", err: nil, want: codes.OK},
+		{name: "invalid input", err: apperror.InvalidInput("bad"), want: codes.InvalidArgument},
+		{name: "not found", err: apperror.NotFound("no product"), want: codes.NotFound},
+		{name: "precondition failed", err: apperror.PreconditionFailed("stale"), want: codes.Aborted},
+		{name: "deadline", err: context.DeadlineExceeded, want: codes.DeadlineExceeded},
+		{name: "unknown", err: errors.New("boom"), want: codes.Internal},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			if got := Code(tt.err); got != tt.want {
+				t.Fatalf("Code() = %s, want %s", got, tt.want)
+			}
+		})
+	}
+}
+
+func TestStatus(t *testing.T) {
+	ctx := requestid.ContextWithID(context.Background(), "req-1")
+	err := apperror.Invalid(apperror.Field("name", "is required"))
+
+	st := Status(ctx, err)
+	if st.Code() != codes.InvalidArgument {
+		t.Fatalf("code = %s, want %s", st.Code(), codes.InvalidArgument)
+	}
+	if st.Message() != "the input has invalid fields" {
+		t.Fatalf("message = %q", st.Message())
+	}
+
+	var (
+		info        *errdetails.ErrorInfo
+		badRequest  *errdetails.BadRequest
+		requestInfo *errdetails.RequestInfo
+	)
+	for _, d := range st.Details() {
+		switch d := d.(type) {
+		case *errdetails.ErrorInfo:
+			info = d
+		case *errdetails.BadRequest:
+			badRequest = d
+		case *errdetails.RequestInfo:
+			requestInfo = d
+		case *errdetails.RetryInfo:
+			t.Fatalf("unexpected RetryInfo %v", d)
+		}
+	}
+	if info == nil || info.Reason != "INVALID_INPUT" || info.Domain != Domain || info.Metadata["type"] != "urn:go-prod:problem:invalid-input" {
+		t.Fatalf("ErrorInfo = %v", info)
+	}
+	if badRequest == nil || len(badRequest.FieldViolations) != 1 ||
+		badRequest.FieldViolations[0].Field != "name" || badRequest.FieldViolations[0].Description != "is required" {
+		t.Fatalf("BadRequest = %v", badRequest)
+	}
+	if requestInfo == nil || requestInfo.RequestId != "req-1" {
+		t.Fatalf("RequestInfo = %v", requestInfo)
+	}
+}
+
+func TestStatusRetryInfo(t *testing.T) {
+	st := Status(context.Background(), apperror.RateLimited(2*time.Second, "slow down"))
+	if st.Code() != codes.ResourceExhausted {
+		t.Fatalf("code = %s, want %s", st.Code(), codes.ResourceExhausted)
+	}
+	for _, d := range st.Details() {
+		if retry, ok := d.(*errdetails.RetryInfo); ok {
+			if got := retry.RetryDelay.AsDuration(); got != 2*time.Second {
+				t.Fatalf("retry delay = %s, want 2s", got)
+			}
+			return
+		}
+	}
+	t.Fatal("RetryInfo is missing")
+}
+
+func TestError(t *testing.T) {
+	if err := Error(context.Background(), nil); err != nil {
+		t.Fatalf("Error(nil) = %v", err)
+	}
+
+	existing := status.Error(codes.Unavailable, "down")
+	if err := Error(context.Background(), existing); err != existing {
+		t.Fatalf("Error() = %v, want the status error as is", err)
+	}
+
+	err := Error(context.Background(), errors.New("password=secret"))
+	st, ok := status.FromError(err)
+	if !ok || st.Code() != codes.Internal || st.Message() != "internal error" {
+		t.Fatalf("Error() = %v, want a hidden internal status", err)
+	}
+}
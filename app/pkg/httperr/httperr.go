@@ -0,0 +1,82 @@
+package httperr
+
+import (
+	"encoding/json"
+	"math"
+	"net/http"
+	"prod/pkg/apperror"
+	"prod/pkg/logging"
+	"prod/pkg/problem"
+	"prod/pkg/requestid"
+	"strconv"
+)
+
+const ContentType = "application/problem+json"
+
+// Problem is the RFC 7807 body of every error response.
+type Problem struct {
+	Type      string                `json:"type"`
+	Title     string                `json:"title"`
+	Status    int                   `json:"status"`
+	Detail    string                `json:"detail,omitempty"`
+	Instance  string                `json:"instance,omitempty"`
+	RequestID string                `json:"request_id,omitempty"`
+	Errors    []apperror.FieldError `json:"errors,omitempty"`
+}
+
+// Status translates domain and classified database errors into an HTTP status code.
+func Status(err error) int {
+	if err == nil {
+		return http.StatusOK
+	}
+	return problem.KindOf(err).Status
+}
+
+// NewProblem describes err for the client, internal details are never included.
+func NewProblem(r *http.Request, err error) Problem {
+	kind := problem.KindOf(err)
+	return Problem{
+		Type:      kind.Type(),
+		Title:     kind.Title,
+		Status:    kind.Status,
+		Detail:    problem.Detail(err, kind),
+		Instance:  r.URL.Path,
+		RequestID: requestid.FromContext(r.Context()),
+		Errors:    problem.Fields(err),
+	}
+}
+
+// Write responds with the problem of err, server errors are logged with their details.
+func Write(w http.ResponseWriter, r *http.Request, err error) {
+	p := NewProblem(r, err)
+	if p.Status >= http.StatusInternalServerError {
+		logging.GetLogger(r.Context()).WithError(err).WithField("request_id", p.RequestID).Errorln("request failed")
+	}
+
+	if p.Status == http.StatusUnauthorized {
+		w.Header().Set("WWW-Authenticate", "Bearer")
+		w.Header().Add("WWW-Authenticate", "ApiKey")
+	}
+	if retryAfter := problem.RetryAfter(err, problem.KindOf(err)); retryAfter > 0 {
+		w.Header().Set("Retry-After", strconv.Itoa(int(math.Ceil(retryAfter.Seconds()))))
+	}
+
+	WriteProblem(w, p)
+}
+
+// WriteProblem responds with p as is, for callers that have already logged the error.
+func WriteProblem(w http.ResponseWriter, p Problem) {
+	w.Header().Set("Content-Type", ContentType)
+	w.WriteHeader(p.Status)
+	_ = json.NewEncoder(w).Encode(p)
+}
+
+// NotFound answers requests that match no route.
+func NotFound(w http.ResponseWriter, r *http.Request) {
+	Write(w, r, apperror.NotFound("no route for %s", r.URL.Path))
+}
+
+// MethodNotAllowed answers requests to a known route with an unsupported method.
+func MethodNotAllowed(w http.ResponseWriter, r *http.Request) {
+	Write(w, r, apperror.MethodNotAllowed("%s is not supported by %s", r.Method, r.URL.Path))
+}
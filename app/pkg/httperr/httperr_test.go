@@ -0,0 +1,176 @@
+package httperr
+
+import (
+	"encoding/json"
+	"errors"
+	"github.com/jackc/pgconn"
+	"net/http"
+	"net/http/httptest"
+	"prod/pkg/apperror"
+	"prod/pkg/client/postgresql"
+	"prod/pkg/requestid"
+	"reflect"
+	"testing"
+	"time"
+)
+
+func TestWrite(t *testing.T) {
+	tests := []struct {
+		name       string
+		err        error
+		want       Problem
+		wantHeader http.Header
+	}{
+		{
+			name: "not found",
+			err:  apperror.NotFound("product 1 not found"),
+			want: Problem{
+				Type:      "urn:go-prod:problem:not-found",
+				Title:     "Not found",
+				Status:    http.StatusNotFound,
+				Detail:    "product 1 not found",
+				Instance:  "/api/products/1",
+				RequestID: "req-1",
+			},
+		},
+		{
+			name: "field errors",
+			err:  apperror.Invalid(apperror.Field("name", "is required")),
+			want: Problem{
+				Type:      "urn:go-prod:problem:invalid-input",
+				Title:     "Invalid input",
+				Status:    http.StatusBadRequest,
+				Detail:    "the input has invalid fields",
+				Instance:  "/api/products/1",
+				RequestID: "req-1",
+				Errors:    []apperror.FieldError{{Field: "name", Message: "is required"}},
+			},
+		},
+		{
+			name: "unauthorized",
+			err:  apperror.Unauthorized("token expired"),
+			want: Problem{
+				Type:      "urn:go-prod:problem:unauthorized",
+				Title:     "Unauthorized",
+				Status:    http.StatusUnauthorized,
+				Detail:    "token expired",
+				Instance:  "/api/products/1",
+				RequestID: "req-1",
+			},
+			wantHeader: http.Header{"Www-Authenticate": {"Bearer", "ApiKey"}},
+		},
+		{
+			name: "rate limited rounds retry after up",
+			err:  apperror.RateLimited(1500*time.Millisecond, "slow down"),
+			want: Problem{
+				Type:      "urn:go-prod:problem:rate-limited",
+				Title:     "Too many requests",
+				Status:    http.StatusTooManyRequests,
+				Detail:    "slow down",
+				Instance:  "/api/products/1",
+				RequestID: "req-1",
+			},
+			wantHeader: http.Header{"Retry-After": {"2"}},
+		},
+		{
+			name: "database details are hidden",
+			err:  postgresql.ClassifyError(&pgconn.PgError{Code: "40001", Message: "could not serialize access"}),
+			want: Problem{
+				Type:      "urn:go-prod:problem:concurrent-update",
+				Title:     "Concurrent update",
+				Status:    http.StatusServiceUnavailable,
+				Detail:    "the request collided with a concurrent one, retry it",
+				Instance:  "/api/products/1",
+				RequestID: "req-1",
+			},
+			wantHeader: http.Header{"Retry-After": {"1"}},
+		},
+		{
+			name: "internal",
+			err:  errors.New("dial tcp: password authentication failed"),
+			want: Problem{
+				Type:      "urn:go-prod:problem:internal",
+				Title:     "Internal server error",
+				Status:    http.StatusInternalServerError,
+				Detail:    "internal error",
+				Instance:  "/api/products/1",
+				RequestID: "req-1",
+			},
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			r := httptest.NewRequest(http.MethodGet, "/api/products/1", nil)
+			r = r.WithContext(requestid.ContextWithID(r.Context(), "req-1"))
+			w := httptest.NewRecorder()
+
+			Write(w, r, tt.err)
+
+			if w.Code != tt.want.Status {
+				t.Fatalf("status = %d, want %d", w.Code, tt.want.Status)
+			}
+			if got := w.Header().Get("Content-Type"); got != ContentType {
+				t.Fatalf("Content-Type = %q, want %q", got, ContentType)
+			}
+			for key, want := range tt.wantHeader {
+				if got := w.Header().Values(key); !reflect.DeepEqual(got, want) {
+					t.Fatalf("%s = %q, want %q", key, got, want)
+				}
+			}
+			if tt.wantHeader.Get("Retry-After") == "" && w.Header().Get("Retry-After") != "" {
+				t.Fatalf("unexpected Retry-After %q", w.Header().Get("Retry-After"))
+			}
+
+			var got Problem
+			if err := json.NewDecoder(w.Body).Decode(&got); err != nil {
+				t.Fatalf("decode body: %v", err)
+			}
+			if !reflect.DeepEqual(got, tt.want) {
+				t.Fatalf("body = %+v, want %+v", got, tt.want)
+			}
+		})
+	}
+}
+
+func TestStatus(t *testing.T) {
+	tests := []struct {
+		name string
+		err  error
+		want int
+	}{
+		{name: "nil", err: nil, want: http.StatusOK},
+		{name: "forbidden", err: apperror.Forbidden("no"), want: http.StatusForbidden},
+		{name: "unique", err: postgresql.ClassifyError(&pgconn.PgError{Code: "23505"}), want: http.StatusConflict},
+		{name: "unknown", err: errors.New("boom"), want: http.StatusInternalServerError},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			if got := Status(tt.err); got != tt.want {
+				t.Fatalf("Status() = %d, want %d", got, tt.want)
+			}
+		})
+	}
+}
+
+func TestNotFoundAndMethodNotAllowed(t *testing.T) {
+	w := httptest.NewRecorder()
+	NotFound(w, httptest.NewRequest(http.MethodGet, "/missing", nil))
+	if w.Code != http.StatusNotFound {
+		t.Fatalf("NotFound status = %d", w.Code)
+	}
+
+	w = httptest.NewRecorder()
+	MethodNotAllowed(w, httptest.NewRequest(http.MethodPut, "/api/products", nil))
+	if w.Code != http.StatusMethodNotAllowed {
+		t.Fatalf("MethodNotAllowed status = %d", w.Code)
+	}
+	var p Problem
+	if err := json.NewDecoder(w.Body).Decode(&p); err != nil {
+		t.Fatalf("decode body: %v", err)
+	}
+	if want := "PUT is not supported by /api/products"; p.Detail != want {
+		t.Fatalf("detail = %q, want %q", p.Detail, want)
+	}
+}
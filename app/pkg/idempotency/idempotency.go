@@ -0,0 +1,183 @@
+package idempotency
+
+import (
+	"bytes"
+	"context"
+	"crypto/sha256"
+	"io"
+	"net/http"
+	"prod/pkg/apperror"
+	"prod/pkg/auth"
+	"prod/pkg/httperr"
+	"prod/pkg/logging"
+	"prod/pkg/route"
+	"time"
+)
+
+const (
+	Header         = "Idempotency-Key"
+	ReplayedHeader = "Idempotent-Replayed"
+	maxKeyLength   = 255
+)
+
+// storedHeaders are replayed with the response, the others belong to the transport of a single request.
+var storedHeaders = []string{"Content-Type", "Location", "ETag", "Last-Modified"}
+
+// Response is a stored response replayed for repeated requests.
+type Response struct {
+	Status int
+	Header http.Header
+	Body   []byte
+}
+
+// Lock is the claim of a key while its request is handled, concurrent requests with the same key are refused.
+type Lock interface {
+	// Save stores the response for the replays and releases the lock.
+	Save(ctx context.Context, res Response) error
+	// Release forgets the key, so that the request may be retried.
+	Release(ctx context.Context) error
+}
+
+// Store keeps the keys with the fingerprint of their request and its response.
+type Store interface {
+	// Acquire claims key for a new request. It returns the response of a completed request with the same key
+	// instead, apperror.ErrConflict while that request is in progress, or apperror.ErrUnprocessable when the key
+	// was used for a request with a different fingerprint.
+	Acquire(ctx context.Context, key string, fingerprint []byte, ttl time.Duration) (Lock, *Response, error)
+}
+
+type Middleware struct {
+	store  Store
+	ttl    time.Duration
+	routes *route.Table[bool]
+}
+
+// NewMiddleware covers the given "POST /path" routes, see route.Table for the patterns. Responses are stored
+// as they are, so routes whose responses carry secrets, like issued tokens or API keys, must not be listed.
+func NewMiddleware(store Store, ttl time.Duration, routes []string) (*Middleware, error) {
+	covered := make(map[string]bool, len(routes))
+	for _, name := range routes {
+		covered[name] = true
+	}
+	table, err := route.NewTable(false, covered)
+	if err != nil {
+		return nil, err
+	}
+
+	return &Middleware{
+		store:  store,
+		ttl:    ttl,
+		routes: table,
+	}, nil
+}
+
+// Handler makes requests to the covered routes with an Idempotency-Key safe to retry: the first response is
+// stored for ttl and replayed for repeats with the same key and payload. Keys are scoped to the identified
+// caller, anonymous requests are handled as usual. Server errors are not stored, the request may be retried
+// after them.
+func (m *Middleware) Handler(next http.Handler) http.Handler {
+	return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		key := r.Header.Get(Header)
+		principal, identified := auth.PrincipalFromContext(r.Context())
+		if _, covered := m.routes.Match(r.Method, r.URL.Path); !covered || key == "" || !identified {
+			next.ServeHTTP(w, r)
+			return
+		}
+		if len(key) > maxKeyLength {
+			httperr.Write(w, r, apperror.InvalidInput("%s must not be longer than %d characters", Header, maxKeyLength))
+			return
+		}
+
+		body, err := io.ReadAll(r.Body)
+		if err != nil {
+			httperr.Write(w, r, apperror.InvalidInput("failed to read request body"))
+			return
+		}
+		r.Body = io.NopCloser(bytes.NewReader(body))
+
+		lock, stored, err := m.store.Acquire(r.Context(), principal.Key()+"|"+key, fingerprint(r, body), m.ttl)
+		if err != nil {
+			httperr.Write(w, r, err)
+			return
+		}
+		if stored != nil {
+			replay(w, *stored)
+			return
+		}
+
+		rec := &recorder{ResponseWriter: w}
+		next.ServeHTTP(rec, r)
+
+		// the lock outlives a canceled request, it must be released either way
+		ctx := context.WithoutCancel(r.Context())
+		if rec.status >= http.StatusInternalServerError {
+			err = lock.Release(ctx)
+		} else {
+			err = lock.Save(ctx, rec.response())
+		}
+		if err != nil {
+			logging.GetLogger(ctx).WithError(err).Warningln("failed to store idempotent response")
+		}
+	})
+}
+
+// fingerprint identifies the request a key was used for, the same key with another payload is refused.
+func fingerprint(r *http.Request, body []byte) []byte {
+	h := sha256.New()
+	h.Write([]byte(r.Method + " " + r.URL.RequestURI() + "\n"))
+	h.Write(body)
+	return h.Sum(nil)
+}
+
+func replay(w http.ResponseWriter, res Response) {
+	for name, values := range res.Header {
+		for _, v := range values {
+			w.Header().Add(name, v)
+		}
+	}
+	w.Header().Set(ReplayedHeader, "true")
+	w.WriteHeader(res.Status)
+	_, _ = w.Write(res.Body)
+}
+
+// recorder passes the response through and keeps a copy for the replays.
+type recorder struct {
+	http.ResponseWriter
+	status int
+	body   bytes.Buffer
+}
+
+func (r *recorder) WriteHeader(status int) {
+	if r.status == 0 {
+		r.status = status
+	}
+	r.ResponseWriter.WriteHeader(status)
+}
+
+func (r *recorder) Write(b []byte) (int, error) {
+	if r.status == 0 {
+		r.status = http.StatusOK
+	}
+	r.body.Write(b)
+	return r.ResponseWriter.Write(b)
+}
+
+// Unwrap lets http.ResponseController reach the underlying writer.
+func (r *recorder) Unwrap() http.ResponseWriter {
+	return r.ResponseWriter
+}
+
+func (r *recorder) response() Response {
+	header := http.Header{}
+	for _, name := range storedHeaders {
+		if values := r.Header().Values(name); len(values) > 0 {
+			header[name] = values
+		}
+	}
+
+	status := r.status
+	if status == 0 {
+		status = http.StatusOK
+	}
+	return Response{Status: status, Header: header, Body: r.body.Bytes()}
+}
@@ -0,0 +1,213 @@
+package idempotency
+
+import (
+	"bytes"
+	"context"
+	"io"
+	"net/http"
+	"net/http/httptest"
+	"prod/pkg/apperror"
+	"prod/pkg/auth"
+	"strconv"
+	"strings"
+	"sync"
+	"sync/atomic"
+	"testing"
+	"time"
+)
+
+// fakeStore claims keys like PostgreSQLStore does, without leases.
+type fakeStore struct {
+	mu   sync.Mutex
+	keys map[string]*fakeEntry
+}
+
+type fakeEntry struct {
+	fingerprint []byte
+	res         *Response
+}
+
+func newFakeStore() *fakeStore {
+	return &fakeStore{keys: make(map[string]*fakeEntry)}
+}
+
+func (s *fakeStore) Acquire(ctx context.Context, key string, fingerprint []byte, ttl time.Duration) (Lock, *Response, error) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	e, ok := s.keys[key]
+	switch {
+	case !ok:
+		s.keys[key] = &fakeEntry{fingerprint: fingerprint}
+		return &fakeLock{store: s, key: key}, nil, nil
+	case !bytes.Equal(e.fingerprint, fingerprint):
+		return nil, nil, apperror.Unprocessable("%s has been used for a different request", Header)
+	case e.res == nil:
+		return nil, nil, apperror.Conflict(retryInProgress, "a request with the same %s is in progress", Header)
+	}
+	return nil, e.res, nil
+}
+
+type fakeLock struct {
+	store *fakeStore
+	key   string
+}
+
+func (l *fakeLock) Save(ctx context.Context, res Response) error {
+	l.store.mu.Lock()
+	defer l.store.mu.Unlock()
+	l.store.keys[l.key].res = &res
+	return nil
+}
+
+func (l *fakeLock) Release(ctx context.Context) error {
+	l.store.mu.Lock()
+	defer l.store.mu.Unlock()
+	delete(l.store.keys, l.key)
+	return nil
+}
+
+func newRequest(method string, target string, key string, body string, principal *auth.Principal) *http.Request {
+	r := httptest.NewRequest(method, target, strings.NewReader(body))
+	if key != "" {
+		r.Header.Set(Header, key)
+	}
+	if principal != nil {
+		r = r.WithContext(auth.ContextWithPrincipal(r.Context(), *principal))
+	}
+	return r
+}
+
+func newMiddleware(t *testing.T) *Middleware {
+	t.Helper()
+	m, err := NewMiddleware(newFakeStore(), time.Hour, []string{"POST /api/products"})
+	if err != nil {
+		t.Fatal(err)
+	}
+	return m
+}
+
+func TestMiddlewareHandler(t *testing.T) {
+	alice := &auth.Principal{Subject: "alice"}
+	bob := &auth.Principal{Subject: "bob"}
+
+	var calls atomic.Int32
+	handler := newMiddleware(t).Handler(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		n := calls.Add(1)
+		body, _ := io.ReadAll(r.Body)
+		if string(body) == "fail" {
+			w.WriteHeader(http.StatusInternalServerError)
+			return
+		}
+		w.Header().Set("Content-Type", "application/json")
+		w.Header().Set("ETag", `"1"`)
+		w.Header().Set("X-Trace", "not stored")
+		w.WriteHeader(http.StatusCreated)
+		_, _ = io.WriteString(w, `{"n":`+strconv.Itoa(int(n))+`}`)
+	}))
+
+	steps := []struct {
+		name         string
+		request      *http.Request
+		wantStatus   int
+		wantBody     string
+		wantReplayed bool
+		wantCalls    int32
+	}{
+		{name: "first", request: newRequest(http.MethodPost, "/api/products", "k1", `{"name":"a"}`, alice), wantStatus: http.StatusCreated, wantBody: `{"n":1}`, wantCalls: 1},
+		{name: "repeat is replayed", request: newRequest(http.MethodPost, "/api/products", "k1", `{"name":"a"}`, alice), wantStatus: http.StatusCreated, wantBody: `{"n":1}`, wantReplayed: true, wantCalls: 1},
+		{name: "other payload", request: newRequest(http.MethodPost, "/api/products", "k1", `{"name":"b"}`, alice), wantStatus: http.StatusUnprocessableEntity, wantCalls: 1},
+		{name: "keys are scoped to the caller", request: newRequest(http.MethodPost, "/api/products", "k1", `{"name":"a"}`, bob), wantStatus: http.StatusCreated, wantBody: `{"n":2}`, wantCalls: 2},
+		{name: "anonymous", request: newRequest(http.MethodPost, "/api/products", "k1", `{"name":"a"}`, nil), wantStatus: http.StatusCreated, wantBody: `{"n":3}`, wantCalls: 3},
+		{name: "without key", request: newRequest(http.MethodPost, "/api/products", "", `{"name":"a"}`, alice), wantStatus: http.StatusCreated, wantBody: `{"n":4}`, wantCalls: 4},
+		{name: "not a post", request: newRequest(http.MethodPut, "/api/products", "k1", `{"name":"a"}`, alice), wantStatus: http.StatusCreated, wantBody: `{"n":5}`, wantCalls: 5},
+		{name: "route not covered", request: newRequest(http.MethodPost, "/api/admin/api-keys", "k3", `{}`, alice), wantStatus: http.StatusCreated, wantBody: `{"n":6}`, wantCalls: 6},
+		{name: "route not covered is not replayed", request: newRequest(http.MethodPost, "/api/admin/api-keys", "k3", `{}`, alice), wantStatus: http.StatusCreated, wantBody: `{"n":7}`, wantCalls: 7},
+		{name: "key too long", request: newRequest(http.MethodPost, "/api/products", strings.Repeat("k", maxKeyLength+1), `{}`, alice), wantStatus: http.StatusBadRequest, wantCalls: 7},
+		{name: "server error", request: newRequest(http.MethodPost, "/api/products", "k2", "fail", alice), wantStatus: http.StatusInternalServerError, wantCalls: 8},
+		{name: "retry after a server error", request: newRequest(http.MethodPost, "/api/products", "k2", "fail", alice), wantStatus: http.StatusInternalServerError, wantCalls: 9},
+	}
+
+	for _, step := range steps {
+		w := httptest.NewRecorder()
+		handler.ServeHTTP(w, step.request)
+
+		if w.Code != step.wantStatus {
+			t.Fatalf("%s: status = %d, want %d", step.name, w.Code, step.wantStatus)
+		}
+		if step.wantBody != "" && w.Body.String() != step.wantBody {
+			t.Fatalf("%s: body = %q, want %q", step.name, w.Body.String(), step.wantBody)
+		}
+		if replayed := w.Header().Get(ReplayedHeader) == "true"; replayed != step.wantReplayed {
+			t.Fatalf("%s: replayed = %t, want %t", step.name, replayed, step.wantReplayed)
+		}
+		if step.wantReplayed {
+			if w.Header().Get("ETag") != `"1"` || w.Header().Get("Content-Type") != "application/json" || w.Header().Get("X-Trace") != "" {
+				t.Fatalf("%s: replayed headers = %v", step.name, w.Header())
+			}
+		}
+		if got := calls.Load(); got != step.wantCalls {
+			t.Fatalf("%s: handler calls = %d, want %d", step.name, got, step.wantCalls)
+		}
+	}
+}
+
+func TestMiddlewareConcurrentDuplicates(t *testing.T) {
+	principal := &auth.Principal{Subject: "alice"}
+	entered := make(chan struct{})
+	proceed := make(chan struct{})
+
+	var calls atomic.Int32
+	handler := newMiddleware(t).Handler(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		calls.Add(1)
+		close(entered)
+		<-proceed
+		w.WriteHeader(http.StatusCreated)
+		_, _ = io.WriteString(w, "created")
+	}))
+
+	first := httptest.NewRecorder()
+	done := make(chan struct{})
+	go func() {
+		defer close(done)
+		handler.ServeHTTP(first, newRequest(http.MethodPost, "/api/products", "k", "{}", principal))
+	}()
+	<-entered
+
+	const duplicates = 10
+	var wg sync.WaitGroup
+	recorders := make([]*httptest.ResponseRecorder, duplicates)
+	for i := range recorders {
+		recorders[i] = httptest.NewRecorder()
+		wg.Add(1)
+		go func(w *httptest.ResponseRecorder) {
+			defer wg.Done()
+			handler.ServeHTTP(w, newRequest(http.MethodPost, "/api/products", "k", "{}", principal))
+		}(recorders[i])
+	}
+	wg.Wait()
+
+	for i, w := range recorders {
+		if w.Code != http.StatusConflict {
+			t.Fatalf("duplicate %d: status = %d, want %d", i, w.Code, http.StatusConflict)
+		}
+		if got := w.Header().Get("Retry-After"); got != "1" {
+			t.Fatalf("duplicate %d: Retry-After = %q, want 1", i, got)
+		}
+	}
+
+	close(proceed)
+	<-done
+	if first.Code != http.StatusCreated {
+		t.Fatalf("first: status = %d, want %d", first.Code, http.StatusCreated)
+	}
+
+	w := httptest.NewRecorder()
+	handler.ServeHTTP(w, newRequest(http.MethodPost, "/api/products", "k", "{}", principal))
+	if w.Code != http.StatusCreated || w.Body.String() != "created" || w.Header().Get(ReplayedHeader) != "true" {
+		t.Fatalf("after completion: status = %d, body = %q, want the replay", w.Code, w.Body.String())
+	}
+	if got := calls.Load(); got != 1 {
+		t.Fatalf("handler calls = %d, want 1", got)
+	}
+}
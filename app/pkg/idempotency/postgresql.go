@@ -0,0 +1,160 @@
+package idempotency
+
+import (
+	"bytes"
+	"context"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"net/http"
+	"prod/pkg/apperror"
+	"prod/pkg/client/postgresql"
+	"prod/pkg/logging"
+	"time"
+)
+
+// claimQuery inserts the key of a new request, or takes over a key whose response or lease has expired.
+// It returns nothing when another request holds the key.
+const claimQuery = `
+INSERT INTO public.idempotency_key (key, fingerprint, expires_at)
+VALUES ($1, $2, now() + make_interval(secs => $3))
+ON CONFLICT (key) DO UPDATE
+SET fingerprint = EXCLUDED.fingerprint, status = NULL, header = NULL, body = NULL,
+    created_at = now(), expires_at = EXCLUDED.expires_at
+WHERE idempotency_key.expires_at < now()
+RETURNING created_at`
+
+const selectQuery = `
+SELECT fingerprint, status, header, body
+FROM public.idempotency_key
+WHERE key = $1`
+
+// saveQuery and releaseQuery only touch the claim they were given, created_at tells it apart from a later one
+// that has taken over the key after the lease.
+const saveQuery = `
+UPDATE public.idempotency_key
+SET status = $3, header = $4, body = $5, expires_at = now() + make_interval(secs => $6)
+WHERE key = $1 AND created_at = $2`
+
+const releaseQuery = `
+DELETE FROM public.idempotency_key
+WHERE key = $1 AND created_at = $2 AND status IS NULL`
+
+// claimAttempts bounds the retries of a claim whose key is released or deleted between the statements.
+const claimAttempts = 3
+
+// retryInProgress is suggested to the duplicates of a request in progress.
+const retryInProgress = time.Second
+
+// PostgreSQLStore keeps the keys in a shared table. A request claims its key with a short statement and
+// holds it for the lease, so no connection of the pool is held while the request is handled. Duplicates
+// on any instance are refused while the key is held and get the stored response after that.
+type PostgreSQLStore struct {
+	client postgresql.Client
+	lease  time.Duration
+}
+
+func NewPostgreSQLStore(client postgresql.Client, lease time.Duration) *PostgreSQLStore {
+	return &PostgreSQLStore{
+		client: client,
+		lease:  lease,
+	}
+}
+
+func (s *PostgreSQLStore) Acquire(ctx context.Context, key string, fingerprint []byte, ttl time.Duration) (Lock, *Response, error) {
+	for attempt := 0; attempt < claimAttempts; attempt++ {
+		var claimedAt time.Time
+		err := s.client.QueryRow(ctx, claimQuery, key, fingerprint, s.lease.Seconds()).Scan(&claimedAt)
+		if err == nil {
+			return &postgreSQLLock{client: s.client, key: key, claimedAt: claimedAt, ttl: ttl}, nil, nil
+		}
+		if err = postgresql.ClassifyError(err); !errors.Is(err, postgresql.ErrNotFound) {
+			return nil, nil, fmt.Errorf("failed to claim idempotency key: %w", err)
+		}
+
+		res, err := s.stored(ctx, key, fingerprint)
+		if errors.Is(err, postgresql.ErrNotFound) {
+			// the holder has released the key in the meantime
+			continue
+		}
+		return nil, res, err
+	}
+	return nil, nil, apperror.Conflict(retryInProgress, "a request with the same %s is in progress", Header)
+}
+
+// stored returns the response of the request holding key, or an error telling why there is none.
+func (s *PostgreSQLStore) stored(ctx context.Context, key string, fingerprint []byte) (*Response, error) {
+	var stored []byte
+	var status *int
+	var header []byte
+	var body []byte
+	err := s.client.QueryRow(ctx, selectQuery, key).Scan(&stored, &status, &header, &body)
+	if err != nil {
+		err = postgresql.ClassifyError(err)
+		if errors.Is(err, postgresql.ErrNotFound) {
+			return nil, err
+		}
+		return nil, fmt.Errorf("failed to read idempotency key: %w", err)
+	}
+
+	switch {
+	case !bytes.Equal(stored, fingerprint):
+		return nil, apperror.Unprocessable("%s has been used for a different request", Header)
+	case status == nil:
+		return nil, apperror.Conflict(retryInProgress, "a request with the same %s is in progress", Header)
+	}
+
+	res := &Response{Status: *status, Header: http.Header{}, Body: body}
+	if err = json.Unmarshal(header, &res.Header); err != nil {
+		return nil, fmt.Errorf("failed to decode stored headers: %w", err)
+	}
+	return res, nil
+}
+
+// Run deletes expired keys every interval until ctx is done.
+func (s *PostgreSQLStore) Run(ctx context.Context, interval time.Duration) error {
+	ticker := time.NewTicker(interval)
+	defer ticker.Stop()
+
+	for {
+		select {
+		case <-ctx.Done():
+			return nil
+		case <-ticker.C:
+			if _, err := s.client.Exec(ctx, "DELETE FROM public.idempotency_key WHERE expires_at < now()"); err != nil {
+				logging.GetLogger(ctx).WithError(err).Warningln("failed to delete idempotency keys")
+			}
+		}
+	}
+}
+
+type postgreSQLLock struct {
+	client    postgresql.Client
+	key       string
+	claimedAt time.Time
+	ttl       time.Duration
+}
+
+func (l *postgreSQLLock) Save(ctx context.Context, res Response) error {
+	header, err := json.Marshal(res.Header)
+	if err != nil {
+		_ = l.Release(ctx)
+		return fmt.Errorf("failed to encode headers: %w", err)
+	}
+
+	tag, err := l.client.Exec(ctx, saveQuery, l.key, l.claimedAt, res.Status, header, res.Body, l.ttl.Seconds())
+	if err != nil {
+		return fmt.Errorf("failed to save idempotent response: %w", postgresql.ClassifyError(err))
+	}
+	if tag.RowsAffected() == 0 {
+		return errors.New("failed to save idempotent response: the lease of the key has expired")
+	}
+	return nil
+}
+
+func (l *postgreSQLLock) Release(ctx context.Context) error {
+	if _, err := l.client.Exec(ctx, releaseQuery, l.key, l.claimedAt); err != nil {
+		return fmt.Errorf("failed to release idempotency key: %w", postgresql.ClassifyError(err))
+	}
+	return nil
+}
@@ -0,0 +1,150 @@
+package idempotency
+
+import (
+	"context"
+	"errors"
+	"github.com/jackc/pgx/v4/pgxpool"
+	"io/fs"
+	"net/http"
+	"os"
+	"prod/migrations"
+	"prod/pkg/apperror"
+	"sync"
+	"testing"
+	"time"
+)
+
+// testStore connects to TEST_POSTGRES_URL and creates the key table when the database has none.
+func testStore(t *testing.T, lease time.Duration) (*PostgreSQLStore, *pgxpool.Pool) {
+	t.Helper()
+	url := os.Getenv("TEST_POSTGRES_URL")
+	if url == "" {
+		t.Skip("TEST_POSTGRES_URL is not set")
+	}
+	ctx := context.Background()
+	pool, err := pgxpool.Connect(ctx, url)
+	if err != nil {
+		t.Fatal(err)
+	}
+	t.Cleanup(pool.Close)
+
+	var exists bool
+	if err = pool.QueryRow(ctx, "SELECT to_regclass('public.idempotency_key') IS NOT NULL").Scan(&exists); err != nil {
+		t.Fatal(err)
+	}
+	if !exists {
+		up, err := fs.ReadFile(migrations.FS, "00009_idempotency_key.up.sql")
+		if err != nil {
+			t.Fatal(err)
+		}
+		if _, err = pool.Exec(ctx, string(up)); err != nil {
+			t.Fatal(err)
+		}
+		t.Cleanup(func() { _, _ = pool.Exec(ctx, "DROP TABLE public.idempotency_key") })
+	}
+	return NewPostgreSQLStore(pool, lease), pool
+}
+
+func testKey(t *testing.T, pool *pgxpool.Pool) string {
+	key := "test|" + t.Name() + "|" + time.Now().Format(time.RFC3339Nano)
+	t.Cleanup(func() {
+		_, _ = pool.Exec(context.Background(), "DELETE FROM public.idempotency_key WHERE key = $1", key)
+	})
+	return key
+}
+
+func TestPostgreSQLStoreConcurrentAcquire(t *testing.T) {
+	store, pool := testStore(t, time.Minute)
+	ctx := context.Background()
+	key := testKey(t, pool)
+	fingerprint := []byte("request")
+
+	const requests = 10
+	var (
+		wg        sync.WaitGroup
+		mu        sync.Mutex
+		locks     []Lock
+		conflicts int
+	)
+	for i := 0; i < requests; i++ {
+		wg.Add(1)
+		go func() {
+			defer wg.Done()
+			lock, res, err := store.Acquire(ctx, key, fingerprint, time.Hour)
+			mu.Lock()
+			defer mu.Unlock()
+			switch {
+			case errors.Is(err, apperror.ErrConflict):
+				conflicts++
+			case err != nil || res != nil:
+				t.Errorf("Acquire() = %v, %v", res, err)
+			default:
+				locks = append(locks, lock)
+			}
+		}()
+	}
+	wg.Wait()
+
+	if len(locks) != 1 || conflicts != requests-1 {
+		t.Fatalf("claims = %d, conflicts = %d, want 1 and %d", len(locks), conflicts, requests-1)
+	}
+
+	saved := Response{Status: http.StatusCreated, Header: http.Header{"Etag": {`"1"`}}, Body: []byte("created")}
+	if err := locks[0].Save(ctx, saved); err != nil {
+		t.Fatalf("Save() error = %v", err)
+	}
+
+	lock, res, err := store.Acquire(ctx, key, fingerprint, time.Hour)
+	if err != nil || lock != nil || res == nil || res.Status != saved.Status || string(res.Body) != "created" || res.Header.Get("ETag") != `"1"` {
+		t.Fatalf("Acquire() after Save = %v, %+v, %v, want the stored response", lock, res, err)
+	}
+
+	if _, _, err = store.Acquire(ctx, key, []byte("other"), time.Hour); !errors.Is(err, apperror.ErrUnprocessable) {
+		t.Fatalf("Acquire() with another fingerprint error = %v, want ErrUnprocessable", err)
+	}
+}
+
+func TestPostgreSQLStoreRelease(t *testing.T) {
+	store, pool := testStore(t, time.Minute)
+	ctx := context.Background()
+	key := testKey(t, pool)
+
+	lock, _, err := store.Acquire(ctx, key, []byte("request"), time.Hour)
+	if err != nil || lock == nil {
+		t.Fatalf("Acquire() = %v, %v", lock, err)
+	}
+	if err = lock.Release(ctx); err != nil {
+		t.Fatalf("Release() error = %v", err)
+	}
+
+	if lock, _, err = store.Acquire(ctx, key, []byte("other"), time.Hour); err != nil || lock == nil {
+		t.Fatalf("Acquire() after Release = %v, %v, want a new claim", lock, err)
+	}
+}
+
+func TestPostgreSQLStoreExpiredLease(t *testing.T) {
+	store, pool := testStore(t, 50*time.Millisecond)
+	ctx := context.Background()
+	key := testKey(t, pool)
+
+	stale, _, err := store.Acquire(ctx, key, []byte("request"), time.Hour)
+	if err != nil || stale == nil {
+		t.Fatalf("Acquire() = %v, %v", stale, err)
+	}
+	time.Sleep(100 * time.Millisecond)
+
+	fresh, _, err := store.Acquire(ctx, key, []byte("request"), time.Hour)
+	if err != nil || fresh == nil {
+		t.Fatalf("Acquire() after the lease = %v, %v, want a new claim", fresh, err)
+	}
+
+	if err = stale.Save(ctx, Response{Status: http.StatusCreated}); err == nil {
+		t.Fatal("Save() of an expired claim succeeded")
+	}
+	if err = stale.Release(ctx); err != nil {
+		t.Fatalf("Release() of an expired claim error = %v", err)
+	}
+	if _, _, err = store.Acquire(ctx, key, []byte("request"), time.Hour); !errors.Is(err, apperror.ErrConflict) {
+		t.Fatalf("Acquire() error = %v, want the fresh claim to be kept", err)
+	}
+}
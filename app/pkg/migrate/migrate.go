@@ -0,0 +1,326 @@
+package migrate
+
+import (
+	"context"
+	"crypto/sha256"
+	"encoding/hex"
+	"errors"
+	"fmt"
+	"github.com/jackc/pgx/v4"
+	"github.com/jackc/pgx/v4/pgxpool"
+	"io/fs"
+	"prod/pkg/logging"
+	"regexp"
+	"sort"
+	"strconv"
+	"time"
+)
+
+const (
+	DefaultTable = "public.schema_migrations"
+
+	// lockID is the key of the advisory lock held while migrations are checked or applied.
+	lockID = 7245010431
+)
+
+var (
+	ErrSchemaBehind     = errors.New("database schema is behind the application")
+	ErrChecksumMismatch = errors.New("applied migration checksum mismatch")
+	ErrNoDownMigration  = errors.New("down migration is missing")
+	ErrOutOfOrder       = errors.New("pending migration is older than an applied one")
+)
+
+var fileNameRegexp = regexp.MustCompile(`^(\d+)_(\w+)\.(up|down)\.sql$`)
+
+type Migration struct {
+	Version  int64
+	Name     string
+	Up       string
+	Down     string
+	Checksum string
+}
+
+type Status struct {
+	Version   int64
+	Name      string
+	AppliedAt *time.Time
+	// Missing is set for versions applied to the database but unknown to the application.
+	Missing bool
+}
+
+type applied struct {
+	Name      string
+	Checksum  string
+	AppliedAt time.Time
+}
+
+type Migrator struct {
+	pool       *pgxpool.Pool
+	table      string
+	migrations []Migration
+}
+
+// Load reads migrations named "<version>_<name>.up.sql" with an optional "<version>_<name>.down.sql" pair.
+func Load(fsys fs.FS) ([]Migration, error) {
+	entries, err := fs.ReadDir(fsys, ".")
+	if err != nil {
+		return nil, fmt.Errorf("failed to read migrations: %w", err)
+	}
+
+	byVersion := make(map[int64]*Migration)
+	hasUp := make(map[int64]bool)
+	for _, entry := range entries {
+		if entry.IsDir() {
+			continue
+		}
+		matches := fileNameRegexp.FindStringSubmatch(entry.Name())
+		if matches == nil {
+			continue
+		}
+
+		version, err := strconv.ParseInt(matches[1], 10, 64)
+		if err != nil {
+			return nil, fmt.Errorf("invalid migration version %s: %w", entry.Name(), err)
+		}
+
+		body, err := fs.ReadFile(fsys, entry.Name())
+		if err != nil {
+			return nil, fmt.Errorf("failed to read migration %s: %w", entry.Name(), err)
+		}
+
+		m, ok := byVersion[version]
+		if !ok {
+			m = &Migration{Version: version, Name: matches[2]}
+			byVersion[version] = m
+		}
+		if m.Name != matches[2] {
+			return nil, fmt.Errorf("migration version %d is used by %s and %s", version, m.Name, matches[2])
+		}
+
+		if matches[3] == "up" {
+			hasUp[version] = true
+			m.Up = string(body)
+			sum := sha256.Sum256(body)
+			m.Checksum = hex.EncodeToString(sum[:])
+		} else {
+			m.Down = string(body)
+		}
+	}
+
+	list := make([]Migration, 0, len(byVersion))
+	for _, m := range byVersion {
+		if !hasUp[m.Version] {
+			return nil, fmt.Errorf("up migration is missing for version %d", m.Version)
+		}
+		list = append(list, *m)
+	}
+	sort.Slice(list, func(i, j int) bool {
+		return list[i].Version < list[j].Version
+	})
+
+	return list, nil
+}
+
+func NewMigrator(pool *pgxpool.Pool, fsys fs.FS) (*Migrator, error) {
+	migrations, err := Load(fsys)
+	if err != nil {
+		return nil, err
+	}
+
+	return &Migrator{
+		pool:       pool,
+		table:      DefaultTable,
+		migrations: migrations,
+	}, nil
+}
+
+func (m *Migrator) Migrations() []Migration {
+	return m.migrations
+}
+
+// Up applies all pending migrations, each one in its own transaction. It refuses to apply a migration
+// older than the latest applied one.
+func (m *Migrator) Up(ctx context.Context) ([]Migration, error) {
+	done := make([]Migration, 0)
+
+	err := m.withLock(ctx, func(conn *pgxpool.Conn, applied map[int64]applied) error {
+		if err := m.verify(applied); err != nil {
+			return err
+		}
+
+		for _, migration := range m.migrations {
+			if _, ok := applied[migration.Version]; ok {
+				continue
+			}
+
+			logging.GetLogger(ctx).Infof("applying migration %d_%s", migration.Version, migration.Name)
+			err := conn.BeginFunc(ctx, func(tx pgx.Tx) error {
+				if _, err := tx.Exec(ctx, migration.Up); err != nil {
+					return err
+				}
+				_, err := tx.Exec(ctx,
+					"INSERT INTO "+m.table+" (version, name, checksum) VALUES ($1, $2, $3)",
+					migration.Version, migration.Name, migration.Checksum,
+				)
+				return err
+			})
+			if err != nil {
+				return fmt.Errorf("failed to apply migration %d_%s: %w", migration.Version, migration.Name, err)
+			}
+			done = append(done, migration)
+		}
+
+		return nil
+	})
+
+	return done, err
+}
+
+// Down reverts the given number of the most recently applied migrations.
+func (m *Migrator) Down(ctx context.Context, steps int) ([]Migration, error) {
+	done := make([]Migration, 0)
+
+	err := m.withLock(ctx, func(conn *pgxpool.Conn, applied map[int64]applied) error {
+		for i := len(m.migrations) - 1; i >= 0 && len(done) < steps; i-- {
+			migration := m.migrations[i]
+			if _, ok := applied[migration.Version]; !ok {
+				continue
+			}
+			if migration.Down == "" {
+				return fmt.Errorf("%w: %d_%s", ErrNoDownMigration, migration.Version, migration.Name)
+			}
+
+			logging.GetLogger(ctx).Infof("reverting migration %d_%s", migration.Version, migration.Name)
+			err := conn.BeginFunc(ctx, func(tx pgx.Tx) error {
+				if _, err := tx.Exec(ctx, migration.Down); err != nil {
+					return err
+				}
+				_, err := tx.Exec(ctx, "DELETE FROM "+m.table+" WHERE version = $1", migration.Version)
+				return err
+			})
+			if err != nil {
+				return fmt.Errorf("failed to revert migration %d_%s: %w", migration.Version, migration.Name, err)
+			}
+			done = append(done, migration)
+		}
+
+		return nil
+	})
+
+	return done, err
+}
+
+func (m *Migrator) Status(ctx context.Context) ([]Status, error) {
+	list := make([]Status, 0, len(m.migrations))
+
+	err := m.withLock(ctx, func(_ *pgxpool.Conn, applied map[int64]applied) error {
+		known := make(map[int64]bool, len(m.migrations))
+		for _, migration := range m.migrations {
+			known[migration.Version] = true
+			s := Status{Version: migration.Version, Name: migration.Name}
+			if a, ok := applied[migration.Version]; ok {
+				s.AppliedAt = &a.AppliedAt
+			}
+			list = append(list, s)
+		}
+		for version, a := range applied {
+			if !known[version] {
+				appliedAt := a.AppliedAt
+				list = append(list, Status{Version: version, Name: a.Name, AppliedAt: &appliedAt, Missing: true})
+			}
+		}
+		return nil
+	})
+
+	sort.Slice(list, func(i, j int) bool {
+		return list[i].Version < list[j].Version
+	})
+
+	return list, err
+}
+
+// Check returns ErrSchemaBehind if any migration is pending, ErrChecksumMismatch if an applied one was changed
+// and ErrOutOfOrder if a pending one is older than an applied one.
+func (m *Migrator) Check(ctx context.Context) error {
+	return m.withLock(ctx, func(_ *pgxpool.Conn, applied map[int64]applied) error {
+		if err := m.verify(applied); err != nil {
+			return err
+		}
+		for _, migration := range m.migrations {
+			if _, ok := applied[migration.Version]; !ok {
+				return fmt.Errorf("%w: migration %d_%s is not applied", ErrSchemaBehind, migration.Version, migration.Name)
+			}
+		}
+		return nil
+	})
+}
+
+// verify rejects changed migrations and pending ones with a version below the latest applied one,
+// which would otherwise run after the migrations that were meant to follow them.
+func (m *Migrator) verify(applied map[int64]applied) error {
+	var latest int64
+	for version := range applied {
+		latest = max(latest, version)
+	}
+
+	for _, migration := range m.migrations {
+		a, ok := applied[migration.Version]
+		switch {
+		case ok && a.Checksum != migration.Checksum:
+			return fmt.Errorf("%w: %d_%s", ErrChecksumMismatch, migration.Version, migration.Name)
+		case !ok && migration.Version < latest:
+			return fmt.Errorf("%w: %d_%s is pending, %d is applied", ErrOutOfOrder, migration.Version, migration.Name, latest)
+		}
+	}
+	return nil
+}
+
+// withLock runs fn on a single connection holding the migration advisory lock,
+// so that several instances starting at once don't apply the same migrations.
+func (m *Migrator) withLock(ctx context.Context, fn func(conn *pgxpool.Conn, applied map[int64]applied) error) error {
+	conn, err := m.pool.Acquire(ctx)
+	if err != nil {
+		return fmt.Errorf("failed to acquire connection: %w", err)
+	}
+	defer conn.Release()
+
+	if _, err = conn.Exec(ctx, "SELECT pg_advisory_lock($1)", lockID); err != nil {
+		return fmt.Errorf("failed to take migration lock: %w", err)
+	}
+	defer func() {
+		if _, err := conn.Exec(context.WithoutCancel(ctx), "SELECT pg_advisory_unlock($1)", lockID); err != nil {
+			logging.GetLogger(ctx).WithError(err).Errorln("failed to release migration lock")
+		}
+	}()
+
+	_, err = conn.Exec(ctx, `CREATE TABLE IF NOT EXISTS `+m.table+` (
+		version BIGINT PRIMARY KEY,
+		name TEXT NOT NULL,
+		checksum TEXT NOT NULL,
+		applied_at TIMESTAMPTZ NOT NULL DEFAULT now()
+	)`)
+	if err != nil {
+		return fmt.Errorf("failed to create migrations table: %w", err)
+	}
+
+	rows, err := conn.Query(ctx, "SELECT version, name, checksum, applied_at FROM "+m.table)
+	if err != nil {
+		return fmt.Errorf("failed to read applied migrations: %w", err)
+	}
+	list := make(map[int64]applied)
+	for rows.Next() {
+		var version int64
+		a := applied{}
+		if err = rows.Scan(&version, &a.Name, &a.Checksum, &a.AppliedAt); err != nil {
+			rows.Close()
+			return fmt.Errorf("failed to read applied migrations: %w", err)
+		}
+		list[version] = a
+	}
+	rows.Close()
+	if err = rows.Err(); err != nil {
+		return fmt.Errorf("failed to read applied migrations: %w", err)
+	}
+
+	return fn(conn, list)
+}
@@ -0,0 +1,208 @@
+package migrate
+
+import (
+	"context"
+	"crypto/sha256"
+	"encoding/hex"
+	"errors"
+	"github.com/jackc/pgx/v4/pgxpool"
+	"os"
+	"strings"
+	"sync"
+	"testing"
+	"testing/fstest"
+)
+
+func checksum(body string) string {
+	sum := sha256.Sum256([]byte(body))
+	return hex.EncodeToString(sum[:])
+}
+
+func TestLoad(t *testing.T) {
+	tests := []struct {
+		name    string
+		fsys    fstest.MapFS
+		want    []Migration
+		wantErr string
+	}{
+		{
+			name: "sorted by version with optional down",
+			fsys: fstest.MapFS{
+				"00002_b.up.sql":   {Data: []byte("B")},
+				"00001_a.up.sql":   {Data: []byte("A")},
+				"00001_a.down.sql": {Data: []byte("-A")},
+				"README.md":        {Data: []byte("ignored")},
+				"00003_c.sql":      {Data: []byte("ignored")},
+			},
+			want: []Migration{
+				{Version: 1, Name: "a", Up: "A", Down: "-A", Checksum: checksum("A")},
+				{Version: 2, Name: "b", Up: "B", Checksum: checksum("B")},
+			},
+		},
+		{
+			name: "empty up migration is allowed",
+			fsys: fstest.MapFS{
+				"00001_noop.up.sql": {Data: []byte("")},
+			},
+			want: []Migration{
+				{Version: 1, Name: "noop", Checksum: checksum("")},
+			},
+		},
+		{
+			name: "missing up",
+			fsys: fstest.MapFS{
+				"00001_a.down.sql": {Data: []byte("-A")},
+			},
+			wantErr: "up migration is missing for version 1",
+		},
+		{
+			name: "version used twice",
+			fsys: fstest.MapFS{
+				"00001_a.up.sql": {Data: []byte("A")},
+				"00001_b.up.sql": {Data: []byte("B")},
+			},
+			wantErr: "migration version 1 is used by a and b",
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			got, err := Load(tt.fsys)
+			if tt.wantErr != "" {
+				if err == nil || !strings.Contains(err.Error(), tt.wantErr) {
+					t.Fatalf("err = %v, want %q", err, tt.wantErr)
+				}
+				return
+			}
+			if err != nil {
+				t.Fatal(err)
+			}
+			if len(got) != len(tt.want) {
+				t.Fatalf("got %d migrations, want %d", len(got), len(tt.want))
+			}
+			for i := range got {
+				if got[i] != tt.want[i] {
+					t.Errorf("migration %d = %+v, want %+v", i, got[i], tt.want[i])
+				}
+			}
+		})
+	}
+}
+
+func TestVerify(t *testing.T) {
+	m := &Migrator{migrations: []Migration{
+		{Version: 1, Name: "a", Checksum: checksum("A")},
+		{Version: 2, Name: "b", Checksum: checksum("B")},
+	}}
+
+	tests := []struct {
+		name    string
+		applied map[int64]applied
+		wantErr error
+	}{
+		{name: "nothing applied", applied: map[int64]applied{}},
+		{name: "matching", applied: map[int64]applied{1: {Name: "a", Checksum: checksum("A")}}},
+		{name: "unknown version", applied: map[int64]applied{
+			1: {Name: "a", Checksum: checksum("A")},
+			2: {Name: "b", Checksum: checksum("B")},
+			9: {Name: "z", Checksum: "x"},
+		}},
+		{name: "changed", applied: map[int64]applied{
+			1: {Name: "a", Checksum: checksum("A")},
+			2: {Name: "b", Checksum: checksum("B2")},
+		}, wantErr: ErrChecksumMismatch},
+		{name: "pending below applied", applied: map[int64]applied{2: {Name: "b", Checksum: checksum("B")}}, wantErr: ErrOutOfOrder},
+		{name: "pending below unknown", applied: map[int64]applied{9: {Name: "z", Checksum: "x"}}, wantErr: ErrOutOfOrder},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			if err := m.verify(tt.applied); !errors.Is(err, tt.wantErr) {
+				t.Errorf("verify() = %v, want %v", err, tt.wantErr)
+			}
+		})
+	}
+}
+
+// testPool connects to the database from TEST_POSTGRES_URL, the test is skipped when it is not set.
+func testPool(t *testing.T) *pgxpool.Pool {
+	t.Helper()
+	url := os.Getenv("TEST_POSTGRES_URL")
+	if url == "" {
+		t.Skip("TEST_POSTGRES_URL is not set")
+	}
+	pool, err := pgxpool.Connect(context.Background(), url)
+	if err != nil {
+		t.Fatal(err)
+	}
+	t.Cleanup(pool.Close)
+	return pool
+}
+
+func TestMigratorConcurrentUp(t *testing.T) {
+	pool := testPool(t)
+	ctx := context.Background()
+
+	const table = "public.schema_migrations_test"
+	cleanup := func() {
+		_, _ = pool.Exec(ctx, "DROP TABLE IF EXISTS migrate_test_counter, "+table)
+	}
+	cleanup()
+	t.Cleanup(cleanup)
+
+	fsys := fstest.MapFS{
+		"00001_counter.up.sql": {Data: []byte("CREATE TABLE migrate_test_counter (n INT)")},
+		"00002_insert.up.sql":  {Data: []byte("INSERT INTO migrate_test_counter VALUES (1)")},
+	}
+
+	const instances = 5
+	var (
+		wg    sync.WaitGroup
+		mu    sync.Mutex
+		total int
+	)
+	for i := 0; i < instances; i++ {
+		wg.Add(1)
+		go func() {
+			defer wg.Done()
+			m, err := NewMigrator(pool, fsys)
+			if err != nil {
+				t.Error(err)
+				return
+			}
+			m.table = table
+			done, err := m.Up(ctx)
+			if err != nil {
+				t.Error(err)
+				return
+			}
+			mu.Lock()
+			total += len(done)
+			mu.Unlock()
+		}()
+	}
+	wg.Wait()
+
+	if total != len(fsys) {
+		t.Errorf("applied %d migrations in total, want %d", total, len(fsys))
+	}
+	var rows int
+	if err := pool.QueryRow(ctx, "SELECT count(*) FROM migrate_test_counter").Scan(&rows); err != nil {
+		t.Fatal(err)
+	}
+	if rows != 1 {
+		t.Errorf("insert migration ran %d times, want 1", rows)
+	}
+
+	m, err := NewMigrator(pool, fstest.MapFS{
+		"00001_counter.up.sql": {Data: []byte("CREATE TABLE migrate_test_counter (n BIGINT)")},
+		"00002_insert.up.sql":  fsys["00002_insert.up.sql"],
+	})
+	if err != nil {
+		t.Fatal(err)
+	}
+	m.table = table
+	if err = m.Check(ctx); !errors.Is(err, ErrChecksumMismatch) {
+		t.Errorf("Check() after editing an applied migration = %v, want %v", err, ErrChecksumMismatch)
+	}
+}
@@ -0,0 +1,46 @@
+package password
+
+import (
+	"errors"
+	"testing"
+)
+
+func TestHashAndCompare(t *testing.T) {
+	hash, err := Hash("correct horse")
+	if err != nil {
+		t.Fatalf("Hash() error = %v", err)
+	}
+	if hash == "correct horse" {
+		t.Fatal("Hash() returned the password")
+	}
+
+	other, err := Hash("correct horse")
+	if err != nil {
+		t.Fatalf("Hash() error = %v", err)
+	}
+	if other == hash {
+		t.Fatal("Hash() is not salted")
+	}
+
+	tests := []struct {
+		name     string
+		hash     string
+		password string
+		wantErr  error
+	}{
+		{name: "match", hash: hash, password: "correct horse"},
+		{name: "mismatch", hash: hash, password: "wrong horse", wantErr: ErrMismatch},
+		{name: "empty", hash: hash, password: "", wantErr: ErrMismatch},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			if err := Compare(tt.hash, tt.password); !errors.Is(err, tt.wantErr) {
+				t.Fatalf("Compare() error = %v, want %v", err, tt.wantErr)
+			}
+		})
+	}
+
+	if err := Compare("not a hash", "correct horse"); err == nil || errors.Is(err, ErrMismatch) {
+		t.Fatalf("Compare() of a malformed hash error = %v, want a distinct error", err)
+	}
+}
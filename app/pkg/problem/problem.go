@@ -0,0 +1,167 @@
+package problem
+
+import (
+	"context"
+	"errors"
+	"google.golang.org/grpc/codes"
+	"net/http"
+	"prod/pkg/apperror"
+	"prod/pkg/client/postgresql"
+	"regexp"
+	"strings"
+	"time"
+)
+
+// TypePrefix starts the problem type URIs, e.g. "urn:go-prod:problem:not-found".
+const TypePrefix = "urn:go-prod:problem:"
+
+// Kind is a class of errors with the same meaning for HTTP and gRPC clients.
+type Kind struct {
+	Slug   string
+	Title  string
+	Status int
+	Code   codes.Code
+	// detail is shown for errors that carry no client-safe message themselves.
+	detail string
+}
+
+func (k Kind) Type() string {
+	return TypePrefix + k.Slug
+}
+
+var (
+	InvalidInput         = Kind{"invalid-input", "Invalid input", http.StatusBadRequest, codes.InvalidArgument, "the input is malformed"}
+	Unauthorized         = Kind{"unauthorized", "Unauthorized", http.StatusUnauthorized, codes.Unauthenticated, "authentication is required"}
+	Forbidden            = Kind{"forbidden", "Forbidden", http.StatusForbidden, codes.PermissionDenied, "the operation is not permitted"}
+	NotFound             = Kind{"not-found", "Not found", http.StatusNotFound, codes.NotFound, "the resource does not exist"}
+	MethodNotAllowed     = Kind{"method-not-allowed", "Method not allowed", http.StatusMethodNotAllowed, codes.Unimplemented, "the method is not supported"}
+	Conflict             = Kind{"conflict", "Conflict", http.StatusConflict, codes.Aborted, "a concurrent request holds the resource"}
+	AlreadyExists        = Kind{"already-exists", "Already exists", http.StatusConflict, codes.AlreadyExists, "a resource with the same unique values already exists"}
+	ReferenceConflict    = Kind{"reference-conflict", "Reference conflict", http.StatusConflict, codes.FailedPrecondition, "the resource references a missing one or is still referenced"}
+	ConstraintViolation  = Kind{"constraint-violation", "Constraint violation", http.StatusUnprocessableEntity, codes.InvalidArgument, "the data violates a constraint"}
+	PreconditionFailed   = Kind{"precondition-failed", "Precondition failed", http.StatusPreconditionFailed, codes.Aborted, "the resource has changed since it was read"}
+	PreconditionRequired = Kind{"precondition-required", "Precondition required", http.StatusPreconditionRequired, codes.FailedPrecondition, "the request must be conditional"}
+	Unprocessable        = Kind{"unprocessable", "Unprocessable content", http.StatusUnprocessableEntity, codes.FailedPrecondition, "the request cannot be processed"}
+	RateLimited          = Kind{"rate-limited", "Too many requests", http.StatusTooManyRequests, codes.ResourceExhausted, "the rate limit is exceeded"}
+	Retry                = Kind{"concurrent-update", "Concurrent update", http.StatusServiceUnavailable, codes.Aborted, "the request collided with a concurrent one, retry it"}
+	Unavailable          = Kind{"unavailable", "Service unavailable", http.StatusServiceUnavailable, codes.Unavailable, "the service is temporarily unavailable"}
+	Timeout              = Kind{"timeout", "Timeout", http.StatusGatewayTimeout, codes.DeadlineExceeded, "the request did not complete in time"}
+	Internal             = Kind{"internal", "Internal server error", http.StatusInternalServerError, codes.Internal, "internal error"}
+)
+
+// KindOf classifies domain errors and classified database errors, everything else is Internal.
+func KindOf(err error) Kind {
+	switch {
+	case errors.Is(err, apperror.ErrInvalidInput),
+		errors.Is(err, postgresql.ErrInvalidTextRepresentation):
+		return InvalidInput
+	case errors.Is(err, apperror.ErrUnauthorized):
+		return Unauthorized
+	case errors.Is(err, apperror.ErrForbidden):
+		return Forbidden
+	case errors.Is(err, apperror.ErrRateLimited):
+		return RateLimited
+	case errors.Is(err, apperror.ErrMethodNotAllowed):
+		return MethodNotAllowed
+	case errors.Is(err, apperror.ErrPreconditionFailed):
+		return PreconditionFailed
+	case errors.Is(err, apperror.ErrPreconditionRequired):
+		return PreconditionRequired
+	case errors.Is(err, apperror.ErrUnprocessable):
+		return Unprocessable
+	case errors.Is(err, apperror.ErrConflict):
+		return Conflict
+	case errors.Is(err, apperror.ErrNotFound),
+		errors.Is(err, postgresql.ErrNotFound):
+		return NotFound
+	case errors.Is(err, postgresql.ErrUniqueViolation):
+		return AlreadyExists
+	case errors.Is(err, postgresql.ErrForeignKeyViolation):
+		return ReferenceConflict
+	case errors.Is(err, postgresql.ErrNotNullViolation),
+		errors.Is(err, postgresql.ErrCheckViolation):
+		return ConstraintViolation
+	case errors.Is(err, postgresql.ErrSerializationFailure),
+		errors.Is(err, postgresql.ErrDeadlock):
+		return Retry
+	case errors.Is(err, postgresql.ErrQueryCanceled):
+		return Unavailable
+	case errors.Is(err, context.DeadlineExceeded):
+		return Timeout
+	default:
+		return Internal
+	}
+}
+
+// Detail returns a message safe to show to the client, database internals are never included.
+func Detail(err error, kind Kind) string {
+	if kind == Internal {
+		return kind.detail
+	}
+
+	var appErr *apperror.Error
+	if errors.As(err, &appErr) && appErr.Message != "" {
+		return appErr.Message
+	}
+	var pgErr *postgresql.Error
+	if errors.As(err, &pgErr) && errors.Is(err, postgresql.ErrInvalidTextRepresentation) {
+		// e.g. invalid input syntax for type uuid: "abc"
+		return pgErr.Message
+	}
+	return kind.detail
+}
+
+// keyRegexp extracts the columns from details like "Key (email)=(a@b.c) already exists.".
+var keyRegexp = regexp.MustCompile(`^Key \(([^)]+)\)=`)
+
+// Fields returns field-level problems of domain errors and of database constraint violations.
+func Fields(err error) []apperror.FieldError {
+	var appErr *apperror.Error
+	if errors.As(err, &appErr) {
+		return appErr.Fields
+	}
+
+	var pgErr *postgresql.Error
+	if !errors.As(err, &pgErr) {
+		return nil
+	}
+
+	switch {
+	case errors.Is(err, postgresql.ErrNotNullViolation) && pgErr.Column != "":
+		return []apperror.FieldError{{Field: pgErr.Column, Message: "is required"}}
+	case errors.Is(err, postgresql.ErrCheckViolation) && pgErr.Constraint != "":
+		// constraints are named <table>_<column>_check
+		column := strings.TrimSuffix(strings.TrimPrefix(pgErr.Constraint, pgErr.Table+"_"), "_check")
+		return []apperror.FieldError{{Field: column, Message: "is out of the allowed range"}}
+	case errors.Is(err, postgresql.ErrUniqueViolation):
+		return keyFields(pgErr.Detail, "is already taken")
+	case errors.Is(err, postgresql.ErrForeignKeyViolation) && strings.Contains(pgErr.Detail, "is not present"):
+		return keyFields(pgErr.Detail, "references a missing resource")
+	}
+	return nil
+}
+
+func keyFields(detail string, message string) []apperror.FieldError {
+	matches := keyRegexp.FindStringSubmatch(detail)
+	if matches == nil {
+		return nil
+	}
+
+	fields := make([]apperror.FieldError, 0)
+	for _, column := range strings.Split(matches[1], ",") {
+		fields = append(fields, apperror.FieldError{Field: strings.TrimSpace(column), Message: message})
+	}
+	return fields
+}
+
+// RetryAfter tells when a failed request may be repeated, zero means it should not be repeated as is.
+func RetryAfter(err error, kind Kind) time.Duration {
+	var appErr *apperror.Error
+	if errors.As(err, &appErr) && appErr.RetryAfter > 0 {
+		return appErr.RetryAfter
+	}
+	if kind == Retry || kind == Unavailable {
+		return time.Second
+	}
+	return 0
+}
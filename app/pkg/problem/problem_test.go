@@ -0,0 +1,161 @@
+package problem
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"github.com/jackc/pgconn"
+	"prod/pkg/apperror"
+	"prod/pkg/client/postgresql"
+	"reflect"
+	"testing"
+	"time"
+)
+
+func pgError(code string) error {
+	return postgresql.ClassifyError(&pgconn.PgError{Code: code, Message: "secret database message"})
+}
+
+func TestKindOf(t *testing.T) {
+	tests := []struct {
+		name string
+		err  error
+		want Kind
+	}{
+		{name: "invalid input", err: apperror.InvalidInput("bad"), want: InvalidInput},
+		{name: "invalid text representation", err: pgError("22P02"), want: InvalidInput},
+		{name: "unauthorized", err: apperror.Unauthorized("no token"), want: Unauthorized},
+		{name: "forbidden", err: apperror.Forbidden("no role"), want: Forbidden},
+		{name: "rate limited", err: apperror.RateLimited(time.Second, "slow down"), want: RateLimited},
+		{name: "method not allowed", err: apperror.MethodNotAllowed("no"), want: MethodNotAllowed},
+		{name: "precondition failed", err: apperror.PreconditionFailed("stale"), want: PreconditionFailed},
+		{name: "precondition required", err: apperror.PreconditionRequired("no If-Match"), want: PreconditionRequired},
+		{name: "unprocessable", err: apperror.Unprocessable("no"), want: Unprocessable},
+		{name: "conflict", err: apperror.Conflict(time.Second, "in progress"), want: Conflict},
+		{name: "not found", err: apperror.NotFound("no product"), want: NotFound},
+		{name: "no rows", err: postgresql.ErrNotFound, want: NotFound},
+		{name: "unique", err: pgError("23505"), want: AlreadyExists},
+		{name: "foreign key", err: pgError("23503"), want: ReferenceConflict},
+		{name: "not null", err: pgError("23502"), want: ConstraintViolation},
+		{name: "check", err: pgError("23514"), want: ConstraintViolation},
+		{name: "serialization", err: pgError("40001"), want: Retry},
+		{name: "deadlock", err: pgError("40P01"), want: Retry},
+		{name: "query canceled", err: pgError("57014"), want: Unavailable},
+		{name: "deadline", err: context.DeadlineExceeded, want: Timeout},
+		{name: "wrapped", err: fmt.Errorf("create product: %w", apperror.NotFound("no category")), want: NotFound},
+		{name: "unknown", err: errors.New("boom"), want: Internal},
+		{name: "unknown sqlstate", err: pgError("XX000"), want: Internal},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			if got := KindOf(tt.err); got != tt.want {
+				t.Fatalf("KindOf() = %s, want %s", got.Slug, tt.want.Slug)
+			}
+		})
+	}
+}
+
+func TestDetail(t *testing.T) {
+	tests := []struct {
+		name string
+		err  error
+		want string
+	}{
+		{name: "domain message", err: apperror.NotFound("product %d not found", 1), want: "product 1 not found"},
+		{name: "domain without message", err: apperror.ErrNotFound, want: NotFound.detail},
+		{name: "database error is hidden", err: pgError("23505"), want: AlreadyExists.detail},
+		{name: "invalid text representation is shown", err: pgError("22P02"), want: "secret database message"},
+		{name: "internal is hidden", err: errors.New("password=secret"), want: Internal.detail},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			if got := Detail(tt.err, KindOf(tt.err)); got != tt.want {
+				t.Fatalf("Detail() = %q, want %q", got, tt.want)
+			}
+		})
+	}
+}
+
+func TestFields(t *testing.T) {
+	tests := []struct {
+		name string
+		err  error
+		want []apperror.FieldError
+	}{
+		{
+			name: "domain fields",
+			err:  apperror.Invalid(apperror.Field("name", "is required")),
+			want: []apperror.FieldError{{Field: "name", Message: "is required"}},
+		},
+		{
+			name: "not null",
+			err:  postgresql.ClassifyError(&pgconn.PgError{Code: "23502", ColumnName: "price"}),
+			want: []apperror.FieldError{{Field: "price", Message: "is required"}},
+		},
+		{
+			name: "check",
+			err:  postgresql.ClassifyError(&pgconn.PgError{Code: "23514", TableName: "product", ConstraintName: "product_rating_check"}),
+			want: []apperror.FieldError{{Field: "rating", Message: "is out of the allowed range"}},
+		},
+		{
+			name: "unique on several columns",
+			err:  postgresql.ClassifyError(&pgconn.PgError{Code: "23505", Detail: "Key (name, category_id)=(phone, 1) already exists."}),
+			want: []apperror.FieldError{{Field: "name", Message: "is already taken"}, {Field: "category_id", Message: "is already taken"}},
+		},
+		{
+			name: "missing reference",
+			err:  postgresql.ClassifyError(&pgconn.PgError{Code: "23503", Detail: `Key (category_id)=(7) is not present in table "category".`}),
+			want: []apperror.FieldError{{Field: "category_id", Message: "references a missing resource"}},
+		},
+		{
+			name: "still referenced",
+			err:  postgresql.ClassifyError(&pgconn.PgError{Code: "23503", Detail: `Key (id)=(7) is still referenced from table "product".`}),
+			want: nil,
+		},
+		{
+			name: "unparsable detail",
+			err:  postgresql.ClassifyError(&pgconn.PgError{Code: "23505", Detail: "duplicate"}),
+			want: nil,
+		},
+		{name: "plain error", err: errors.New("boom"), want: nil},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			if got := Fields(tt.err); !reflect.DeepEqual(got, tt.want) {
+				t.Fatalf("Fields() = %#v, want %#v", got, tt.want)
+			}
+		})
+	}
+}
+
+func TestRetryAfter(t *testing.T) {
+	tests := []struct {
+		name string
+		err  error
+		want time.Duration
+	}{
+		{name: "rate limited", err: apperror.RateLimited(3*time.Second, "slow down"), want: 3 * time.Second},
+		{name: "conflict", err: apperror.Conflict(2*time.Second, "in progress"), want: 2 * time.Second},
+		{name: "serialization", err: pgError("40001"), want: time.Second},
+		{name: "query canceled", err: pgError("57014"), want: time.Second},
+		{name: "not found", err: apperror.NotFound("no product"), want: 0},
+		{name: "internal", err: errors.New("boom"), want: 0},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			if got := RetryAfter(tt.err, KindOf(tt.err)); got != tt.want {
+				t.Fatalf("RetryAfter() = %s, want %s", got, tt.want)
+			}
+		})
+	}
+}
+
+func TestKindType(t *testing.T) {
+	if got, want := NotFound.Type(), "urn:go-prod:problem:not-found"; got != want {
+		t.Fatalf("Type() = %q, want %q", got, want)
+	}
+}
@@ -0,0 +1,81 @@
+package ratelimit
+
+import (
+	"fmt"
+	"net"
+	"net/http"
+	"strings"
+)
+
+// IPResolver finds the client address of a request, X-Forwarded-For is only trusted when set by a trusted proxy.
+type IPResolver struct {
+	trusted []*net.IPNet
+}
+
+// NewIPResolver accepts addresses and CIDR ranges of trusted proxies.
+func NewIPResolver(trusted []string) (*IPResolver, error) {
+	r := &IPResolver{trusted: make([]*net.IPNet, 0, len(trusted))}
+	for _, s := range trusted {
+		if !strings.Contains(s, "/") {
+			ip := net.ParseIP(s)
+			if ip == nil {
+				return nil, fmt.Errorf("%q is not an IP address or CIDR range", s)
+			}
+			bits := 8 * len(ip.To4())
+			if bits == 0 {
+				bits = 8 * net.IPv6len
+			}
+			s = fmt.Sprintf("%s/%d", s, bits)
+		}
+		_, ipNet, err := net.ParseCIDR(s)
+		if err != nil {
+			return nil, fmt.Errorf("%q is not an IP address or CIDR range", s)
+		}
+		r.trusted = append(r.trusted, ipNet)
+	}
+	return r, nil
+}
+
+// ClientIP walks X-Forwarded-For from the nearest hop and returns the first address that isn't a trusted proxy.
+func (r *IPResolver) ClientIP(req *http.Request) string {
+	host, _, err := net.SplitHostPort(req.RemoteAddr)
+	if err != nil {
+		host = req.RemoteAddr
+	}
+	if !r.isTrusted(host) {
+		return host
+	}
+
+	hops := make([]string, 0)
+	for _, header := range req.Header.Values("X-Forwarded-For") {
+		for _, hop := range strings.Split(header, ",") {
+			if hop = strings.TrimSpace(hop); hop != "" {
+				hops = append(hops, hop)
+			}
+		}
+	}
+	for i := len(hops) - 1; i >= 0; i-- {
+		if net.ParseIP(hops[i]) == nil {
+			// a malformed hop can't be attributed, stop at the last known address
+			return host
+		}
+		host = hops[i]
+		if !r.isTrusted(host) {
+			return host
+		}
+	}
+	return host
+}
+
+func (r *IPResolver) isTrusted(host string) bool {
+	ip := net.ParseIP(host)
+	if ip == nil {
+		return false
+	}
+	for _, ipNet := range r.trusted {
+		if ipNet.Contains(ip) {
+			return true
+		}
+	}
+	return false
+}
@@ -0,0 +1,67 @@
+package ratelimit
+
+import (
+	"net/http/httptest"
+	"testing"
+)
+
+func TestNewIPResolver(t *testing.T) {
+	for _, trusted := range [][]string{{"10.0.0.1"}, {"10.0.0.0/8", "::1", "fd00::/8"}, nil} {
+		if _, err := NewIPResolver(trusted); err != nil {
+			t.Errorf("NewIPResolver(%q) = %v", trusted, err)
+		}
+	}
+	for _, trusted := range [][]string{{"proxy.local"}, {"10.0.0.0/33"}, {""}} {
+		if _, err := NewIPResolver(trusted); err == nil {
+			t.Errorf("NewIPResolver(%q) accepted an invalid address", trusted)
+		}
+	}
+}
+
+func TestIPResolverClientIP(t *testing.T) {
+	resolver, err := NewIPResolver([]string{"10.0.0.0/8", "::1"})
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	tests := []struct {
+		name         string
+		remoteAddr   string
+		forwardedFor []string
+		want         string
+	}{
+		{name: "direct client", remoteAddr: "203.0.113.7:5000", want: "203.0.113.7"},
+		{name: "untrusted peer can not spoof", remoteAddr: "203.0.113.7:5000", forwardedFor: []string{"198.51.100.1"}, want: "203.0.113.7"},
+		{name: "trusted proxy", remoteAddr: "10.0.0.1:5000", forwardedFor: []string{"198.51.100.1"}, want: "198.51.100.1"},
+		{
+			name:         "chain of trusted proxies",
+			remoteAddr:   "10.0.0.1:5000",
+			forwardedFor: []string{"198.51.100.1, 10.0.0.3", "10.0.0.2"},
+			want:         "198.51.100.1",
+		},
+		{
+			name:         "spoofed hop before the first untrusted one is ignored",
+			remoteAddr:   "10.0.0.1:5000",
+			forwardedFor: []string{"1.2.3.4, 198.51.100.1"},
+			want:         "198.51.100.1",
+		},
+		{name: "malformed hop", remoteAddr: "10.0.0.1:5000", forwardedFor: []string{"198.51.100.1, garbage"}, want: "10.0.0.1"},
+		{name: "only trusted hops", remoteAddr: "10.0.0.1:5000", forwardedFor: []string{"10.0.0.2"}, want: "10.0.0.2"},
+		{name: "trusted proxy without header", remoteAddr: "10.0.0.1:5000", want: "10.0.0.1"},
+		{name: "ipv6 proxy", remoteAddr: "[::1]:5000", forwardedFor: []string{"2001:db8::1"}, want: "2001:db8::1"},
+		{name: "remote address without port", remoteAddr: "203.0.113.7", want: "203.0.113.7"},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			r := httptest.NewRequest("GET", "/", nil)
+			r.RemoteAddr = tt.remoteAddr
+			for _, v := range tt.forwardedFor {
+				r.Header.Add("X-Forwarded-For", v)
+			}
+			if got := resolver.ClientIP(r); got != tt.want {
+				t.Errorf("ClientIP() = %q, want %q", got, tt.want)
+			}
+		})
+	}
+}
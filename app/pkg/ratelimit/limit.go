@@ -0,0 +1,121 @@
+package ratelimit
+
+import (
+	"context"
+	"fmt"
+	"math"
+	"prod/pkg/route"
+	"strconv"
+	"strings"
+	"time"
+)
+
+// Limit is a token bucket holding Requests tokens that refills completely in Period.
+// The zero Limit means unlimited.
+type Limit struct {
+	Requests int
+	Period   time.Duration
+}
+
+func (l Limit) Unlimited() bool {
+	return l.Requests == 0
+}
+
+// rate returns tokens per second.
+func (l Limit) rate() float64 {
+	return float64(l.Requests) / l.Period.Seconds()
+}
+
+func (l Limit) String() string {
+	if l.Unlimited() {
+		return "off"
+	}
+	return fmt.Sprintf("%d/%s", l.Requests, l.Period)
+}
+
+// ParseLimit parses "<requests>/<period>", e.g. "100/1m", or "off".
+func ParseLimit(s string) (Limit, error) {
+	s = strings.TrimSpace(s)
+	if s == "off" {
+		return Limit{}, nil
+	}
+
+	requests, period, ok := strings.Cut(s, "/")
+	if !ok {
+		return Limit{}, fmt.Errorf("limit %q must look like 100/1m or be off", s)
+	}
+	n, err := strconv.Atoi(requests)
+	if err != nil || n < 1 {
+		return Limit{}, fmt.Errorf("limit %q: requests must be a positive number", s)
+	}
+	d, err := time.ParseDuration(period)
+	if err != nil || d <= 0 {
+		return Limit{}, fmt.Errorf("limit %q: period must be a positive duration", s)
+	}
+
+	return Limit{Requests: n, Period: d}, nil
+}
+
+// Result describes the state of a bucket after a request was counted.
+type Result struct {
+	Allowed    bool
+	Limit      Limit
+	Remaining  int
+	Reset      time.Duration
+	RetryAfter time.Duration
+}
+
+func newResult(limit Limit, tokens float64, allowed bool) Result {
+	rate := limit.rate()
+	res := Result{
+		Allowed:   allowed,
+		Limit:     limit,
+		Remaining: int(math.Max(0, math.Floor(tokens))),
+		Reset:     seconds((float64(limit.Requests) - tokens) / rate),
+	}
+	if !allowed {
+		res.RetryAfter = seconds((1 - tokens) / rate)
+	}
+	return res
+}
+
+func seconds(s float64) time.Duration {
+	return time.Duration(math.Max(0, s) * float64(time.Second))
+}
+
+// Limiter counts a request against the bucket of key.
+type Limiter interface {
+	Allow(ctx context.Context, key string, limit Limit) (Result, error)
+}
+
+// Rules pick the limit of a request by its route, see route.Table for the patterns.
+type Rules struct {
+	table *route.Table[Limit]
+}
+
+func NewRules(def string, routes map[string]string) (*Rules, error) {
+	defLimit, err := ParseLimit(def)
+	if err != nil {
+		return nil, err
+	}
+
+	limits := make(map[string]Limit, len(routes))
+	for name, value := range routes {
+		limit, err := ParseLimit(value)
+		if err != nil {
+			return nil, fmt.Errorf("route %q: %w", name, err)
+		}
+		limits[name] = limit
+	}
+
+	table, err := route.NewTable(defLimit, limits)
+	if err != nil {
+		return nil, err
+	}
+	return &Rules{table: table}, nil
+}
+
+// Match returns the name of the bucket group and its limit, "default" for the default limit.
+func (r *Rules) Match(method string, path string) (string, Limit) {
+	return r.table.Match(method, path)
+}
@@ -0,0 +1,108 @@
+package ratelimit
+
+import (
+	"testing"
+	"time"
+)
+
+func TestParseLimit(t *testing.T) {
+	tests := []struct {
+		value   string
+		want    Limit
+		wantErr bool
+	}{
+		{value: "100/1m", want: Limit{Requests: 100, Period: time.Minute}},
+		{value: " 5/10s ", want: Limit{Requests: 5, Period: 10 * time.Second}},
+		{value: "off", want: Limit{}},
+		{value: "100", wantErr: true},
+		{value: "0/1m", wantErr: true},
+		{value: "-1/1m", wantErr: true},
+		{value: "x/1m", wantErr: true},
+		{value: "100/0s", wantErr: true},
+		{value: "100/minute", wantErr: true},
+		{value: "", wantErr: true},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.value, func(t *testing.T) {
+			got, err := ParseLimit(tt.value)
+			if (err != nil) != tt.wantErr {
+				t.Fatalf("ParseLimit(%q) err = %v, wantErr %v", tt.value, err, tt.wantErr)
+			}
+			if got != tt.want {
+				t.Errorf("ParseLimit(%q) = %+v, want %+v", tt.value, got, tt.want)
+			}
+		})
+	}
+}
+
+func TestNewResult(t *testing.T) {
+	limit := Limit{Requests: 10, Period: 10 * time.Second}
+
+	tests := []struct {
+		name    string
+		tokens  float64
+		allowed bool
+		want    Result
+	}{
+		{
+			name:    "full bucket after one request",
+			tokens:  9,
+			allowed: true,
+			want:    Result{Allowed: true, Limit: limit, Remaining: 9, Reset: time.Second},
+		},
+		{
+			name:    "partial token is not a request",
+			tokens:  0.5,
+			allowed: true,
+			want:    Result{Allowed: true, Limit: limit, Remaining: 0, Reset: 9500 * time.Millisecond},
+		},
+		{
+			name:    "denied waits for the missing part of a token",
+			tokens:  0.25,
+			allowed: false,
+			want:    Result{Limit: limit, Remaining: 0, Reset: 9750 * time.Millisecond, RetryAfter: 750 * time.Millisecond},
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			if got := newResult(limit, tt.tokens, tt.allowed); got != tt.want {
+				t.Errorf("newResult() = %+v, want %+v", got, tt.want)
+			}
+		})
+	}
+}
+
+func TestRulesMatch(t *testing.T) {
+	rules, err := NewRules("300/1m", map[string]string{
+		"POST /api/auth/login":  "10/1m",
+		"GET /api/products/:id": "off",
+	})
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	tests := []struct {
+		method    string
+		path      string
+		wantLimit Limit
+	}{
+		{method: "POST", path: "/api/auth/login", wantLimit: Limit{Requests: 10, Period: time.Minute}},
+		{method: "GET", path: "/api/auth/login", wantLimit: Limit{Requests: 300, Period: time.Minute}},
+		{method: "GET", path: "/api/products/42", wantLimit: Limit{}},
+		{method: "GET", path: "/api/products", wantLimit: Limit{Requests: 300, Period: time.Minute}},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.method+" "+tt.path, func(t *testing.T) {
+			if _, got := rules.Match(tt.method, tt.path); got != tt.wantLimit {
+				t.Errorf("Match() = %+v, want %+v", got, tt.wantLimit)
+			}
+		})
+	}
+
+	if _, err = NewRules("300/1m", map[string]string{"GET /": "fast"}); err == nil {
+		t.Error("NewRules accepted an invalid route limit")
+	}
+}
@@ -0,0 +1,81 @@
+package ratelimit
+
+import (
+	"context"
+	"testing"
+	"time"
+)
+
+// bucketLimit is a bucket of 3 requests refilled at one request per second.
+var bucketLimit = Limit{Requests: 3, Period: 3 * time.Second}
+
+// bucketSteps advance the clock by after and then make a request, they are shared by the tests of every limiter.
+var bucketSteps = []struct {
+	after         time.Duration
+	wantAllowed   bool
+	wantRemaining int
+	wantRetry     time.Duration
+}{
+	{after: 0, wantAllowed: true, wantRemaining: 2},
+	{after: 0, wantAllowed: true, wantRemaining: 1},
+	{after: 0, wantAllowed: true, wantRemaining: 0},
+	{after: 0, wantAllowed: false, wantRemaining: 0, wantRetry: time.Second},
+	{after: 500 * time.Millisecond, wantAllowed: false, wantRemaining: 0, wantRetry: 500 * time.Millisecond},
+	{after: 500 * time.Millisecond, wantAllowed: true, wantRemaining: 0},
+	// the bucket refills no further than its size
+	{after: time.Hour, wantAllowed: true, wantRemaining: 2},
+}
+
+func TestMemoryLimiter(t *testing.T) {
+	start := time.Date(2024, 1, 1, 0, 0, 0, 0, time.UTC)
+
+	l := NewMemoryLimiter()
+	now := start
+	l.now = func() time.Time { return now }
+
+	for i, step := range bucketSteps {
+		now = now.Add(step.after)
+		res, err := l.Allow(context.Background(), "client", bucketLimit)
+		if err != nil {
+			t.Fatal(err)
+		}
+		if res.Allowed != step.wantAllowed || res.Remaining != step.wantRemaining || res.RetryAfter != step.wantRetry {
+			t.Errorf("step %d: got allowed=%v remaining=%d retry=%s, want allowed=%v remaining=%d retry=%s",
+				i, res.Allowed, res.Remaining, res.RetryAfter, step.wantAllowed, step.wantRemaining, step.wantRetry)
+		}
+	}
+}
+
+func TestMemoryLimiterKeysAreIndependent(t *testing.T) {
+	l := NewMemoryLimiter()
+	limit := Limit{Requests: 1, Period: time.Minute}
+	ctx := context.Background()
+
+	if res, _ := l.Allow(ctx, "a", limit); !res.Allowed {
+		t.Fatal("first request of a was denied")
+	}
+	if res, _ := l.Allow(ctx, "a", limit); res.Allowed {
+		t.Error("second request of a was allowed")
+	}
+	if res, _ := l.Allow(ctx, "b", limit); !res.Allowed {
+		t.Error("b was denied because of a")
+	}
+}
+
+func TestMemoryLimiterSweep(t *testing.T) {
+	l := NewMemoryLimiter()
+	now := time.Now()
+	l.now = func() time.Time { return now }
+	limit := Limit{Requests: 10, Period: time.Second}
+
+	_, _ = l.Allow(context.Background(), "idle", limit)
+	now = now.Add(sweepInterval)
+	_, _ = l.Allow(context.Background(), "active", limit)
+
+	if _, ok := l.buckets["idle"]; ok {
+		t.Error("the refilled bucket was not swept")
+	}
+	if _, ok := l.buckets["active"]; !ok {
+		t.Error("the active bucket was swept")
+	}
+}
@@ -0,0 +1,86 @@
+package ratelimit
+
+import (
+	"context"
+	"math"
+	"net/http"
+	"prod/pkg/apperror"
+	"prod/pkg/auth"
+	"prod/pkg/httperr"
+	"prod/pkg/logging"
+	"strconv"
+	"time"
+)
+
+// KeyFunc identifies the client a request is counted against.
+type KeyFunc func(r *http.Request) string
+
+// HTTPKey counts requests per API key or user when the request was identified, per client address otherwise.
+func HTTPKey(ips *IPResolver) KeyFunc {
+	return func(r *http.Request) string {
+		if key, ok := principalKey(r.Context()); ok {
+			return key
+		}
+		return "ip:" + ips.ClientIP(r)
+	}
+}
+
+func principalKey(ctx context.Context) (string, bool) {
+	p, ok := auth.PrincipalFromContext(ctx)
+	if !ok {
+		return "", false
+	}
+	return p.Key(), true
+}
+
+type Middleware struct {
+	limiter Limiter
+	rules   *Rules
+	key     KeyFunc
+}
+
+func NewMiddleware(limiter Limiter, rules *Rules, key KeyFunc) *Middleware {
+	return &Middleware{
+		limiter: limiter,
+		rules:   rules,
+		key:     key,
+	}
+}
+
+// Handler answers 429 once the client has used up the limit of the route, limiter failures let requests through.
+func (m *Middleware) Handler(next http.Handler) http.Handler {
+	return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		name, limit := m.rules.Match(r.Method, r.URL.Path)
+		if limit.Unlimited() {
+			next.ServeHTTP(w, r)
+			return
+		}
+
+		res, err := m.limiter.Allow(r.Context(), m.key(r)+"|"+name, limit)
+		if err != nil {
+			logging.GetLogger(r.Context()).WithError(err).Warningln("rate limiter failed, request is let through")
+			next.ServeHTTP(w, r)
+			return
+		}
+
+		setHeaders(w.Header(), res)
+		if !res.Allowed {
+			httperr.Write(w, r, apperror.RateLimited(res.RetryAfter, "rate limit of %s exceeded", limit))
+			return
+		}
+
+		next.ServeHTTP(w, r)
+	})
+}
+
+// setHeaders writes the RateLimit-* fields of the IETF httpapi-ratelimit-headers draft.
+func setHeaders(h http.Header, res Result) {
+	h.Set("RateLimit-Limit", strconv.Itoa(res.Limit.Requests))
+	h.Set("RateLimit-Remaining", strconv.Itoa(res.Remaining))
+	h.Set("RateLimit-Reset", ceilSeconds(res.Reset))
+	h.Set("RateLimit-Policy", strconv.Itoa(res.Limit.Requests)+";w="+ceilSeconds(res.Limit.Period))
+}
+
+func ceilSeconds(d time.Duration) string {
+	return strconv.Itoa(int(math.Ceil(d.Seconds())))
+}
@@ -0,0 +1,90 @@
+package ratelimit
+
+import (
+	"context"
+	"errors"
+	"net/http"
+	"net/http/httptest"
+	"prod/pkg/auth"
+	"testing"
+)
+
+type failingLimiter struct{}
+
+func (failingLimiter) Allow(ctx context.Context, key string, limit Limit) (Result, error) {
+	return Result{}, errors.New("store is down")
+}
+
+func TestMiddlewareHandler(t *testing.T) {
+	rules, err := NewRules("off", map[string]string{"POST /api/auth/login": "2/1m"})
+	if err != nil {
+		t.Fatal(err)
+	}
+	resolver, err := NewIPResolver(nil)
+	if err != nil {
+		t.Fatal(err)
+	}
+	m := NewMiddleware(NewMemoryLimiter(), rules, HTTPKey(resolver))
+	handler := m.Handler(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {}))
+
+	do := func(method string, remoteAddr string) *httptest.ResponseRecorder {
+		r := httptest.NewRequest(method, "/api/auth/login", nil)
+		r.RemoteAddr = remoteAddr
+		w := httptest.NewRecorder()
+		handler.ServeHTTP(w, r)
+		return w
+	}
+
+	for i, wantStatus := range []int{http.StatusOK, http.StatusOK, http.StatusTooManyRequests} {
+		w := do(http.MethodPost, "203.0.113.7:1")
+		if w.Code != wantStatus {
+			t.Errorf("request %d: status = %d, want %d", i, w.Code, wantStatus)
+		}
+		if w.Header().Get("RateLimit-Limit") != "2" || w.Header().Get("RateLimit-Policy") != "2;w=60" {
+			t.Errorf("request %d: headers = %v", i, w.Header())
+		}
+	}
+	if w := do(http.MethodPost, "203.0.113.7:1"); w.Header().Get("Retry-After") == "" {
+		t.Error("429 without Retry-After")
+	}
+	if w := do(http.MethodPost, "203.0.113.8:1"); w.Code != http.StatusOK {
+		t.Errorf("another client: status = %d, want 200", w.Code)
+	}
+	if w := do(http.MethodGet, "203.0.113.7:1"); w.Code != http.StatusOK || w.Header().Get("RateLimit-Limit") != "" {
+		t.Errorf("unlimited route: status = %d, headers = %v", w.Code, w.Header())
+	}
+}
+
+func TestMiddlewareLimiterFailure(t *testing.T) {
+	rules, err := NewRules("1/1m", nil)
+	if err != nil {
+		t.Fatal(err)
+	}
+	m := NewMiddleware(failingLimiter{}, rules, func(r *http.Request) string { return "client" })
+	handler := m.Handler(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {}))
+
+	w := httptest.NewRecorder()
+	handler.ServeHTTP(w, httptest.NewRequest(http.MethodGet, "/", nil))
+	if w.Code != http.StatusOK {
+		t.Errorf("status = %d, a failing limiter must let requests through", w.Code)
+	}
+}
+
+func TestHTTPKey(t *testing.T) {
+	resolver, err := NewIPResolver(nil)
+	if err != nil {
+		t.Fatal(err)
+	}
+	key := HTTPKey(resolver)
+
+	r := httptest.NewRequest(http.MethodGet, "/", nil)
+	r.RemoteAddr = "203.0.113.7:1"
+	if got := key(r); got != "ip:203.0.113.7" {
+		t.Errorf("anonymous key = %q", got)
+	}
+
+	r = r.WithContext(auth.ContextWithPrincipal(r.Context(), auth.Principal{Subject: "key-1", APIKey: true}))
+	if got := key(r); got != "apikey:key-1" {
+		t.Errorf("api key key = %q", got)
+	}
+}
@@ -0,0 +1,60 @@
+package ratelimit
+
+import (
+	"context"
+	"fmt"
+	"prod/pkg/client/postgresql"
+	"prod/pkg/logging"
+	"time"
+)
+
+// refilled is the token count of the stored bucket at the time of the statement.
+const refilled = "least($2::float8, b.tokens + extract(epoch FROM now() - b.updated_at) * $3::float8)"
+
+// allowQuery refills and takes a token in a single statement, so concurrent instances never lose updates.
+const allowQuery = `
+INSERT INTO public.rate_limit_bucket AS b (key, tokens, allowed, updated_at, full_at)
+VALUES ($1, $2::float8 - 1, true, now(), now() + make_interval(secs => 1 / $3::float8))
+ON CONFLICT (key) DO UPDATE SET
+    allowed = ` + refilled + ` >= 1,
+    tokens = ` + refilled + ` - CASE WHEN ` + refilled + ` >= 1 THEN 1 ELSE 0 END,
+    updated_at = now(),
+    full_at = now() + make_interval(secs => ($2::float8 - ` + refilled + ` + 1) / $3::float8)
+RETURNING tokens, allowed`
+
+// PostgreSQLLimiter keeps buckets in a shared table so that limits hold across instances.
+type PostgreSQLLimiter struct {
+	client postgresql.Client
+}
+
+func NewPostgreSQLLimiter(client postgresql.Client) *PostgreSQLLimiter {
+	return &PostgreSQLLimiter{client: client}
+}
+
+func (l *PostgreSQLLimiter) Allow(ctx context.Context, key string, limit Limit) (Result, error) {
+	var tokens float64
+	var allowed bool
+	err := l.client.QueryRow(ctx, allowQuery, key, float64(limit.Requests), limit.rate()).Scan(&tokens, &allowed)
+	if err != nil {
+		return Result{}, fmt.Errorf("failed to count request: %w", postgresql.ClassifyError(err))
+	}
+
+	return newResult(limit, tokens, allowed), nil
+}
+
+// Run deletes refilled buckets every interval until ctx is done.
+func (l *PostgreSQLLimiter) Run(ctx context.Context, interval time.Duration) error {
+	ticker := time.NewTicker(interval)
+	defer ticker.Stop()
+
+	for {
+		select {
+		case <-ctx.Done():
+			return nil
+		case <-ticker.C:
+			if _, err := l.client.Exec(ctx, "DELETE FROM public.rate_limit_bucket WHERE full_at < now()"); err != nil {
+				logging.GetLogger(ctx).WithError(err).Warningln("failed to delete rate limit buckets")
+			}
+		}
+	}
+}
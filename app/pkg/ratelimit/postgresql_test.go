@@ -0,0 +1,198 @@
+package ratelimit
+
+import (
+	"context"
+	"errors"
+	"github.com/jackc/pgx/v4"
+	"github.com/jackc/pgx/v4/pgxpool"
+	"io/fs"
+	"os"
+	"prod/migrations"
+	"prod/pkg/client/postgresql"
+	"testing"
+	"time"
+)
+
+// fakeClient answers the allow statement with a stored bucket, the other methods are not used by the limiter.
+type fakeClient struct {
+	postgresql.Client
+	tokens  float64
+	allowed bool
+	err     error
+	args    []interface{}
+}
+
+func (c *fakeClient) QueryRow(_ context.Context, _ string, args ...interface{}) pgx.Row {
+	c.args = args
+	return fakeRow{c}
+}
+
+type fakeRow struct {
+	c *fakeClient
+}
+
+func (r fakeRow) Scan(dest ...interface{}) error {
+	if r.c.err != nil {
+		return r.c.err
+	}
+	*dest[0].(*float64) = r.c.tokens
+	*dest[1].(*bool) = r.c.allowed
+	return nil
+}
+
+func TestPostgreSQLLimiterResult(t *testing.T) {
+	tests := []struct {
+		name          string
+		client        *fakeClient
+		wantAllowed   bool
+		wantRemaining int
+		wantRetry     time.Duration
+		wantReset     time.Duration
+		wantErr       bool
+	}{
+		{name: "full bucket", client: &fakeClient{tokens: 2, allowed: true}, wantAllowed: true, wantRemaining: 2, wantReset: time.Second},
+		{name: "last token", client: &fakeClient{tokens: 0, allowed: true}, wantAllowed: true, wantReset: 3 * time.Second},
+		{name: "empty bucket", client: &fakeClient{tokens: 0.25}, wantRetry: 750 * time.Millisecond, wantReset: 2750 * time.Millisecond},
+		{name: "query fails", client: &fakeClient{err: errors.New("connection refused")}, wantErr: true},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			res, err := NewPostgreSQLLimiter(tt.client).Allow(context.Background(), "client", bucketLimit)
+			if (err != nil) != tt.wantErr {
+				t.Fatalf("Allow() error = %v, wantErr %t", err, tt.wantErr)
+			}
+			if tt.wantErr {
+				return
+			}
+			if res.Allowed != tt.wantAllowed || res.Remaining != tt.wantRemaining || res.RetryAfter != tt.wantRetry || res.Reset != tt.wantReset {
+				t.Errorf("got allowed=%v remaining=%d retry=%s reset=%s, want allowed=%v remaining=%d retry=%s reset=%s",
+					res.Allowed, res.Remaining, res.RetryAfter, res.Reset, tt.wantAllowed, tt.wantRemaining, tt.wantRetry, tt.wantReset)
+			}
+			// the bucket size and the refill rate per second are the parameters of the statement
+			if tt.client.args[0] != "client" || tt.client.args[1] != 3.0 || tt.client.args[2] != 1.0 {
+				t.Errorf("args = %v, want [client 3 1]", tt.client.args)
+			}
+		})
+	}
+}
+
+// testLimiter connects to TEST_POSTGRES_URL and creates the bucket table when the database has none.
+func testLimiter(t *testing.T) (*PostgreSQLLimiter, *pgxpool.Pool) {
+	t.Helper()
+	url := os.Getenv("TEST_POSTGRES_URL")
+	if url == "" {
+		t.Skip("TEST_POSTGRES_URL is not set")
+	}
+	ctx := context.Background()
+	pool, err := pgxpool.Connect(ctx, url)
+	if err != nil {
+		t.Fatal(err)
+	}
+	t.Cleanup(pool.Close)
+
+	var exists bool
+	if err = pool.QueryRow(ctx, "SELECT to_regclass('public.rate_limit_bucket') IS NOT NULL").Scan(&exists); err != nil {
+		t.Fatal(err)
+	}
+	if !exists {
+		up, err := fs.ReadFile(migrations.FS, "00007_rate_limit.up.sql")
+		if err != nil {
+			t.Fatal(err)
+		}
+		if _, err = pool.Exec(ctx, string(up)); err != nil {
+			t.Fatal(err)
+		}
+		t.Cleanup(func() { _, _ = pool.Exec(ctx, "DROP TABLE public.rate_limit_bucket") })
+	}
+	return NewPostgreSQLLimiter(pool), pool
+}
+
+func testKey(t *testing.T, pool *pgxpool.Pool) string {
+	key := "test|" + t.Name() + "|" + time.Now().Format(time.RFC3339Nano)
+	t.Cleanup(func() {
+		_, _ = pool.Exec(context.Background(), "DELETE FROM public.rate_limit_bucket WHERE key LIKE $1 || '%'", key)
+	})
+	return key
+}
+
+// rewind moves the bucket of key back in time, the clock of the database can't be moved forward.
+func rewind(t *testing.T, pool *pgxpool.Pool, key string, d time.Duration) {
+	t.Helper()
+	_, err := pool.Exec(context.Background(), `
+UPDATE public.rate_limit_bucket
+SET updated_at = updated_at - make_interval(secs => $2), full_at = full_at - make_interval(secs => $2)
+WHERE key = $1`, key, d.Seconds())
+	if err != nil {
+		t.Fatal(err)
+	}
+}
+
+func TestPostgreSQLLimiter(t *testing.T) {
+	l, pool := testLimiter(t)
+	key := testKey(t, pool)
+
+	// the database clock runs on between the statements, so Retry-After may be a little shorter than expected
+	const tolerance = 100 * time.Millisecond
+
+	for i, step := range bucketSteps {
+		rewind(t, pool, key, step.after)
+		res, err := l.Allow(context.Background(), key, bucketLimit)
+		if err != nil {
+			t.Fatal(err)
+		}
+		if res.Allowed != step.wantAllowed || res.Remaining != step.wantRemaining ||
+			res.RetryAfter > step.wantRetry || res.RetryAfter < step.wantRetry-tolerance {
+			t.Errorf("step %d: got allowed=%v remaining=%d retry=%s, want allowed=%v remaining=%d retry=%s",
+				i, res.Allowed, res.Remaining, res.RetryAfter, step.wantAllowed, step.wantRemaining, step.wantRetry)
+		}
+	}
+}
+
+func TestPostgreSQLLimiterKeysAreIndependent(t *testing.T) {
+	l, pool := testLimiter(t)
+	key := testKey(t, pool)
+	limit := Limit{Requests: 1, Period: time.Minute}
+	ctx := context.Background()
+
+	if res, _ := l.Allow(ctx, key+"a", limit); !res.Allowed {
+		t.Fatal("first request of a was denied")
+	}
+	if res, _ := l.Allow(ctx, key+"a", limit); res.Allowed {
+		t.Error("second request of a was allowed")
+	}
+	if res, _ := l.Allow(ctx, key+"b", limit); !res.Allowed {
+		t.Error("b was denied because of a")
+	}
+}
+
+func TestPostgreSQLLimiterRun(t *testing.T) {
+	l, pool := testLimiter(t)
+	key := testKey(t, pool)
+	limit := Limit{Requests: 10, Period: time.Second}
+	ctx := context.Background()
+
+	_, _ = l.Allow(ctx, key+"idle", limit)
+	_, _ = l.Allow(ctx, key+"active", limit)
+	rewind(t, pool, key+"idle", time.Hour)
+
+	runCtx, cancel := context.WithTimeout(ctx, 100*time.Millisecond)
+	defer cancel()
+	if err := l.Run(runCtx, 10*time.Millisecond); err != nil {
+		t.Fatal(err)
+	}
+
+	for _, tt := range []struct {
+		key  string
+		want bool
+	}{{key + "idle", false}, {key + "active", true}} {
+		var exists bool
+		err := pool.QueryRow(ctx, "SELECT EXISTS (SELECT 1 FROM public.rate_limit_bucket WHERE key = $1)", tt.key).Scan(&exists)
+		if err != nil {
+			t.Fatal(err)
+		}
+		if exists != tt.want {
+			t.Errorf("bucket %s exists = %t, want %t", tt.key, exists, tt.want)
+		}
+	}
+}
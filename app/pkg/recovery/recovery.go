@@ -0,0 +1,67 @@
+package recovery
+
+import (
+	"context"
+	"fmt"
+	"net/http"
+	"prod/pkg/httperr"
+	"prod/pkg/logging"
+	"prod/pkg/requestid"
+	"runtime/debug"
+)
+
+// Middleware turns a panic of next into a 500 problem response and logs the stack with the request ID.
+// http.ErrAbortHandler is re-panicked, the server uses it to abort the response silently.
+func Middleware(next http.Handler) http.Handler {
+	return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		rw := &responseWriter{ResponseWriter: w}
+		defer func() {
+			v := recover()
+			if v == nil {
+				return
+			}
+			if v == http.ErrAbortHandler {
+				panic(v)
+			}
+
+			err := log(r.Context(), v)
+			if rw.wroteHeader {
+				// the client has got a part of the response already, it can only be cut off
+				panic(http.ErrAbortHandler)
+			}
+			httperr.WriteProblem(w, httperr.NewProblem(r, err))
+		}()
+
+		next.ServeHTTP(rw, r)
+	})
+}
+
+func log(ctx context.Context, v interface{}) error {
+	err := fmt.Errorf("panic: %v", v)
+	logging.GetLogger(ctx).WithError(err).
+		WithField("request_id", requestid.FromContext(ctx)).
+		WithField("stack", string(debug.Stack())).
+		Errorln("recovered from panic")
+	return err
+}
+
+// responseWriter remembers whether the response has been started.
+type responseWriter struct {
+	http.ResponseWriter
+	wroteHeader bool
+}
+
+func (w *responseWriter) WriteHeader(status int) {
+	w.wroteHeader = true
+	w.ResponseWriter.WriteHeader(status)
+}
+
+func (w *responseWriter) Write(b []byte) (int, error) {
+	w.wroteHeader = true
+	return w.ResponseWriter.Write(b)
+}
+
+// Unwrap lets http.ResponseController reach the underlying writer.
+func (w *responseWriter) Unwrap() http.ResponseWriter {
+	return w.ResponseWriter
+}
@@ -0,0 +1,75 @@
+package recovery
+
+import (
+	"encoding/json"
+	"net/http"
+	"net/http/httptest"
+	"prod/pkg/httperr"
+	"testing"
+)
+
+func TestMiddleware(t *testing.T) {
+	tests := []struct {
+		name       string
+		handler    http.HandlerFunc
+		wantStatus int
+		wantPanic  interface{}
+	}{
+		{
+			name:       "no panic",
+			handler:    func(w http.ResponseWriter, r *http.Request) { w.WriteHeader(http.StatusNoContent) },
+			wantStatus: http.StatusNoContent,
+		},
+		{
+			name:       "panic before the response",
+			handler:    func(w http.ResponseWriter, r *http.Request) { panic("boom") },
+			wantStatus: http.StatusInternalServerError,
+		},
+		{
+			name: "panic after the response has started",
+			handler: func(w http.ResponseWriter, r *http.Request) {
+				_, _ = w.Write([]byte("partial"))
+				panic("boom")
+			},
+			wantStatus: http.StatusOK,
+			wantPanic:  http.ErrAbortHandler,
+		},
+		{
+			name:       "abort is passed through",
+			handler:    func(w http.ResponseWriter, r *http.Request) { panic(http.ErrAbortHandler) },
+			wantStatus: http.StatusOK,
+			wantPanic:  http.ErrAbortHandler,
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			w := httptest.NewRecorder()
+			r := httptest.NewRequest(http.MethodGet, "/api/products", nil)
+
+			func() {
+				defer func() {
+					if got := recover(); got != tt.wantPanic {
+						t.Fatalf("panic = %v, want %v", got, tt.wantPanic)
+					}
+				}()
+				Middleware(tt.handler).ServeHTTP(w, r)
+			}()
+
+			if w.Code != tt.wantStatus {
+				t.Fatalf("status = %d, want %d", w.Code, tt.wantStatus)
+			}
+			if tt.wantStatus != http.StatusInternalServerError {
+				return
+			}
+
+			var p httperr.Problem
+			if err := json.NewDecoder(w.Body).Decode(&p); err != nil {
+				t.Fatalf("decode body: %v", err)
+			}
+			if p.Type != "urn:go-prod:problem:internal" || p.Detail != "internal error" {
+				t.Fatalf("problem = %+v, want a hidden internal error", p)
+			}
+		})
+	}
+}
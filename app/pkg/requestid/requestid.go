@@ -0,0 +1,53 @@
+package requestid
+
+import (
+	"context"
+	"crypto/rand"
+	"encoding/hex"
+	"net/http"
+)
+
+const (
+	Header    = "X-Request-ID"
+	maxLength = 128
+)
+
+type ctxRequestID struct{}
+
+func ContextWithID(ctx context.Context, id string) context.Context {
+	return context.WithValue(ctx, ctxRequestID{}, id)
+}
+
+// FromContext returns the request ID or an empty string outside of a request.
+func FromContext(ctx context.Context) string {
+	id, _ := ctx.Value(ctxRequestID{}).(string)
+	return id
+}
+
+// Middleware keeps the X-Request-ID of the caller when it looks sane, generates one otherwise, and echoes it back.
+func Middleware(next http.Handler) http.Handler {
+	return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		id := accept(r.Header.Get(Header))
+		w.Header().Set(Header, id)
+		next.ServeHTTP(w, r.WithContext(ContextWithID(r.Context(), id)))
+	})
+}
+
+func accept(id string) string {
+	if id == "" || len(id) > maxLength {
+		return generate()
+	}
+	for _, c := range id {
+		// printable ASCII only, the ID ends up in logs and headers
+		if c < 0x21 || c > 0x7e {
+			return generate()
+		}
+	}
+	return id
+}
+
+func generate() string {
+	b := make([]byte, 16)
+	_, _ = rand.Read(b)
+	return hex.EncodeToString(b)
+}
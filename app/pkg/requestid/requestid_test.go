@@ -0,0 +1,61 @@
+package requestid
+
+import (
+	"context"
+	"net/http"
+	"net/http/httptest"
+	"regexp"
+	"strings"
+	"testing"
+)
+
+var generatedRegexp = regexp.MustCompile(`^[0-9a-f]{32}$`)
+
+func TestMiddleware(t *testing.T) {
+	tests := []struct {
+		name         string
+		header       string
+		wantAccepted bool
+	}{
+		{name: "missing", header: "", wantAccepted: false},
+		{name: "sane", header: "req-1:abc", wantAccepted: true},
+		{name: "longest", header: strings.Repeat("a", maxLength), wantAccepted: true},
+		{name: "too long", header: strings.Repeat("a", maxLength+1), wantAccepted: false},
+		{name: "space", header: "req 1", wantAccepted: false},
+		{name: "control", header: "req\x01", wantAccepted: false},
+		{name: "non-ASCII", header: "запрос", wantAccepted: false},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			var got string
+			handler := Middleware(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+				got = FromContext(r.Context())
+			}))
+			r := httptest.NewRequest(http.MethodGet, "/", nil)
+			r.Header.Set(Header, tt.header)
+			w := httptest.NewRecorder()
+
+			handler.ServeHTTP(w, r)
+
+			if tt.wantAccepted && got != tt.header {
+				t.Fatalf("request ID = %q, want %q", got, tt.header)
+			}
+			if !tt.wantAccepted && !generatedRegexp.MatchString(got) {
+				t.Fatalf("request ID = %q, want a generated one", got)
+			}
+			if echoed := w.Header().Get(Header); echoed != got {
+				t.Fatalf("%s = %q, want %q", Header, echoed, got)
+			}
+		})
+	}
+}
+
+func TestFromContext(t *testing.T) {
+	if got := FromContext(context.Background()); got != "" {
+		t.Fatalf("FromContext() = %q outside of a request", got)
+	}
+	if got := FromContext(ContextWithID(context.Background(), "req-1")); got != "req-1" {
+		t.Fatalf("FromContext() = %q, want req-1", got)
+	}
+}
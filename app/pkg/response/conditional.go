@@ -0,0 +1,98 @@
+package response
+
+import (
+	"crypto/sha256"
+	"encoding/base64"
+	"encoding/json"
+	"net/http"
+	"strings"
+	"time"
+)
+
+// CachedJSON responds like JSON with a strong ETag of the body and, when lastModified is not zero, Last-Modified.
+// GET and HEAD requests whose If-None-Match or If-Modified-Since still hold get 304 Not Modified without a body.
+func CachedJSON(w http.ResponseWriter, r *http.Request, v interface{}, lastModified time.Time) {
+	body, err := json.Marshal(v)
+	if err != nil {
+		w.WriteHeader(http.StatusInternalServerError)
+		return
+	}
+	body = append(body, '\n')
+
+	sum := sha256.Sum256(body)
+	writeConditional(w, r, body, `"`+base64.RawURLEncoding.EncodeToString(sum[:16])+`"`, lastModified)
+}
+
+// VersionedJSON is CachedJSON with the ETag of the caller, e.g. one derived from a version column.
+func VersionedJSON(w http.ResponseWriter, r *http.Request, v interface{}, etag string, lastModified time.Time) {
+	body, err := json.Marshal(v)
+	if err != nil {
+		w.WriteHeader(http.StatusInternalServerError)
+		return
+	}
+	writeConditional(w, r, append(body, '\n'), etag, lastModified)
+}
+
+func writeConditional(w http.ResponseWriter, r *http.Request, body []byte, etag string, lastModified time.Time) {
+	h := w.Header()
+	h.Set("ETag", etag)
+	h.Set("Cache-Control", "no-cache")
+	if !lastModified.IsZero() {
+		h.Set("Last-Modified", lastModified.UTC().Format(http.TimeFormat))
+	}
+
+	if (r.Method == http.MethodGet || r.Method == http.MethodHead) && notModified(r, etag, lastModified) {
+		w.WriteHeader(http.StatusNotModified)
+		return
+	}
+
+	h.Set("Content-Type", "application/json")
+	w.WriteHeader(http.StatusOK)
+	_, _ = w.Write(body)
+}
+
+// notModified evaluates the validators as RFC 9110 does, If-Modified-Since is ignored when If-None-Match is sent.
+func notModified(r *http.Request, etag string, lastModified time.Time) bool {
+	if inm := r.Header.Get("If-None-Match"); inm != "" {
+		return MatchETag(inm, etag)
+	}
+
+	ims := r.Header.Get("If-Modified-Since")
+	if ims == "" || lastModified.IsZero() {
+		return false
+	}
+	t, err := http.ParseTime(ims)
+	if err != nil {
+		return false
+	}
+	// the header has a precision of one second
+	return !lastModified.Truncate(time.Second).After(t)
+}
+
+// MatchETag reports whether the comma separated list of an If-None-Match header contains etag or "*".
+// It is the weak comparison of RFC 9110: weak validators match their strong counterparts.
+func MatchETag(list string, etag string) bool {
+	etag = strings.TrimPrefix(etag, "W/")
+	for _, candidate := range strings.Split(list, ",") {
+		candidate = strings.TrimSpace(candidate)
+		if candidate == "*" || strings.TrimPrefix(candidate, "W/") == etag {
+			return true
+		}
+	}
+	return false
+}
+
+// MatchStrongETag is MatchETag with the strong comparison If-Match requires: a weak validator on either side
+// never matches, only "*" or the same strong etag do.
+func MatchStrongETag(list string, etag string) bool {
+	if strings.HasPrefix(etag, "W/") {
+		return list == "*"
+	}
+	for _, candidate := range strings.Split(list, ",") {
+		candidate = strings.TrimSpace(candidate)
+		if candidate == "*" || candidate == etag {
+			return true
+		}
+	}
+	return false
+}
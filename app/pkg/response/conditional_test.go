@@ -0,0 +1,137 @@
+package response
+
+import (
+	"net/http"
+	"net/http/httptest"
+	"testing"
+	"time"
+)
+
+func TestMatchETag(t *testing.T) {
+	tests := []struct {
+		list string
+		etag string
+		want bool
+	}{
+		{list: `"1"`, etag: `"1"`, want: true},
+		{list: `"2"`, etag: `"1"`, want: false},
+		{list: `"2", "1"`, etag: `"1"`, want: true},
+		{list: `*`, etag: `"1"`, want: true},
+		{list: `W/"1"`, etag: `"1"`, want: true},
+		{list: `"1"`, etag: `W/"1"`, want: true},
+		{list: `W/"1"`, etag: `W/"1"`, want: true},
+		{list: `1`, etag: `"1"`, want: false},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.list+" "+tt.etag, func(t *testing.T) {
+			if got := MatchETag(tt.list, tt.etag); got != tt.want {
+				t.Fatalf("MatchETag(%s, %s) = %t, want %t", tt.list, tt.etag, got, tt.want)
+			}
+		})
+	}
+}
+
+func TestMatchStrongETag(t *testing.T) {
+	tests := []struct {
+		list string
+		etag string
+		want bool
+	}{
+		{list: `"1"`, etag: `"1"`, want: true},
+		{list: `"2"`, etag: `"1"`, want: false},
+		{list: `"2" , "1"`, etag: `"1"`, want: true},
+		{list: `*`, etag: `"1"`, want: true},
+		{list: `*`, etag: `W/"1"`, want: true},
+		{list: `W/"1"`, etag: `"1"`, want: false},
+		{list: `"1"`, etag: `W/"1"`, want: false},
+		{list: `W/"1"`, etag: `W/"1"`, want: false},
+		{list: `W/"1", "2"`, etag: `"1"`, want: false},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.list+" "+tt.etag, func(t *testing.T) {
+			if got := MatchStrongETag(tt.list, tt.etag); got != tt.want {
+				t.Fatalf("MatchStrongETag(%s, %s) = %t, want %t", tt.list, tt.etag, got, tt.want)
+			}
+		})
+	}
+}
+
+func TestVersionedJSON(t *testing.T) {
+	lastModified := time.Date(2024, 5, 1, 12, 0, 0, 500, time.UTC)
+
+	tests := []struct {
+		name       string
+		method     string
+		header     http.Header
+		wantStatus int
+	}{
+		{name: "unconditional", method: http.MethodGet, wantStatus: http.StatusOK},
+		{name: "etag matches", method: http.MethodGet, header: http.Header{"If-None-Match": {`"3"`}}, wantStatus: http.StatusNotModified},
+		{name: "weak etag matches", method: http.MethodGet, header: http.Header{"If-None-Match": {`W/"3"`}}, wantStatus: http.StatusNotModified},
+		{name: "head", method: http.MethodHead, header: http.Header{"If-None-Match": {`"3"`}}, wantStatus: http.StatusNotModified},
+		{name: "etag differs", method: http.MethodGet, header: http.Header{"If-None-Match": {`"2"`}}, wantStatus: http.StatusOK},
+		{
+			name:       "etag wins over date",
+			method:     http.MethodGet,
+			header:     http.Header{"If-None-Match": {`"2"`}, "If-Modified-Since": {lastModified.Format(http.TimeFormat)}},
+			wantStatus: http.StatusOK,
+		},
+		{name: "not modified since", method: http.MethodGet, header: http.Header{"If-Modified-Since": {lastModified.Format(http.TimeFormat)}}, wantStatus: http.StatusNotModified},
+		{name: "modified since", method: http.MethodGet, header: http.Header{"If-Modified-Since": {lastModified.Add(-time.Second).Format(http.TimeFormat)}}, wantStatus: http.StatusOK},
+		{name: "invalid date", method: http.MethodGet, header: http.Header{"If-Modified-Since": {"yesterday"}}, wantStatus: http.StatusOK},
+		{name: "not a read", method: http.MethodPost, header: http.Header{"If-None-Match": {`"3"`}}, wantStatus: http.StatusOK},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			r := httptest.NewRequest(tt.method, "/api/products/1", nil)
+			for key, values := range tt.header {
+				r.Header[key] = values
+			}
+			w := httptest.NewRecorder()
+
+			VersionedJSON(w, r, map[string]string{"name": "phone"}, `"3"`, lastModified)
+
+			if w.Code != tt.wantStatus {
+				t.Fatalf("status = %d, want %d", w.Code, tt.wantStatus)
+			}
+			if got := w.Header().Get("ETag"); got != `"3"` {
+				t.Fatalf("ETag = %q", got)
+			}
+			if got, want := w.Header().Get("Last-Modified"), "Wed, 01 May 2024 12:00:00 GMT"; got != want {
+				t.Fatalf("Last-Modified = %q, want %q", got, want)
+			}
+			if tt.wantStatus == http.StatusNotModified && w.Body.Len() != 0 {
+				t.Fatalf("304 has a body %q", w.Body.String())
+			}
+			if tt.wantStatus == http.StatusOK && w.Body.String() != "{\"name\":\"phone\"}\n" {
+				t.Fatalf("body = %q", w.Body.String())
+			}
+		})
+	}
+}
+
+func TestCachedJSON(t *testing.T) {
+	w := httptest.NewRecorder()
+	CachedJSON(w, httptest.NewRequest(http.MethodGet, "/api/categories", nil), []int{1, 2}, time.Time{})
+	etag := w.Header().Get("ETag")
+	if w.Code != http.StatusOK || etag == "" || w.Header().Get("Last-Modified") != "" {
+		t.Fatalf("status = %d, ETag = %q, Last-Modified = %q", w.Code, etag, w.Header().Get("Last-Modified"))
+	}
+
+	r := httptest.NewRequest(http.MethodGet, "/api/categories", nil)
+	r.Header.Set("If-None-Match", etag)
+	w = httptest.NewRecorder()
+	CachedJSON(w, r, []int{1, 2}, time.Time{})
+	if w.Code != http.StatusNotModified {
+		t.Fatalf("status = %d, want %d for the same body", w.Code, http.StatusNotModified)
+	}
+
+	w = httptest.NewRecorder()
+	CachedJSON(w, r, []int{1, 3}, time.Time{})
+	if w.Code != http.StatusOK || w.Header().Get("ETag") == etag {
+		t.Fatalf("status = %d, ETag = %q, want a new ETag for another body", w.Code, w.Header().Get("ETag"))
+	}
+}
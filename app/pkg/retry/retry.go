@@ -0,0 +1,126 @@
+package retry
+
+import (
+	"context"
+	"errors"
+	"math"
+	"math/rand"
+	"time"
+)
+
+type Policy struct {
+	// MaxAttempts limits the number of calls, zero means no limit.
+	MaxAttempts int
+	// InitialDelay is the delay before the second attempt.
+	InitialDelay time.Duration
+	// MaxDelay caps a single delay, zero means no cap.
+	MaxDelay time.Duration
+	// Multiplier grows the delay after every failed attempt, values below 1 keep it constant.
+	Multiplier float64
+	// Jitter randomizes every delay by up to the given fraction (0..1) in both directions.
+	Jitter float64
+	// MaxElapsedTime stops retrying once exceeded, zero means no limit.
+	MaxElapsedTime time.Duration
+	// Retryable reports whether err is worth another attempt, nil retries every error.
+	Retryable func(err error) bool
+	// OnRetry is called after a failed attempt before waiting for the next one.
+	OnRetry func(attempt int, err error, delay time.Duration)
+}
+
+func DefaultPolicy() Policy {
+	return Policy{
+		MaxAttempts:  5,
+		InitialDelay: 100 * time.Millisecond,
+		MaxDelay:     5 * time.Second,
+		Multiplier:   2,
+		Jitter:       0.2,
+	}
+}
+
+type permanentError struct {
+	err error
+}
+
+func (e *permanentError) Error() string {
+	return e.err.Error()
+}
+
+func (e *permanentError) Unwrap() error {
+	return e.err
+}
+
+// Permanent marks err as not retryable regardless of the policy predicate.
+func Permanent(err error) error {
+	if err == nil {
+		return nil
+	}
+	return &permanentError{err: err}
+}
+
+// Do calls fn until it succeeds, the policy gives up or ctx is done.
+// The last error of fn is returned, joined with the context error on cancellation.
+func Do(ctx context.Context, p Policy, fn func(ctx context.Context) error) error {
+	start := time.Now()
+	delay := p.InitialDelay
+
+	for attempt := 1; ; attempt++ {
+		err := fn(ctx)
+		if err == nil {
+			return nil
+		}
+
+		var permanent *permanentError
+		if errors.As(err, &permanent) {
+			return permanent.err
+		}
+		if p.Retryable != nil && !p.Retryable(err) {
+			return err
+		}
+		if p.MaxAttempts > 0 && attempt >= p.MaxAttempts {
+			return err
+		}
+
+		wait := p.jitter(delay)
+		if p.MaxElapsedTime > 0 && time.Since(start)+wait > p.MaxElapsedTime {
+			return err
+		}
+
+		if p.OnRetry != nil {
+			p.OnRetry(attempt, err, wait)
+		}
+
+		timer := time.NewTimer(wait)
+		select {
+		case <-ctx.Done():
+			timer.Stop()
+			return errors.Join(err, ctx.Err())
+		case <-timer.C:
+		}
+
+		delay = p.next(delay)
+	}
+}
+
+func (p Policy) next(delay time.Duration) time.Duration {
+	if p.Multiplier > 1 {
+		// float64(math.MaxInt64) rounds up to 2^63, which does not fit back into a Duration.
+		if next := float64(delay) * p.Multiplier; next < math.MaxInt64 {
+			delay = time.Duration(next)
+		} else {
+			delay = math.MaxInt64
+		}
+	}
+	if p.MaxDelay > 0 && delay > p.MaxDelay {
+		delay = p.MaxDelay
+	}
+	return delay
+}
+
+func (p Policy) jitter(delay time.Duration) time.Duration {
+	if p.Jitter <= 0 || delay <= 0 {
+		return delay
+	}
+	jitter := math.Min(p.Jitter, 1)
+	delta := float64(delay) * jitter
+	return time.Duration(float64(delay) - delta + rand.Float64()*2*delta)
+}
@@ -0,0 +1,169 @@
+package retry
+
+import (
+	"context"
+	"errors"
+	"testing"
+	"time"
+)
+
+var errFail = errors.New("fail")
+
+func fastPolicy() Policy {
+	return Policy{
+		MaxAttempts:  3,
+		InitialDelay: time.Millisecond,
+		Multiplier:   2,
+	}
+}
+
+func TestDo(t *testing.T) {
+	tests := []struct {
+		name      string
+		policy    Policy
+		failures  int
+		permanent bool
+		wantCalls int
+		wantErr   bool
+	}{
+		{name: "first attempt succeeds", policy: fastPolicy(), failures: 0, wantCalls: 1},
+		{name: "succeeds after retries", policy: fastPolicy(), failures: 2, wantCalls: 3},
+		{name: "attempts exhausted", policy: fastPolicy(), failures: 5, wantCalls: 3, wantErr: true},
+		{name: "permanent error stops at once", policy: fastPolicy(), failures: 5, permanent: true, wantCalls: 1, wantErr: true},
+		{
+			name: "not retryable",
+			policy: func() Policy {
+				p := fastPolicy()
+				p.Retryable = func(err error) bool { return false }
+				return p
+			}(),
+			failures:  5,
+			wantCalls: 1,
+			wantErr:   true,
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			calls := 0
+			err := Do(context.Background(), tt.policy, func(ctx context.Context) error {
+				calls++
+				if calls > tt.failures {
+					return nil
+				}
+				if tt.permanent {
+					return Permanent(errFail)
+				}
+				return errFail
+			})
+
+			if calls != tt.wantCalls {
+				t.Errorf("calls = %d, want %d", calls, tt.wantCalls)
+			}
+			if tt.wantErr != (err != nil) {
+				t.Fatalf("err = %v, wantErr %v", err, tt.wantErr)
+			}
+			if tt.wantErr && !errors.Is(err, errFail) {
+				t.Errorf("err = %v, want %v", err, errFail)
+			}
+			var permanent *permanentError
+			if errors.As(err, &permanent) {
+				t.Errorf("permanent wrapper leaked to the caller: %v", err)
+			}
+		})
+	}
+}
+
+func TestDoContextCanceled(t *testing.T) {
+	ctx, cancel := context.WithCancel(context.Background())
+	p := Policy{InitialDelay: time.Hour}
+
+	calls := 0
+	err := Do(ctx, p, func(ctx context.Context) error {
+		calls++
+		cancel()
+		return errFail
+	})
+
+	if calls != 1 {
+		t.Errorf("calls = %d, want 1", calls)
+	}
+	if !errors.Is(err, errFail) || !errors.Is(err, context.Canceled) {
+		t.Errorf("err = %v, want both %v and %v", err, errFail, context.Canceled)
+	}
+}
+
+func TestDoMaxElapsedTime(t *testing.T) {
+	p := Policy{
+		InitialDelay:   20 * time.Millisecond,
+		MaxElapsedTime: 50 * time.Millisecond,
+	}
+
+	calls := 0
+	start := time.Now()
+	err := Do(context.Background(), p, func(ctx context.Context) error {
+		calls++
+		return errFail
+	})
+
+	if !errors.Is(err, errFail) {
+		t.Errorf("err = %v, want %v", err, errFail)
+	}
+	if calls != 3 {
+		t.Errorf("calls = %d, want 3", calls)
+	}
+	if elapsed := time.Since(start); elapsed > p.MaxElapsedTime {
+		t.Errorf("elapsed = %s, want at most %s", elapsed, p.MaxElapsedTime)
+	}
+}
+
+func TestDoOnRetry(t *testing.T) {
+	p := fastPolicy()
+	var attempts []int
+	p.OnRetry = func(attempt int, err error, delay time.Duration) {
+		attempts = append(attempts, attempt)
+	}
+
+	_ = Do(context.Background(), p, func(ctx context.Context) error {
+		return errFail
+	})
+
+	if len(attempts) != 2 || attempts[0] != 1 || attempts[1] != 2 {
+		t.Errorf("OnRetry attempts = %v, want [1 2]", attempts)
+	}
+}
+
+func TestPolicyNext(t *testing.T) {
+	tests := []struct {
+		name   string
+		policy Policy
+		delay  time.Duration
+		want   time.Duration
+	}{
+		{name: "grows by multiplier", policy: Policy{Multiplier: 2}, delay: time.Second, want: 2 * time.Second},
+		{name: "capped by max delay", policy: Policy{Multiplier: 3, MaxDelay: 2 * time.Second}, delay: time.Second, want: 2 * time.Second},
+		{name: "constant below 1", policy: Policy{Multiplier: 0.5}, delay: time.Second, want: time.Second},
+		{name: "no overflow", policy: Policy{Multiplier: 10}, delay: time.Duration(1 << 62), want: time.Duration(1<<63 - 1)},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			if got := tt.policy.next(tt.delay); got != tt.want {
+				t.Errorf("next(%s) = %s, want %s", tt.delay, got, tt.want)
+			}
+		})
+	}
+}
+
+func TestPolicyJitter(t *testing.T) {
+	p := Policy{Jitter: 0.5}
+	for i := 0; i < 100; i++ {
+		got := p.jitter(time.Second)
+		if got < 500*time.Millisecond || got > 1500*time.Millisecond {
+			t.Fatalf("jitter(1s) = %s, want within [500ms, 1.5s]", got)
+		}
+	}
+	if got := (Policy{}).jitter(time.Second); got != time.Second {
+		t.Errorf("jitter without Jitter = %s, want 1s", got)
+	}
+}
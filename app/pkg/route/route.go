@@ -0,0 +1,88 @@
+package route
+
+import (
+	"fmt"
+	"sort"
+	"strings"
+)
+
+// Table picks a value for a request by "<METHOD> <pattern>" routes written like httprouter paths,
+// ":name" matches one segment and "*name" the rest of the path. Requests matching no route get the default.
+type Table[T any] struct {
+	def    T
+	routes []entry[T]
+}
+
+type entry[T any] struct {
+	name     string
+	method   string
+	segments []string
+	value    T
+}
+
+func NewTable[T any](def T, routes map[string]T) (*Table[T], error) {
+	t := &Table[T]{def: def, routes: make([]entry[T], 0, len(routes))}
+	for name, value := range routes {
+		method, path, ok := strings.Cut(strings.TrimSpace(name), " ")
+		if !ok || !strings.HasPrefix(path, "/") {
+			return nil, fmt.Errorf("route %q must look like \"GET /api/products\"", name)
+		}
+		t.routes = append(t.routes, entry[T]{
+			name:     name,
+			method:   strings.ToUpper(method),
+			segments: split(path),
+			value:    value,
+		})
+	}
+
+	// routes with more static segments are more specific and are tried first
+	sort.Slice(t.routes, func(i, j int) bool {
+		si, sj := t.routes[i].static(), t.routes[j].static()
+		if si != sj {
+			return si > sj
+		}
+		return t.routes[i].name < t.routes[j].name
+	})
+
+	return t, nil
+}
+
+// Match returns the name of the matched route and its value, "default" for the default value.
+func (t *Table[T]) Match(method string, path string) (string, T) {
+	segments := split(path)
+	for _, e := range t.routes {
+		if e.method == method && e.match(segments) {
+			return e.name, e.value
+		}
+	}
+	return "default", t.def
+}
+
+func (e entry[T]) match(segments []string) bool {
+	for i, s := range e.segments {
+		if strings.HasPrefix(s, "*") {
+			return true
+		}
+		if i >= len(segments) {
+			return false
+		}
+		if !strings.HasPrefix(s, ":") && s != segments[i] {
+			return false
+		}
+	}
+	return len(segments) == len(e.segments)
+}
+
+func (e entry[T]) static() int {
+	n := 0
+	for _, s := range e.segments {
+		if !strings.HasPrefix(s, ":") && !strings.HasPrefix(s, "*") {
+			n++
+		}
+	}
+	return n
+}
+
+func split(path string) []string {
+	return strings.Split(strings.Trim(path, "/"), "/")
+}
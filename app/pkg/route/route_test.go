@@ -0,0 +1,52 @@
+package route
+
+import "testing"
+
+func TestTableMatch(t *testing.T) {
+	table, err := NewTable("default value", map[string]string{
+		"GET /api/products":             "list",
+		"GET /api/products/:id":         "get",
+		"GET /api/products/suggestions": "suggest",
+		"post /api/products":            "create",
+		"GET /api/images/*path":         "image",
+		"GET /":                         "root",
+	})
+	if err != nil {
+		t.Fatalf("NewTable() error = %v", err)
+	}
+
+	tests := []struct {
+		method    string
+		path      string
+		wantName  string
+		wantValue string
+	}{
+		{method: "GET", path: "/api/products", wantName: "GET /api/products", wantValue: "list"},
+		{method: "GET", path: "/api/products/", wantName: "GET /api/products", wantValue: "list"},
+		{method: "GET", path: "/api/products/42", wantName: "GET /api/products/:id", wantValue: "get"},
+		{method: "GET", path: "/api/products/suggestions", wantName: "GET /api/products/suggestions", wantValue: "suggest"},
+		{method: "POST", path: "/api/products", wantName: "post /api/products", wantValue: "create"},
+		{method: "GET", path: "/api/images/a/b/c.png", wantName: "GET /api/images/*path", wantValue: "image"},
+		{method: "GET", path: "/", wantName: "GET /", wantValue: "root"},
+		{method: "DELETE", path: "/api/products/42", wantName: "default", wantValue: "default value"},
+		{method: "GET", path: "/api/products/42/images", wantName: "default", wantValue: "default value"},
+		{method: "GET", path: "/api", wantName: "default", wantValue: "default value"},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.method+" "+tt.path, func(t *testing.T) {
+			name, value := table.Match(tt.method, tt.path)
+			if name != tt.wantName || value != tt.wantValue {
+				t.Fatalf("Match() = %q, %q, want %q, %q", name, value, tt.wantName, tt.wantValue)
+			}
+		})
+	}
+}
+
+func TestNewTableInvalidRoute(t *testing.T) {
+	for _, name := range []string{"/api/products", "GET", "GET api/products"} {
+		if _, err := NewTable(0, map[string]int{name: 1}); err == nil {
+			t.Fatalf("NewTable(%q) error = nil", name)
+		}
+	}
+}
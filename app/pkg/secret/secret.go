@@ -0,0 +1,91 @@
+package secret
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"prod/pkg/logging"
+	"strings"
+	"sync/atomic"
+	"time"
+)
+
+// RefPrefix marks config values that are references to a secret, e.g. "vault:database/prod#password".
+const RefPrefix = "vault:"
+
+var ErrNotFound = errors.New("secret not found")
+
+type Ref struct {
+	Path string
+	Key  string
+}
+
+func (r Ref) String() string {
+	return RefPrefix + r.Path + "#" + r.Key
+}
+
+// ParseRef reports whether s is a secret reference and parses it.
+func ParseRef(s string) (Ref, bool, error) {
+	if !strings.HasPrefix(s, RefPrefix) {
+		return Ref{}, false, nil
+	}
+	path, key, ok := strings.Cut(strings.TrimPrefix(s, RefPrefix), "#")
+	if !ok || path == "" || key == "" {
+		return Ref{}, true, fmt.Errorf("invalid secret reference %q, expected %s<path>#<key>", s, RefPrefix)
+	}
+	return Ref{Path: path, Key: key}, true, nil
+}
+
+type Provider interface {
+	Get(ctx context.Context, ref Ref) (string, error)
+}
+
+// Value keeps the last fetched value of a secret that may be rotated by the provider.
+type Value struct {
+	provider Provider
+	ref      Ref
+	current  atomic.Pointer[string]
+}
+
+func NewValue(ctx context.Context, provider Provider, ref Ref) (*Value, error) {
+	v := &Value{provider: provider, ref: ref}
+	if err := v.Refresh(ctx); err != nil {
+		return nil, err
+	}
+	return v, nil
+}
+
+func (v *Value) Get() string {
+	return *v.current.Load()
+}
+
+func (v *Value) Refresh(ctx context.Context) error {
+	s, err := v.provider.Get(ctx, v.ref)
+	if err != nil {
+		return fmt.Errorf("failed to fetch secret %s: %w", v.ref, err)
+	}
+	v.current.Store(&s)
+	return nil
+}
+
+// Run refreshes the value every interval until ctx is done, failures keep the previous value.
+func (v *Value) Run(ctx context.Context, interval time.Duration) error {
+	ticker := time.NewTicker(interval)
+	defer ticker.Stop()
+
+	for {
+		select {
+		case <-ctx.Done():
+			return nil
+		case <-ticker.C:
+			old := v.Get()
+			if err := v.Refresh(ctx); err != nil {
+				logging.GetLogger(ctx).WithError(err).Errorln("secret refresh failed")
+				continue
+			}
+			if v.Get() != old {
+				logging.GetLogger(ctx).WithField("secret", v.ref.String()).Infoln("secret rotated")
+			}
+		}
+	}
+}
@@ -0,0 +1,38 @@
+package secret
+
+import "testing"
+
+func TestParseRef(t *testing.T) {
+	tests := []struct {
+		value   string
+		want    Ref
+		wantRef bool
+		wantErr bool
+	}{
+		{value: "plain password", wantRef: false},
+		{value: "", wantRef: false},
+		{value: "vault:database/prod#password", want: Ref{Path: "database/prod", Key: "password"}, wantRef: true},
+		{value: "vault:a#b#c", want: Ref{Path: "a", Key: "b#c"}, wantRef: true},
+		{value: "vault:database/prod", wantRef: true, wantErr: true},
+		{value: "vault:#password", wantRef: true, wantErr: true},
+		{value: "vault:database/prod#", wantRef: true, wantErr: true},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.value, func(t *testing.T) {
+			got, ok, err := ParseRef(tt.value)
+			if ok != tt.wantRef {
+				t.Errorf("ParseRef(%q) reference = %v, want %v", tt.value, ok, tt.wantRef)
+			}
+			if (err != nil) != tt.wantErr {
+				t.Fatalf("ParseRef(%q) err = %v, wantErr %v", tt.value, err, tt.wantErr)
+			}
+			if got != tt.want {
+				t.Errorf("ParseRef(%q) = %+v, want %+v", tt.value, got, tt.want)
+			}
+			if err == nil && ok && got.String() != tt.value {
+				t.Errorf("String() = %q, want %q", got.String(), tt.value)
+			}
+		})
+	}
+}
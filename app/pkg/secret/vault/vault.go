@@ -0,0 +1,111 @@
+package vault
+
+import (
+	"context"
+	"encoding/json"
+	"fmt"
+	"net/http"
+	"net/url"
+	"prod/pkg/secret"
+	"strings"
+	"time"
+)
+
+type Config struct {
+	Address string
+	Token   string
+	// Mount is the path the KV secrets engine is mounted at.
+	Mount string
+	// KVVersion is the version of the KV secrets engine, 1 or 2.
+	KVVersion int
+	Timeout   time.Duration
+}
+
+// Client reads secrets from the HashiCorp Vault KV secrets engine over its HTTP API.
+type Client struct {
+	cfg        Config
+	httpClient *http.Client
+}
+
+func NewClient(cfg Config) *Client {
+	if cfg.Mount == "" {
+		cfg.Mount = "secret"
+	}
+	if cfg.KVVersion == 0 {
+		cfg.KVVersion = 2
+	}
+	if cfg.Timeout <= 0 {
+		cfg.Timeout = 5 * time.Second
+	}
+
+	return &Client{
+		cfg:        cfg,
+		httpClient: &http.Client{Timeout: cfg.Timeout},
+	}
+}
+
+type kvResponse struct {
+	Data   json.RawMessage `json:"data"`
+	Errors []string        `json:"errors"`
+}
+
+type kv2Data struct {
+	Data map[string]interface{} `json:"data"`
+}
+
+func (c *Client) Get(ctx context.Context, ref secret.Ref) (string, error) {
+	path := strings.Trim(c.cfg.Mount, "/") + "/" + strings.Trim(ref.Path, "/")
+	if c.cfg.KVVersion == 2 {
+		path = strings.Trim(c.cfg.Mount, "/") + "/data/" + strings.Trim(ref.Path, "/")
+	}
+
+	endpoint, err := url.JoinPath(c.cfg.Address, "v1", path)
+	if err != nil {
+		return "", fmt.Errorf("invalid vault address: %w", err)
+	}
+
+	req, err := http.NewRequestWithContext(ctx, http.MethodGet, endpoint, nil)
+	if err != nil {
+		return "", err
+	}
+	req.Header.Set("X-Vault-Token", c.cfg.Token)
+
+	resp, err := c.httpClient.Do(req)
+	if err != nil {
+		return "", fmt.Errorf("vault request failed: %w", err)
+	}
+	defer resp.Body.Close()
+
+	body := kvResponse{}
+	if err = json.NewDecoder(resp.Body).Decode(&body); err != nil && resp.StatusCode == http.StatusOK {
+		return "", fmt.Errorf("invalid vault response: %w", err)
+	}
+
+	switch {
+	case resp.StatusCode == http.StatusNotFound:
+		return "", fmt.Errorf("%w: %s", secret.ErrNotFound, ref.Path)
+	case resp.StatusCode != http.StatusOK:
+		return "", fmt.Errorf("vault responded with %s: %s", resp.Status, strings.Join(body.Errors, "; "))
+	}
+
+	data := map[string]interface{}{}
+	if c.cfg.KVVersion == 2 {
+		kv2 := kv2Data{}
+		if err = json.Unmarshal(body.Data, &kv2); err != nil {
+			return "", fmt.Errorf("invalid vault response: %w", err)
+		}
+		data = kv2.Data
+	} else if err = json.Unmarshal(body.Data, &data); err != nil {
+		return "", fmt.Errorf("invalid vault response: %w", err)
+	}
+
+	value, ok := data[ref.Key]
+	if !ok {
+		return "", fmt.Errorf("%w: key %s in %s", secret.ErrNotFound, ref.Key, ref.Path)
+	}
+	s, ok := value.(string)
+	if !ok {
+		return "", fmt.Errorf("secret %s is not a string", ref)
+	}
+	return s, nil
+}
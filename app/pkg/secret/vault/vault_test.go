@@ -0,0 +1,158 @@
+package vault
+
+import (
+	"context"
+	"encoding/json"
+	"errors"
+	"net/http"
+	"net/http/httptest"
+	"prod/pkg/secret"
+	"strings"
+	"sync"
+	"testing"
+)
+
+const testToken = "s.test-token"
+
+// fakeVault serves KV secrets keyed by their full API path, e.g. "/v1/secret/data/db".
+type fakeVault struct {
+	mu      sync.Mutex
+	secrets map[string]map[string]interface{}
+	kv2     bool
+}
+
+func (v *fakeVault) set(path string, data map[string]interface{}) {
+	v.mu.Lock()
+	defer v.mu.Unlock()
+	v.secrets[path] = data
+}
+
+func (v *fakeVault) ServeHTTP(w http.ResponseWriter, r *http.Request) {
+	w.Header().Set("Content-Type", "application/json")
+	if r.Header.Get("X-Vault-Token") != testToken {
+		w.WriteHeader(http.StatusForbidden)
+		_, _ = w.Write([]byte(`{"errors":["permission denied"]}`))
+		return
+	}
+
+	v.mu.Lock()
+	data, ok := v.secrets[r.URL.Path]
+	v.mu.Unlock()
+	if !ok {
+		w.WriteHeader(http.StatusNotFound)
+		_, _ = w.Write([]byte(`{"errors":[]}`))
+		return
+	}
+
+	var body interface{} = map[string]interface{}{"data": data}
+	if v.kv2 {
+		body = map[string]interface{}{"data": map[string]interface{}{
+			"data":     data,
+			"metadata": map[string]interface{}{"version": 1},
+		}}
+	}
+	_ = json.NewEncoder(w).Encode(body)
+}
+
+func newFakeVault(t *testing.T, kv2 bool) (*fakeVault, *httptest.Server) {
+	t.Helper()
+	v := &fakeVault{secrets: make(map[string]map[string]interface{}), kv2: kv2}
+	srv := httptest.NewServer(v)
+	t.Cleanup(srv.Close)
+	return v, srv
+}
+
+func TestClientGet(t *testing.T) {
+	tests := []struct {
+		name      string
+		kvVersion int
+		token     string
+		ref       secret.Ref
+		want      string
+		wantErr   error
+		wantMsg   string
+	}{
+		{name: "kv v2", kvVersion: 2, ref: secret.Ref{Path: "db", Key: "password"}, want: "v2-password"},
+		{name: "kv v2 by default", ref: secret.Ref{Path: "/db/", Key: "password"}, want: "v2-password"},
+		{name: "kv v1", kvVersion: 1, ref: secret.Ref{Path: "db", Key: "password"}, want: "v1-password"},
+		{name: "missing path", kvVersion: 2, ref: secret.Ref{Path: "nope", Key: "password"}, wantErr: secret.ErrNotFound},
+		{name: "missing key", kvVersion: 2, ref: secret.Ref{Path: "db", Key: "nope"}, wantErr: secret.ErrNotFound},
+		{name: "not a string", kvVersion: 2, ref: secret.Ref{Path: "db", Key: "port"}, wantMsg: "is not a string"},
+		{name: "wrong token", kvVersion: 2, token: "s.wrong", ref: secret.Ref{Path: "db", Key: "password"}, wantMsg: "403 Forbidden: permission denied"},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			kv2 := tt.kvVersion != 1
+			v, srv := newFakeVault(t, kv2)
+			if kv2 {
+				v.set("/v1/kv/data/db", map[string]interface{}{"password": "v2-password", "port": 5432})
+			} else {
+				v.set("/v1/kv/db", map[string]interface{}{"password": "v1-password"})
+			}
+
+			token := tt.token
+			if token == "" {
+				token = testToken
+			}
+			c := NewClient(Config{Address: srv.URL, Token: token, Mount: "/kv/", KVVersion: tt.kvVersion})
+
+			got, err := c.Get(context.Background(), tt.ref)
+			switch {
+			case tt.wantErr != nil:
+				if !errors.Is(err, tt.wantErr) {
+					t.Fatalf("err = %v, want %v", err, tt.wantErr)
+				}
+			case tt.wantMsg != "":
+				if err == nil || !strings.Contains(err.Error(), tt.wantMsg) {
+					t.Fatalf("err = %v, want %q", err, tt.wantMsg)
+				}
+			case err != nil:
+				t.Fatal(err)
+			case got != tt.want:
+				t.Errorf("Get() = %q, want %q", got, tt.want)
+			}
+		})
+	}
+}
+
+func TestClientGetUnreachable(t *testing.T) {
+	_, srv := newFakeVault(t, true)
+	srv.Close()
+
+	_, err := NewClient(Config{Address: srv.URL, Token: testToken}).Get(context.Background(), secret.Ref{Path: "db", Key: "password"})
+	if err == nil || !strings.Contains(err.Error(), "vault request failed") {
+		t.Errorf("err = %v, want a request failure", err)
+	}
+}
+
+func TestValueRefreshRotatedSecret(t *testing.T) {
+	v, srv := newFakeVault(t, true)
+	v.set("/v1/secret/data/db", map[string]interface{}{"password": "first"})
+	ctx := context.Background()
+
+	value, err := secret.NewValue(ctx, NewClient(Config{Address: srv.URL, Token: testToken}), secret.Ref{Path: "db", Key: "password"})
+	if err != nil {
+		t.Fatal(err)
+	}
+	if got := value.Get(); got != "first" {
+		t.Fatalf("Get() = %q, want first", got)
+	}
+
+	v.set("/v1/secret/data/db", map[string]interface{}{"password": "second"})
+	if err = value.Refresh(ctx); err != nil {
+		t.Fatal(err)
+	}
+	if got := value.Get(); got != "second" {
+		t.Errorf("Get() after rotation = %q, want second", got)
+	}
+
+	// a failed refresh keeps serving the last known value
+	v.set("/v1/secret/data/db", map[string]interface{}{})
+	if err = value.Refresh(ctx); !errors.Is(err, secret.ErrNotFound) {
+		t.Errorf("Refresh() = %v, want %v", err, secret.ErrNotFound)
+	}
+	if got := value.Get(); got != "second" {
+		t.Errorf("Get() after a failed refresh = %q, want second", got)
+	}
+}
@@ -0,0 +1,74 @@
+package timeout
+
+import (
+	"context"
+	"fmt"
+	"net/http"
+	"prod/pkg/route"
+	"strings"
+	"time"
+)
+
+// Rules pick the deadline of a request by its route, see route.Table for the patterns.
+// The zero duration means no deadline.
+type Rules struct {
+	table *route.Table[time.Duration]
+}
+
+// ParseTimeout parses a positive duration, e.g. "5s", or "off".
+func ParseTimeout(s string) (time.Duration, error) {
+	s = strings.TrimSpace(s)
+	if s == "off" {
+		return 0, nil
+	}
+	d, err := time.ParseDuration(s)
+	if err != nil || d <= 0 {
+		return 0, fmt.Errorf("timeout %q must be a positive duration or off", s)
+	}
+	return d, nil
+}
+
+func NewRules(def string, routes map[string]string) (*Rules, error) {
+	defTimeout, err := ParseTimeout(def)
+	if err != nil {
+		return nil, err
+	}
+
+	timeouts := make(map[string]time.Duration, len(routes))
+	for name, value := range routes {
+		d, err := ParseTimeout(value)
+		if err != nil {
+			return nil, fmt.Errorf("route %q: %w", name, err)
+		}
+		timeouts[name] = d
+	}
+
+	table, err := route.NewTable(defTimeout, timeouts)
+	if err != nil {
+		return nil, err
+	}
+	return &Rules{table: table}, nil
+}
+
+func (r *Rules) Match(method string, path string) time.Duration {
+	_, d := r.table.Match(method, path)
+	return d
+}
+
+// Middleware sets the deadline of the route on the request context. Queries run with that context are canceled
+// when it passes and the handlers respond with 504, see problem.Timeout.
+func Middleware(rules *Rules) func(http.Handler) http.Handler {
+	return func(next http.Handler) http.Handler {
+		return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+			d := rules.Match(r.Method, r.URL.Path)
+			if d == 0 {
+				next.ServeHTTP(w, r)
+				return
+			}
+
+			ctx, cancel := context.WithTimeout(r.Context(), d)
+			defer cancel()
+			next.ServeHTTP(w, r.WithContext(ctx))
+		})
+	}
+}
@@ -0,0 +1,107 @@
+package timeout
+
+import (
+	"net/http"
+	"net/http/httptest"
+	"testing"
+	"time"
+)
+
+func TestParseTimeout(t *testing.T) {
+	tests := []struct {
+		in      string
+		want    time.Duration
+		wantErr bool
+	}{
+		{in: "5s", want: 5 * time.Second},
+		{in: " 150ms ", want: 150 * time.Millisecond},
+		{in: "off", want: 0},
+		{in: "0s", wantErr: true},
+		{in: "-1s", wantErr: true},
+		{in: "5", wantErr: true},
+		{in: "", wantErr: true},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.in, func(t *testing.T) {
+			got, err := ParseTimeout(tt.in)
+			if (err != nil) != tt.wantErr {
+				t.Fatalf("ParseTimeout() error = %v, wantErr %t", err, tt.wantErr)
+			}
+			if got != tt.want {
+				t.Fatalf("ParseTimeout() = %s, want %s", got, tt.want)
+			}
+		})
+	}
+}
+
+func TestNewRules(t *testing.T) {
+	rules, err := NewRules("5s", map[string]string{
+		"GET /api/products/search": "10s",
+		"GET /api/export":          "off",
+	})
+	if err != nil {
+		t.Fatalf("NewRules() error = %v", err)
+	}
+
+	tests := []struct {
+		method string
+		path   string
+		want   time.Duration
+	}{
+		{method: "GET", path: "/api/products/search", want: 10 * time.Second},
+		{method: "GET", path: "/api/export", want: 0},
+		{method: "GET", path: "/api/products", want: 5 * time.Second},
+	}
+	for _, tt := range tests {
+		if got := rules.Match(tt.method, tt.path); got != tt.want {
+			t.Fatalf("Match(%s %s) = %s, want %s", tt.method, tt.path, got, tt.want)
+		}
+	}
+
+	if _, err := NewRules("soon", nil); err == nil {
+		t.Fatal("NewRules() accepted an invalid default")
+	}
+	if _, err := NewRules("5s", map[string]string{"GET /api/products": "0s"}); err == nil {
+		t.Fatal("NewRules() accepted an invalid route timeout")
+	}
+	if _, err := NewRules("5s", map[string]string{"/api/products": "1s"}); err == nil {
+		t.Fatal("NewRules() accepted a route without a method")
+	}
+}
+
+func TestMiddleware(t *testing.T) {
+	rules, err := NewRules("5s", map[string]string{"GET /api/export": "off"})
+	if err != nil {
+		t.Fatalf("NewRules() error = %v", err)
+	}
+
+	tests := []struct {
+		path         string
+		wantDeadline bool
+	}{
+		{path: "/api/products", wantDeadline: true},
+		{path: "/api/export", wantDeadline: false},
+	}
+	for _, tt := range tests {
+		t.Run(tt.path, func(t *testing.T) {
+			var (
+				deadline time.Time
+				ok       bool
+			)
+			handler := Middleware(rules)(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+				deadline, ok = r.Context().Deadline()
+			}))
+
+			start := time.Now()
+			handler.ServeHTTP(httptest.NewRecorder(), httptest.NewRequest(http.MethodGet, tt.path, nil))
+
+			if ok != tt.wantDeadline {
+				t.Fatalf("deadline set = %t, want %t", ok, tt.wantDeadline)
+			}
+			if ok && (deadline.Before(start.Add(5*time.Second)) || deadline.After(time.Now().Add(5*time.Second))) {
+				t.Fatalf("deadline = %s, want 5s from the request", deadline.Sub(start))
+			}
+		})
+	}
+}
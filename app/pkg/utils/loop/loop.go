@@ -1,18 +0,0 @@
-package loop
-
-import "time"
-
-func DoWithAttempts(fn func() error, maxAttempts int, delay time.Duration) error {
-	var err error
-
-	for maxAttempts > 0 {
-		if err = fn(); err != nil {
-			time.Sleep(delay)
-			maxAttempts--
-			continue
-		}
-		return nil
-	}
-
-	return err
-}
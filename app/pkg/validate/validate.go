@@ -0,0 +1,238 @@
+package validate
+
+import (
+	"encoding/json"
+	"fmt"
+	"prod/pkg/apperror"
+	"reflect"
+	"regexp"
+	"strconv"
+	"strings"
+	"sync"
+	"unicode/utf8"
+)
+
+// Struct checks the `validate` tags of v, a struct or a pointer to one, and returns field-level problems
+// addressed by the json names. Rules are comma separated:
+//
+//	required       not the zero value, nil or empty
+//	omitempty      skip the other rules for zero values
+//	min=N, max=N   number bounds
+//	minlen=N,
+//	maxlen=N       string length in characters
+//	uuid           a UUID string
+//	json_object    a JSON object, for json.RawMessage, []byte and strings
+//	oneof=a|b      one of the listed strings
+//
+// Nested structs are checked with their json name as a prefix.
+func Struct(v interface{}) error {
+	fields := make([]apperror.FieldError, 0)
+	check(reflect.ValueOf(v), "", &fields)
+	if len(fields) == 0 {
+		return nil
+	}
+	return apperror.Invalid(fields...)
+}
+
+func check(v reflect.Value, prefix string, fields *[]apperror.FieldError) {
+	for v.Kind() == reflect.Pointer {
+		if v.IsNil() {
+			return
+		}
+		v = v.Elem()
+	}
+	if v.Kind() != reflect.Struct {
+		return
+	}
+
+	t := v.Type()
+	for i := 0; i < t.NumField(); i++ {
+		sf := t.Field(i)
+		if !sf.IsExported() {
+			continue
+		}
+		name := strings.Split(sf.Tag.Get("json"), ",")[0]
+		if name == "-" {
+			continue
+		}
+		if name == "" {
+			name = sf.Name
+		}
+		if prefix != "" {
+			name = prefix + "." + name
+		}
+
+		fv := v.Field(i)
+		if tag, ok := sf.Tag.Lookup("validate"); ok {
+			if msg := checkField(fv, rulesOf(tag)); msg != "" {
+				*fields = append(*fields, apperror.FieldError{Field: name, Message: msg})
+				continue
+			}
+		}
+		if indirect(fv).Kind() == reflect.Struct && sf.Type != rawMessageType {
+			check(fv, name, fields)
+		}
+	}
+}
+
+type rule struct {
+	name  string
+	param string
+}
+
+var rulesCache sync.Map
+
+func rulesOf(tag string) []rule {
+	if cached, ok := rulesCache.Load(tag); ok {
+		return cached.([]rule)
+	}
+	rules := make([]rule, 0)
+	for _, item := range strings.Split(tag, ",") {
+		name, param, _ := strings.Cut(strings.TrimSpace(item), "=")
+		if name != "" {
+			rules = append(rules, rule{name: name, param: param})
+		}
+	}
+	rulesCache.Store(tag, rules)
+	return rules
+}
+
+var (
+	rawMessageType = reflect.TypeOf(json.RawMessage{})
+	uuidRegexp     = regexp.MustCompile(`^[0-9a-fA-F]{8}-[0-9a-fA-F]{4}-[0-9a-fA-F]{4}-[0-9a-fA-F]{4}-[0-9a-fA-F]{12}$`)
+)
+
+// checkField returns the message of the first failed rule, an empty string when all pass.
+func checkField(fv reflect.Value, rules []rule) string {
+	v := indirect(fv)
+	empty := !v.IsValid() || v.IsZero() || (v.Kind() == reflect.Slice || v.Kind() == reflect.Map) && v.Len() == 0
+	if v.IsValid() && v.Type() == rawMessageType && string(v.Bytes()) == "null" {
+		empty = true
+	}
+
+	for _, r := range rules {
+		switch r.name {
+		case "required":
+			if empty {
+				return "is required"
+			}
+		case "omitempty":
+			if empty {
+				return ""
+			}
+		}
+	}
+	if !v.IsValid() {
+		return ""
+	}
+
+	for _, r := range rules {
+		var msg string
+		switch r.name {
+		case "required", "omitempty":
+		case "min", "max":
+			msg = checkNumber(v, r)
+		case "minlen", "maxlen":
+			msg = checkLength(v, r)
+		case "uuid":
+			if v.Kind() != reflect.String || !uuidRegexp.MatchString(v.String()) {
+				msg = "must be a UUID"
+			}
+		case "json_object":
+			msg = checkJSONObject(v)
+		case "oneof":
+			if !contains(strings.Split(r.param, "|"), fmt.Sprint(v.Interface())) {
+				msg = "must be one of " + strings.ReplaceAll(r.param, "|", ", ")
+			}
+		default:
+			panic(fmt.Sprintf("validate: unknown rule %q", r.name))
+		}
+		if msg != "" {
+			return msg
+		}
+	}
+	return ""
+}
+
+func checkNumber(v reflect.Value, r rule) string {
+	bound, err := strconv.ParseFloat(r.param, 64)
+	if err != nil {
+		panic(fmt.Sprintf("validate: %s needs a number, got %q", r.name, r.param))
+	}
+
+	var n float64
+	switch v.Kind() {
+	case reflect.Int, reflect.Int8, reflect.Int16, reflect.Int32, reflect.Int64:
+		n = float64(v.Int())
+	case reflect.Uint, reflect.Uint8, reflect.Uint16, reflect.Uint32, reflect.Uint64:
+		n = float64(v.Uint())
+	case reflect.Float32, reflect.Float64:
+		n = v.Float()
+	default:
+		panic(fmt.Sprintf("validate: %s is not applicable to %s", r.name, v.Type()))
+	}
+
+	if r.name == "min" && n < bound {
+		return "must be at least " + r.param
+	}
+	if r.name == "max" && n > bound {
+		return "must be at most " + r.param
+	}
+	return ""
+}
+
+func checkLength(v reflect.Value, r rule) string {
+	bound, err := strconv.Atoi(r.param)
+	if err != nil {
+		panic(fmt.Sprintf("validate: %s needs an integer, got %q", r.name, r.param))
+	}
+	if v.Kind() != reflect.String {
+		panic(fmt.Sprintf("validate: %s is not applicable to %s", r.name, v.Type()))
+	}
+
+	n := utf8.RuneCountInString(v.String())
+	if r.name == "minlen" && n < bound {
+		return fmt.Sprintf("must be at least %d characters long", bound)
+	}
+	if r.name == "maxlen" && n > bound {
+		return fmt.Sprintf("must be at most %d characters long", bound)
+	}
+	return ""
+}
+
+func checkJSONObject(v reflect.Value) string {
+	var data []byte
+	switch {
+	case v.Kind() == reflect.String:
+		data = []byte(v.String())
+	case v.Kind() == reflect.Slice && v.Type().Elem().Kind() == reflect.Uint8:
+		data = v.Bytes()
+	default:
+		panic(fmt.Sprintf("validate: json_object is not applicable to %s", v.Type()))
+	}
+
+	var object map[string]json.RawMessage
+	if err := json.Unmarshal(data, &object); err != nil || object == nil {
+		return "must be a JSON object"
+	}
+	return ""
+}
+
+func indirect(v reflect.Value) reflect.Value {
+	for v.Kind() == reflect.Pointer {
+		if v.IsNil() {
+			return reflect.Value{}
+		}
+		v = v.Elem()
+	}
+	return v
+}
+
+func contains(list []string, s string) bool {
+	for _, item := range list {
+		if item == s {
+			return true
+		}
+	}
+	return false
+}
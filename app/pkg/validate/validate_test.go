@@ -0,0 +1,216 @@
+package validate
+
+import (
+	"encoding/json"
+	"errors"
+	"prod/pkg/apperror"
+	"reflect"
+	"testing"
+)
+
+type address struct {
+	City string `json:"city" validate:"required"`
+}
+
+type payload struct {
+	Name     string          `json:"name" validate:"required,minlen=2,maxlen=5"`
+	Price    int64           `json:"price" validate:"min=0,max=100"`
+	Ratio    float64         `json:"ratio" validate:"omitempty,max=1.5"`
+	ImageId  *string         `json:"image_id" validate:"omitempty,uuid"`
+	Status   string          `json:"status" validate:"omitempty,oneof=draft|published"`
+	Spec     json.RawMessage `json:"spec" validate:"omitempty,json_object"`
+	Tags     []string        `json:"tags" validate:"required"`
+	Address  address         `json:"address"`
+	Shipping *address        `json:"shipping"`
+	Ignored  string          `json:"-" validate:"required"`
+	NoJSON   string          `validate:"maxlen=1"`
+	internal string          `validate:"required"`
+}
+
+func validPayload() payload {
+	return payload{
+		Name:    "phone",
+		Price:   100,
+		Tags:    []string{"new"},
+		Address: address{City: "Moscow"},
+	}
+}
+
+func ptr(s string) *string {
+	return &s
+}
+
+func TestStruct(t *testing.T) {
+	tests := []struct {
+		name   string
+		modify func(p *payload)
+		want   []apperror.FieldError
+	}{
+		{name: "valid", modify: func(p *payload) {}},
+		{
+			name:   "required string",
+			modify: func(p *payload) { p.Name = "" },
+			want:   []apperror.FieldError{{Field: "name", Message: "is required"}},
+		},
+		{
+			name:   "length counts characters",
+			modify: func(p *payload) { p.Name = "тел" },
+		},
+		{
+			name:   "too short",
+			modify: func(p *payload) { p.Name = "a" },
+			want:   []apperror.FieldError{{Field: "name", Message: "must be at least 2 characters long"}},
+		},
+		{
+			name:   "too long",
+			modify: func(p *payload) { p.Name = "phones" },
+			want:   []apperror.FieldError{{Field: "name", Message: "must be at most 5 characters long"}},
+		},
+		{
+			name:   "below min",
+			modify: func(p *payload) { p.Price = -1 },
+			want:   []apperror.FieldError{{Field: "price", Message: "must be at least 0"}},
+		},
+		{
+			name:   "above max",
+			modify: func(p *payload) { p.Price = 101 },
+			want:   []apperror.FieldError{{Field: "price", Message: "must be at most 100"}},
+		},
+		{
+			name:   "float bound",
+			modify: func(p *payload) { p.Ratio = 1.6 },
+			want:   []apperror.FieldError{{Field: "ratio", Message: "must be at most 1.5"}},
+		},
+		{
+			name:   "uuid",
+			modify: func(p *payload) { p.ImageId = ptr("0f8fad5b-d9cb-469f-a165-70867728950e") },
+		},
+		{
+			name:   "not a uuid",
+			modify: func(p *payload) { p.ImageId = ptr("abc") },
+			want:   []apperror.FieldError{{Field: "image_id", Message: "must be a UUID"}},
+		},
+		{
+			name:   "empty optional pointer",
+			modify: func(p *payload) { p.ImageId = ptr("") },
+		},
+		{
+			name:   "oneof",
+			modify: func(p *payload) { p.Status = "draft" },
+		},
+		{
+			name:   "not oneof",
+			modify: func(p *payload) { p.Status = "deleted" },
+			want:   []apperror.FieldError{{Field: "status", Message: "must be one of draft, published"}},
+		},
+		{
+			name:   "json object",
+			modify: func(p *payload) { p.Spec = json.RawMessage(`{"color":"red"}`) },
+		},
+		{
+			name:   "json null is empty",
+			modify: func(p *payload) { p.Spec = json.RawMessage(`null`) },
+		},
+		{
+			name:   "json array",
+			modify: func(p *payload) { p.Spec = json.RawMessage(`[1]`) },
+			want:   []apperror.FieldError{{Field: "spec", Message: "must be a JSON object"}},
+		},
+		{
+			name:   "empty slice is missing",
+			modify: func(p *payload) { p.Tags = []string{} },
+			want:   []apperror.FieldError{{Field: "tags", Message: "is required"}},
+		},
+		{
+			name:   "nested struct",
+			modify: func(p *payload) { p.Address.City = "" },
+			want:   []apperror.FieldError{{Field: "address.city", Message: "is required"}},
+		},
+		{
+			name:   "nested pointer",
+			modify: func(p *payload) { p.Shipping = &address{} },
+			want:   []apperror.FieldError{{Field: "shipping.city", Message: "is required"}},
+		},
+		{
+			name:   "field without json name",
+			modify: func(p *payload) { p.NoJSON = "ab" },
+			want:   []apperror.FieldError{{Field: "NoJSON", Message: "must be at most 1 characters long"}},
+		},
+		{
+			name: "several fields in order",
+			modify: func(p *payload) {
+				p.Name = ""
+				p.Price = -1
+				p.Status = "deleted"
+			},
+			want: []apperror.FieldError{
+				{Field: "name", Message: "is required"},
+				{Field: "price", Message: "must be at least 0"},
+				{Field: "status", Message: "must be one of draft, published"},
+			},
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			p := validPayload()
+			tt.modify(&p)
+
+			err := Struct(&p)
+			if tt.want == nil {
+				if err != nil {
+					t.Fatalf("Struct() = %v, want nil", err)
+				}
+				return
+			}
+
+			if !errors.Is(err, apperror.ErrInvalidInput) {
+				t.Fatalf("Struct() = %v, want ErrInvalidInput", err)
+			}
+			var appErr *apperror.Error
+			if !errors.As(err, &appErr) || !reflect.DeepEqual(appErr.Fields, tt.want) {
+				t.Fatalf("Struct() fields = %#v, want %#v", appErr.Fields, tt.want)
+			}
+		})
+	}
+}
+
+func TestStructNonStruct(t *testing.T) {
+	var nilPayload *payload
+	for _, v := range []interface{}{nil, nilPayload, 42, "text"} {
+		if err := Struct(v); err != nil {
+			t.Fatalf("Struct(%#v) = %v, want nil", v, err)
+		}
+	}
+}
+
+func TestStructInvalidRules(t *testing.T) {
+	tests := []struct {
+		name string
+		v    interface{}
+	}{
+		{name: "unknown rule", v: struct {
+			A string `validate:"email"`
+		}{A: "a"}},
+		{name: "min on string", v: struct {
+			A string `validate:"min=1"`
+		}{A: "a"}},
+		{name: "maxlen on number", v: struct {
+			A int `validate:"maxlen=1"`
+		}{A: 1}},
+		{name: "bad bound", v: struct {
+			A int `validate:"max=x"`
+		}{A: 1}},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			defer func() {
+				if recover() == nil {
+					t.Fatal("Struct() did not panic on a broken tag")
+				}
+			}()
+			_ = Struct(tt.v)
+		})
+	}
+}